@@ -1,7 +1,9 @@
 package goyht
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
 )
 
@@ -19,6 +21,10 @@ func (p YhtBaseResp) Message() string {
 	return "请求成功"
 }
 
+func (p *YhtBaseResp) baseResp() *YhtBaseResp {
+	return p
+}
+
 // Success 请求是否成功
 func (p YhtBaseResp) Success() bool {
 	if 200 == p.Code {
@@ -43,39 +49,39 @@ func (p yhtAuthLoginReq) Method() string {
 
 // 个人用户身份地区类型（V4版本）
 const (
-	YHTIdentityRegionMainland = "0" // 大陆
-	YHTIdentityRegionHK       = "1" // 香港
-	YHTIdentityRegionTaiwan   = "2" // 台湾
-	YHTIdentityRegionMacao    = "3" // 澳门
-	YHTIdentityRegionForeign  = "4" // 海外
+	YHTIdentityRegionMainland IdentityRegion = "0" // 大陆
+	YHTIdentityRegionHK       IdentityRegion = "1" // 香港
+	YHTIdentityRegionTaiwan   IdentityRegion = "2" // 台湾
+	YHTIdentityRegionMacao    IdentityRegion = "3" // 澳门
+	YHTIdentityRegionForeign  IdentityRegion = "4" // 海外
 )
 
 // 个人用户证件类型（V4版本）
 const (
-	YHTPersonCertTypeIDCard   = "a" // 身份证
-	YHTPersonCertTypePassport = "b" // 护照
-	YHTPersonCertTypeEEP      = "d" // 港澳通行证
-	YHTPersonCertTypeMTPForTW = "e" // 台胞证
-	YHTPersonCertTypeMTPForHM = "f" // 港澳居民来往内地通行证
-	YHTPersonCertTypeOther    = "z" // 其它
+	YHTPersonCertTypeIDCard   PersonCertType = "a" // 身份证
+	YHTPersonCertTypePassport PersonCertType = "b" // 护照
+	YHTPersonCertTypeEEP      PersonCertType = "d" // 港澳通行证
+	YHTPersonCertTypeMTPForTW PersonCertType = "e" // 台胞证
+	YHTPersonCertTypeMTPForHM PersonCertType = "f" // 港澳居民来往内地通行证
+	YHTPersonCertTypeOther    PersonCertType = "z" // 其它
 )
 
 // 个人用户手机号地区类型（V4版本）
 const (
-	YHTPhoneRegionMainland = "0" // 大陆
-	YHTPhoneRegionHKMacao  = "1" // 香港澳门
-	YHTPhoneRegionTaiwan   = "2" // 台湾
+	YHTPhoneRegionMainland PhoneRegion = "0" // 大陆
+	YHTPhoneRegionHKMacao  PhoneRegion = "1" // 香港澳门
+	YHTPhoneRegionTaiwan   PhoneRegion = "2" // 台湾
 )
 
 // YhtCreatePersonReq 云合同创建个人用户请求模型
 type YhtCreatePersonReq struct {
-	Username       string `json:"userName"`
-	IdentityRegion string `json:"identityRegion"`
-	CertType       string `json:"certifyType"`
-	CertNum        string `json:"certifyNum"`
-	PhoneRegion    string `json:"phoneRegion"`
-	Phone          string `json:"phoneNo"`
-	CAType         string `json:"caType"` // 固定传B2
+	Username       string         `json:"userName"`
+	IdentityRegion IdentityRegion `json:"identityRegion"`
+	CertType       PersonCertType `json:"certifyType"`
+	CertNum        string         `json:"certifyNum"`
+	PhoneRegion    PhoneRegion    `json:"phoneRegion"`
+	Phone          string         `json:"phoneNo"`
+	CAType         string         `json:"caType"` // 固定传B2
 }
 
 // SignerIDResp 用户ID应答模型
@@ -101,16 +107,16 @@ func (p YhtCreatePersonReq) Method() string {
 
 // 企业用户证件类型
 const (
-	YHTCompanyCertTypeUniformSocailCreditCode = "1"
+	YHTCompanyCertTypeUniformSocailCreditCode CompanyCertType = "1"
 )
 
 // YhtCreateCompanyReq 云合同创建企业用户请求
 type YhtCreateCompanyReq struct {
-	Username string `json:"userName"`
-	CertType string `json:"certifyType"` // 固定为1， 社会统一信用代码
-	CertNum  string `json:"certifyNum"`
-	Phone    string `json:"phoneNo"`
-	CAType   string `json:"caType"` // 固定传B2
+	Username string          `json:"userName"`
+	CertType CompanyCertType `json:"certifyType"` // 固定为1， 社会统一信用代码
+	CertNum  string          `json:"certifyNum"`
+	Phone    string          `json:"phoneNo"`
+	CAType   string          `json:"caType"` // 固定传B2
 }
 
 // URI .
@@ -146,47 +152,47 @@ type YhtQuerySignerIDResp struct {
 
 // 云合同个人印章边框类型
 const (
-	YHTPMWithBorder    = "B1" // 有边框
-	YHTPMWithoutBorder = "B2" // 无边框
+	YHTPMWithBorder    BorderType = "B1" // 有边框
+	YHTPMWithoutBorder BorderType = "B2" // 无边框
 )
 
 // 云合同个人印章字体类型
 const (
-	YHTPMFontKaiti = "F1" // 楷体
-	YHTPMFontHWFS  = "F2" // 华文仿宋
-	YHTPMFontHWKT  = "F3" // 华文楷体
-	YHTPMFontMSYH  = "F4" // 微软雅黑
+	YHTPMFontKaiti FontFamily = "F1" // 楷体
+	YHTPMFontHWFS  FontFamily = "F2" // 华文仿宋
+	YHTPMFontHWKT  FontFamily = "F3" // 华文楷体
+	YHTPMFontMSYH  FontFamily = "F4" // 微软雅黑
 )
 
 // 云合同个人印章字体颜色类型
 const (
-	YHTMFontColorRed   = "C1" // 红
-	YHTMFontColorBlue  = "C2" // 蓝
-	YHTMFontColorBlack = "C3" // 黑
+	YHTMFontColorRed   FontColor = "C1" // 红
+	YHTMFontColorBlue  FontColor = "C2" // 蓝
+	YHTMFontColorBlack FontColor = "C3" // 黑
 )
 
 // 云合同个人印章模式
 const (
-	YHTMModeNormal      = "0" // 常规
-	YHTMModeTransparent = "1" // 透明
-	YHTMModeSafe        = "2" // 脱敏
+	YHTMModeNormal      MoulageMode = "0" // 常规
+	YHTMModeTransparent MoulageMode = "1" // 透明
+	YHTMModeSafe        MoulageMode = "2" // 脱敏
 )
 
 // 云合同个人印章缩放类型
 const (
-	YHTPMZoomCodeLarge  = "0" // 大
-	YHTPMZoomCodeNormal = "1" // 中
-	YHTPMZoomCodeSmall  = "2" // 小
+	YHTPMZoomCodeLarge  ZoomCode = "0" // 大
+	YHTPMZoomCodeNormal ZoomCode = "1" // 中
+	YHTPMZoomCodeSmall  ZoomCode = "2" // 小
 )
 
 // YhtCreatePersonMoulageReq 云合同创建个人印章请求
 type YhtCreatePersonMoulageReq struct {
-	SignerID   string `json:"signerId"`
-	BorderType string `json:"borderType"`
-	FontFamily string `json:"fontFamily"`
-	FontColor  string `json:"color"`
-	Mode       string `json:"mode"`
-	ZoomCode   string `json:"zoomCode"`
+	SignerID   string      `json:"signerId"`
+	BorderType BorderType  `json:"borderType"`
+	FontFamily FontFamily  `json:"fontFamily"`
+	FontColor  FontColor   `json:"color"`
+	Mode       MoulageMode `json:"mode"`
+	ZoomCode   ZoomCode    `json:"zoomCode"`
 }
 
 // URI .
@@ -201,18 +207,18 @@ func (p YhtCreatePersonMoulageReq) Method() string {
 
 // 云合同企业印章形状
 const (
-	YHTCMStyleTypeCircle  = "1" // 圆形
-	YHTCMStyleTypeEllipse = "2" // 椭圆
+	YHTCMStyleTypeCircle  SealStyle = "1" // 圆形
+	YHTCMStyleTypeEllipse SealStyle = "2" // 椭圆
 )
 
 // YhtCreateCompanyMoulageReq 云合同创建企业印章请求
 type YhtCreateCompanyMoulageReq struct {
-	SignerID    string `json:"signerId"`
-	StyleType   string `json:"styleType"`
-	TextContent string `json:"textContent"` // 横向文案
-	KeyContent  string `json:"keyContent"`  // 防伪码，13位数字
-	FontColor   string `json:"color"`
-	Mode        string `json:"mode"`
+	SignerID    string      `json:"signerId"`
+	StyleType   SealStyle   `json:"styleType"`
+	TextContent string      `json:"textContent"` // 横向文案
+	KeyContent  string      `json:"keyContent"`  // 防伪码，13位数字
+	FontColor   FontColor   `json:"color"`
+	Mode        MoulageMode `json:"mode"`
 }
 
 // URI .
@@ -236,12 +242,33 @@ type YhtCreateMoulageResp struct {
 	Data MoulageIDResp `json:"data"`
 }
 
+// YhtQueryMoulagesReq 云合同查询用户印章请求
+type YhtQueryMoulagesReq struct {
+	SignerID string `json:"signerId"`
+}
+
+// URI .
+func (p YhtQueryMoulagesReq) URI() string {
+	return "/user/moulages"
+}
+
+// Method .
+func (p YhtQueryMoulagesReq) Method() string {
+	return http.MethodPost
+}
+
+// YhtQueryMoulagesResp 云合同查询用户印章应答，按创建顺序排列
+type YhtQueryMoulagesResp struct {
+	YhtBaseResp
+	Data []MoulageIDResp `json:"data"`
+}
+
 // YhtCreateTemplateContractReq 云合同根据模板生成合同请求
 type YhtCreateTemplateContractReq struct {
 	Title        string      `json:"contractTitle"` // 合同标题
 	ContractNo   string      `json:"contractNo"`    // 自定义合同编号
 	TemplateID   string      `json:"templateId"`    // 模板ID
-	ContractData interface{} `json:"contractData"`  // 合同参数
+	ContractData interface{} `json:"contractData"`  // 合同参数，可以是占位符map或带yht标签的结构体，见BindTemplateData
 }
 
 // URI .
@@ -273,37 +300,37 @@ const (
 
 // 签署定位方式
 const (
-	YHTSignPositionTypeKeyWord     = "0" // 关键字定位
-	YHTSignPositionTypePlaceHolder = "1" // 占位符定位
-	YHTSignPositionTypeCoord       = "2" // 坐标定位
+	YHTSignPositionTypeKeyWord     SignPositionType = "0" // 关键字定位
+	YHTSignPositionTypePlaceHolder SignPositionType = "1" // 占位符定位
+	YHTSignPositionTypeCoord       SignPositionType = "2" // 坐标定位
 )
 
 // 签署验证方式
 const (
-	YHTSignValidateTypeIgnore = "0" // 不校验
-	YHTSignValidateTypeSMS    = "1" // 短信验证
+	YHTSignValidateTypeIgnore SignValidateType = "0" // 不校验
+	YHTSignValidateTypeSMS    SignValidateType = "1" // 短信验证
 )
 
 // 印章使用类型
 const (
-	YHTSignModeSpecify = "0" // 指定印章
-	YHTSignModeRender  = "1" // 每次绘制
+	YHTSignModeSpecify SignMode = "0" // 指定印章
+	YHTSignModeRender  SignMode = "1" // 每次绘制
 )
 
 // 合同签署形态
 const (
-	YHTSignFormJS = "0" // JS集成页面
-	YHTSignFormH5 = "1" // 独立H5页面
+	YHTSignFormJS SignForm = "0" // JS集成页面
+	YHTSignFormH5 SignForm = "1" // 独立H5页面
 )
 
-// YhtSigner .
+// YhtSigner 签署者，可以使用NewSigner构建
 type YhtSigner struct {
-	SignerID         string `json:"signerId"`
-	SignPositionType string `json:"signPositionType"` // 签署的定位方式：0=关键字定位，1=签名占位符定位，2=签署坐标
-	PositionContent  string `json:"positionContent"`
-	SignValidateType string `json:"signValidateType"` // 签署验证方式：0=不校验，1=短信验证
-	SignMode         string `json:"signMode"`         // 印章使用类型（针对页面签署）：0=指定印章，1=每次绘制
-	SignForm         string `json:"signForm"`         // 签署形态，0=JS集成页面(默认)，1=独立H5页面
+	SignerID         string           `json:"signerId"`
+	SignPositionType SignPositionType `json:"signPositionType"` // 签署的定位方式：0=关键字定位，1=签名占位符定位，2=签署坐标
+	PositionContent  string           `json:"positionContent"`  // 关键字、签名占位符名称或坐标，坐标格式见FormatCoordinates
+	SignValidateType SignValidateType `json:"signValidateType"` // 签署验证方式：0=不校验，1=短信验证
+	SignMode         SignMode         `json:"signMode"`         // 印章使用类型（针对页面签署）：0=指定印章，1=每次绘制
+	SignForm         SignForm         `json:"signForm"`         // 签署形态，0=JS集成页面(默认)，1=独立H5页面
 }
 
 // YhtAddSignerReq 云合同添加签署者请求
@@ -325,20 +352,20 @@ func (p YhtAddSignerReq) Method() string {
 
 // 签章样式
 const (
-	YHTSealClassNormal       = "0" // 常规
-	YHTSealClassQF           = "1" // 骑缝
-	YHTSealClassWithAbstract = "2" // 含摘要
-	YHTSealClassWithSignTime = "3" // 含签署时间
-	YHTSealClassNormalWithQF = "4" // 常规+骑缝
+	YHTSealClassNormal       SealClass = "0" // 常规
+	YHTSealClassQF           SealClass = "1" // 骑缝
+	YHTSealClassWithAbstract SealClass = "2" // 含摘要
+	YHTSealClassWithSignTime SealClass = "3" // 含签署时间
+	YHTSealClassNormalWithQF SealClass = "4" // 常规+骑缝
 )
 
 // YhtSignContractReq 云合同签署合同请求
 type YhtSignContractReq struct {
-	IDType    string `json:"idType"`
-	IDContent string `json:"idContent"`
-	SignerID  string `json:"signerId"`
-	MoulageID string `json:"moulageId"`
-	SealClass string `json:"sealClass"` // 签章样式，0=常规样式，1=骑缝章，2=含摘要样式，3=含签署时间样式，4=常规样式+骑缝章，可选参数，不传时使用常规样式
+	IDType    string    `json:"idType"`
+	IDContent string    `json:"idContent"`
+	SignerID  string    `json:"signerId"`
+	MoulageID string    `json:"moulageId"`
+	SealClass SealClass `json:"sealClass"` // 签章样式，0=常规样式，1=骑缝章，2=含摘要样式，3=含签署时间样式，4=常规样式+骑缝章，可选参数，不传时使用常规样式
 }
 
 // URI .
@@ -351,6 +378,143 @@ func (p YhtSignContractReq) Method() string {
 	return http.MethodPost
 }
 
+// YhtContractDetailReq 云合同查询合同详情请求
+type YhtContractDetailReq struct {
+	IDType    string `json:"idType"`    // 合同ID类型，0 合同ID，1 合同自定义编号
+	IDContent string `json:"idContent"` // 合同ID
+}
+
+// URI .
+func (p YhtContractDetailReq) URI() string {
+	return "/contract/detail"
+}
+
+// Method .
+func (p YhtContractDetailReq) Method() string {
+	return http.MethodPost
+}
+
+// YhtContractSigner 合同签署者信息
+type YhtContractSigner struct {
+	SignerID   int        `json:"signerId"`
+	SignStatus SignStatus `json:"signStatus"` // 签署状态
+	SignTime   string     `json:"signTime"`   // 签署时间
+}
+
+// YhtContractDetail 合同详情
+type YhtContractDetail struct {
+	ContractID int                 `json:"contractId"`
+	ContractNo string              `json:"contractNo"`
+	Title      string              `json:"contractTitle"`
+	Status     ContractStatus      `json:"status"` // 合同状态
+	GmtCreate  string              `json:"gmtCreate"`
+	GmtModify  string              `json:"gmtModify"`
+	Signers    []YhtContractSigner `json:"signers"`
+}
+
+// YhtContractDetailResp 云合同查询合同详情应答
+type YhtContractDetailResp struct {
+	YhtBaseResp
+	Data YhtContractDetail `json:"data"`
+}
+
+// YhtListContractsReq 云合同查询合同列表请求
+type YhtListContractsReq struct {
+	PageNum  int            `json:"pageNum"`          // 页码，从1开始
+	PageSize int            `json:"pageSize"`         // 每页数量
+	Status   ContractStatus `json:"status,omitempty"` // 合同状态，可选参数，不传时查询全部
+}
+
+// URI .
+func (p YhtListContractsReq) URI() string {
+	return "/contract/list"
+}
+
+// Method .
+func (p YhtListContractsReq) Method() string {
+	return http.MethodPost
+}
+
+// YhtContractPage 合同分页列表
+type YhtContractPage struct {
+	Total     int                 `json:"total"`
+	Contracts []YhtContractDetail `json:"list"`
+}
+
+// YhtListContractsResp 云合同查询合同列表应答
+type YhtListContractsResp struct {
+	YhtBaseResp
+	Data YhtContractPage `json:"data"`
+}
+
+// YhtDownloadContractReq 云合同下载合同请求，应答为合同文件
+type YhtDownloadContractReq struct {
+	IDType    string `json:"idType"`
+	IDContent string `json:"idContent"`
+}
+
+// URI .
+func (p YhtDownloadContractReq) URI() string {
+	return "/contract/download"
+}
+
+// Method .
+func (p YhtDownloadContractReq) Method() string {
+	return http.MethodPost
+}
+
+// YhtInvalidateContractReq 云合同作废合同请求
+type YhtInvalidateContractReq struct {
+	IDType    string `json:"idType"`
+	IDContent string `json:"idContent"`
+	Remark    string `json:"remark,omitempty"` // 作废原因，可选参数
+}
+
+// URI .
+func (p YhtInvalidateContractReq) URI() string {
+	return "/contract/invalid"
+}
+
+// Method .
+func (p YhtInvalidateContractReq) Method() string {
+	return http.MethodPost
+}
+
+// YhtCreateFileContractReq 云合同上传文件创建合同请求，以multipart/form-data提交
+type YhtCreateFileContractReq struct {
+	Title       string    `json:"contractTitle"` // 合同标题
+	ContractNo  string    `json:"contractNo"`    // 自定义合同编号
+	FileName    string    `json:"-"`             // 文件名
+	File        []byte    `json:"-"`             // 合同文件，与Reader二选一
+	Reader      io.Reader `json:"-"`             // 合同文件内容，以流的方式上传
+	ContentType string    `json:"-"`             // 文件MIME类型，为空时自动检测，仅支持PDF、DOC和DOCX
+}
+
+// contractFile 返回要上传的文件，Reader优先于File
+func (p YhtCreateFileContractReq) contractFile() ContractFile {
+	r := p.Reader
+	if r == nil && len(p.File) > 0 {
+		r = bytes.NewReader(p.File)
+	}
+	return ContractFile{Name: p.FileName, ContentType: p.ContentType, Reader: r}
+}
+
+// URI .
+func (p YhtCreateFileContractReq) URI() string {
+	return "/contract/fileContract"
+}
+
+// Method .
+func (p YhtCreateFileContractReq) Method() string {
+	return http.MethodPost
+}
+
+// YhtCreateFileContractResp 上传文件创建合同应答
+type YhtCreateFileContractResp struct {
+	YhtBaseResp
+	Data ContractIDResp `json:"data"`
+}
+
 // AuthSerialNumResp 实名认证流水号
 type AuthSerialNumResp struct {
 	ID string `json:"id"`
@@ -560,12 +724,12 @@ type ListContractsResponse struct {
 	Message string `json:"message"`
 	Value   struct {
 		ContractList []struct {
-			ID          string `json:"id"`
-			Title       string `json:"title"`
-			Status      string `json:"status"`
-			AppName     string `json:"appName"`
-			GmtModify   string `json:"gmtModify"`
-			PartnerList string `json:"partnerList"`
+			ID          string         `json:"id"`
+			Title       string         `json:"title"`
+			Status      ContractStatus `json:"status"`
+			AppName     string         `json:"appName"`
+			GmtModify   string         `json:"gmtModify"`
+			PartnerList string         `json:"partnerList"`
 		} `json:"contractList"`
 	} `json:"value"`
 }
@@ -585,11 +749,11 @@ type LookupContractDetailResponse struct {
 	Message string `json:"message"`
 	Value   struct {
 		PartnerList []struct {
-			SignStatus string `json:"signStatus"`
-			UserID     string `json:"userId"`
+			SignStatus SignStatus `json:"signStatus"`
+			UserID     string     `json:"userId"`
 		} `json:"partnerList"`
-		Title  string `param:"title"`
-		Status string `json:"status"`
+		Title  string         `param:"title"`
+		Status ContractStatus `json:"status"`
 	} `json:"value"`
 }
 
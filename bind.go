@@ -0,0 +1,292 @@
+package goyht
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 模板数据绑定的默认时间格式
+const (
+	TemplateDateLayout     = "2006-01-02"
+	TemplateDateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// TemplateValuer 自定义类型在模板中的取值，优先于fmt.Stringer
+type TemplateValuer interface {
+	TemplateValue() string
+}
+
+// MissingFieldsError 必填的模板字段为零值
+type MissingFieldsError struct {
+	Fields []string // 缺失的占位符
+}
+
+// Error implements the error interface.
+func (e *MissingFieldsError) Error() string {
+	return "goyht: missing template fields " + strings.Join(e.Fields, ", ")
+}
+
+// Is reports whether target is ErrInvalidParam.
+func (e *MissingFieldsError) Is(target error) bool {
+	return target == ErrInvalidParam
+}
+
+// bindOptions yht标签中的选项
+type bindOptions struct {
+	required  bool
+	omitempty bool
+	decimals  int // 小数位数，-1表示不限制
+	layout    string
+	enum      []string
+}
+
+func parseBindTag(tag string) (string, bindOptions) {
+	parts := strings.Split(tag, ",")
+	opts := bindOptions{decimals: -1, layout: TemplateDateLayout}
+	for _, p := range parts[1:] {
+		key, value := p, ""
+		if i := strings.IndexByte(p, '='); i >= 0 {
+			key, value = p[:i], p[i+1:]
+		}
+		switch key {
+		case "required":
+			opts.required = true
+		case "omitempty":
+			opts.omitempty = true
+		case "money":
+			opts.decimals = 2
+		case "decimal":
+			opts.decimals, _ = strconv.Atoi(value)
+		case "datetime":
+			opts.layout = TemplateDateTimeLayout
+		case "layout":
+			opts.layout = value
+		case "enum":
+			opts.enum = strings.Split(value, "|")
+		}
+	}
+	return parts[0], opts
+}
+
+// placeholder 返回占位符，name未包含${}时自动添加
+func placeholder(name string) string {
+	if strings.HasPrefix(name, "${") {
+		return name
+	}
+	return "${" + name + "}"
+}
+
+// BindTemplateData 将带yht标签的结构体转换为模板占位符，标签格式为`yht:"name,选项..."`，
+// 键为"${name}"。支持的选项：
+//
+//	required      零值时报告缺失
+//	omitempty     零值时不输出
+//	money         保留两位小数
+//	decimal=N     保留N位小数
+//	datetime      时间格式为2006-01-02 15:04:05，默认为2006-01-02
+//	layout=L      自定义时间格式
+//	enum=A|B|C    整数或布尔值按下标输出对应的文字
+//
+// 未加标签的嵌入结构体会被展开，缺失的必填字段以*MissingFieldsError一并返回
+func BindTemplateData(v interface{}) (M, error) {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: need a struct type, got %T", ErrInvalidParam, v)
+	}
+	result := M{}
+	missing := []string{}
+	if err := bindStruct(val, result, &missing); err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return result, &MissingFieldsError{Fields: missing}
+	}
+	return result, nil
+}
+
+func bindStruct(val reflect.Value, result M, missing *[]string) error {
+	typ := val.Type()
+	for i := 0; i < val.NumField(); i++ {
+		sf := typ.Field(i)
+		tag, ok := sf.Tag.Lookup("yht")
+		if !ok {
+			fv := val.Field(i)
+			if sf.Anonymous && fv.Kind() == reflect.Struct {
+				if err := bindStruct(fv, result, missing); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		name, opts := parseBindTag(tag)
+		if name == "-" || sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		key := placeholder(name)
+
+		raw := val.Field(i)
+		fv := raw
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if isZero(fv) {
+			if opts.required {
+				*missing = append(*missing, key)
+				continue
+			}
+			if opts.omitempty {
+				continue
+			}
+			if fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface || fv.Kind() == reflect.String {
+				result[key] = ""
+				continue
+			}
+		}
+		s, err := formatTemplateValue(raw, opts)
+		if err != nil {
+			return fmt.Errorf("%w: field %s: %v", ErrInvalidParam, sf.Name, err)
+		}
+		result[key] = s
+	}
+	return nil
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// formatTemplateValue 按选项格式化字段的值
+func formatTemplateValue(v reflect.Value, opts bindOptions) (string, error) {
+	// 先按指针本身的方法格式化，再逐层解引用
+	for {
+		if v.CanInterface() {
+			switch x := v.Interface().(type) {
+			case time.Time:
+				if x.IsZero() {
+					return "", nil
+				}
+				return x.Format(opts.layout), nil
+			case TemplateValuer:
+				return x.TemplateValue(), nil
+			case *big.Float:
+				return formatDecimal(x.Text('f', -1), opts.decimals)
+			case *big.Rat:
+				return formatDecimal(x.RatString(), opts.decimals)
+			}
+		}
+		if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+			break
+		}
+		v = v.Elem()
+	}
+
+	if opts.enum != nil {
+		idx := -1
+		switch v.Kind() {
+		case reflect.Bool:
+			if v.Bool() {
+				idx = 1
+			} else {
+				idx = 0
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			idx = int(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			idx = int(v.Uint())
+		}
+		if idx < 0 || idx >= len(opts.enum) {
+			return "", fmt.Errorf("value %d out of enum %v", idx, opts.enum)
+		}
+		return opts.enum[idx], nil
+	}
+
+	if v.CanInterface() {
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			if opts.decimals >= 0 {
+				return formatDecimal(s.String(), opts.decimals)
+			}
+			return s.String(), nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if opts.decimals >= 0 {
+			return formatDecimal(v.String(), opts.decimals)
+		}
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if opts.decimals >= 0 {
+			return formatDecimal(strconv.FormatInt(v.Int(), 10), opts.decimals)
+		}
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if opts.decimals >= 0 {
+			return formatDecimal(strconv.FormatUint(v.Uint(), 10), opts.decimals)
+		}
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		// 取浮点数的最短十进制表示后按十进制舍入，与字符串和big.Rat一致，2.675舍入为2.68
+		text := strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+		if opts.decimals >= 0 {
+			return formatDecimal(text, opts.decimals)
+		}
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// formatDecimal 将十进制数或分数字符串四舍五入到decimals位小数
+func formatDecimal(s string, decimals int) (string, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return "", fmt.Errorf("%q is not a decimal", s)
+	}
+	if decimals < 0 {
+		f, _ := r.Float64()
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+	return r.FloatString(decimals), nil
+}
+
+// templateData 返回合同参数，带yht标签的结构体转换为占位符
+func templateData(data interface{}) (interface{}, error) {
+	val := reflect.ValueOf(data)
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct || !hasBindTag(val.Type()) {
+		return data, nil
+	}
+	return BindTemplateData(data)
+}
+
+// hasBindTag 判断结构体是否有yht标签的字段
+func hasBindTag(typ reflect.Type) bool {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if _, ok := sf.Tag.Lookup("yht"); ok {
+			return true
+		}
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && hasBindTag(sf.Type) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package goyht
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type payCycle int
+
+func (p payCycle) TemplateValue() string {
+	return [...]string{"月付", "季付"}[p]
+}
+
+type party struct {
+	Name  string `yht:"lessee,required"`
+	Phone string `yht:"lesseePhone,omitempty"`
+}
+
+type leaseData struct {
+	party
+	Start    time.Time  `yht:"date"`
+	End      *time.Time `yht:"endDate,layout=2006年01月02日"`
+	Months   int        `yht:"leasemonths"`
+	Rent     float64    `yht:"monthlyRent,money"`
+	Deposit  string     `yht:"deposit,money"`
+	Total    *big.Rat   `yht:"aggregaterents,money"`
+	Cycle    payCycle   `yht:"paycycle"`
+	Furnish  bool       `yht:"furnished,enum=否|是"`
+	Room     string     `yht:"roomid,required"`
+	internal string
+}
+
+func TestBindTemplateData(t *testing.T) {
+	start := time.Date(2026, 1, 2, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 2, 0)
+	data := &leaseData{
+		party:   party{Name: "Mike"},
+		Start:   start,
+		End:     &end,
+		Months:  2,
+		Rent:    100,
+		Deposit: "99.995",
+		Total:   big.NewRat(401, 2),
+		Cycle:   1,
+		Furnish: true,
+		Room:    "A-101",
+	}
+	m, err := BindTemplateData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := M{
+		"${lessee}":         "Mike",
+		"${date}":           "2026-01-02",
+		"${endDate}":        "2026年03月02日",
+		"${leasemonths}":    "2",
+		"${monthlyRent}":    "100.00",
+		"${deposit}":        "100.00",
+		"${aggregaterents}": "200.50",
+		"${paycycle}":       "季付",
+		"${furnished}":      "是",
+		"${roomid}":         "A-101",
+	}
+	if !reflect.DeepEqual(m, expect) {
+		t.Fatalf("unexpected data %v", m)
+	}
+
+	// 浮点数与字符串按同样的十进制规则舍入
+	money := struct {
+		A float64 `yht:"a,money"`
+		B float32 `yht:"b,money"`
+		C string  `yht:"c,money"`
+	}{2.675, 1.005, "2.675"}
+	if m, err = BindTemplateData(money); err != nil || m["${a}"] != "2.68" || m["${b}"] != "1.01" || m["${c}"] != "2.68" {
+		t.Fatalf("unexpected money %v %v", m, err)
+	}
+
+	_, err = BindTemplateData(leaseData{})
+	var missing *MissingFieldsError
+	if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Fields, []string{"${lessee}", "${roomid}"}) || !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("expect missing fields, got %v", err)
+	}
+	if _, err = BindTemplateData(M{}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("expect invalid param, got %v", err)
+	}
+}
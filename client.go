@@ -2,18 +2,21 @@ package goyht
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/leesper/holmes"
+	"github.com/iotdog/goyht/validate"
 )
 
 // M is a convenient alias for a map[string]interface{} map.
@@ -46,14 +49,15 @@ const (
 )
 
 // Config contains configurations about YunHeTong service.
+// 使用LoadConfig从配置文件和环境变量读取，String和%v输出时隐藏密钥。
 type Config struct {
-	AppID       string // API V4使用
-	AppKey      string // API V4使用
-	Password    string
-	APIGateway  string // API V4使用
-	AuthID      string
-	AuthPWD     string
-	AuthGateway string // API V4使用
+	AppID       string // 应用ID，V3和V4接口共用
+	AppKey      string // 应用密钥，V4接口使用
+	Password    string // 应用密码，V3接口使用
+	APIGateway  string // 接口网关，默认为YHTAPIGatewayV4，V3接口为YHTAPIGateway
+	AuthID      string // 实名认证账号，AuthRealName使用
+	AuthPWD     string // 实名认证密码，AuthRealName使用
+	AuthGateway string // 实名认证网关，默认为YHTAuthGateway
 }
 
 var (
@@ -61,25 +65,22 @@ var (
 	yhtClient *Client
 )
 
-// updateLTTRoutine 每隔14分钟更新平台长效令牌
-func updateLTTRoutine(yhtClient *Client) {
-	for true {
-		yhtClient.updateLongTimeToken()
-		time.Sleep(14 * time.Minute)
+// InitYHTClient 初始化云合同客户端，平台长效令牌在后台获取，首次请求时等待登录完成。
+// 需要确认登录成功时使用InitYHTClientCtx
+func InitYHTClient(appID, appKey string) {
+	if yhtClient != nil {
+		yhtClient.Close()
 	}
+	yhtClient = NewClient(Config{
+		AppID:  appID,
+		AppKey: appKey,
+	})
 }
 
-// InitYHTClient 初始化云合同客户端，该方法只可调用一次
-func InitYHTClient(appID, appKey string) {
-	yhtClient = newClient(Config{
-		AppID:       appID,
-		AppKey:      appKey,
-		APIGateway:  YHTAPIGatewayV4,
-		AuthGateway: YHTAuthGateway,
-	})
-	// 开启一个goroutine更新平台长效令牌
-	go updateLTTRoutine(yhtClient)
-	// yhtClient.updateLongTimeToken() // 测试
+// InitYHTClientCtx 初始化云合同客户端并登录获取平台长效令牌，登录失败时返回error
+func InitYHTClientCtx(ctx context.Context, appID, appKey string) error {
+	InitYHTClient(appID, appKey)
+	return yhtClient.Authenticate(ctx)
 }
 
 // GetClient 获取云合同客户端
@@ -92,52 +93,99 @@ func GetClient() *Client {
 
 // Client handles all APIs for YunHeTong service.
 type Client struct {
-	config    Config
-	tlsClient http.Client
-	ltt       string // 平台的长效令牌（Long Time Token），有效期15分钟
+	config       Config
+	tlsClient    *http.Client
+	logger       Logger
+	refresher    TokenRefresher
+	interval     time.Duration
+	retry        RetryPolicy
+	maxDownload  int64
+	validation   bool
+	templates    *TemplateRegistry
+	tokens       *tokenManager // 平台的长效令牌（Long Time Token），有效期15分钟
+	userToks     *userTokenCache
+	dupUserCodes map[int]bool
 }
 
-// newClient returns a *Client.
-func newClient(cfg Config) *Client {
+// NewClient returns a *Client configured by cfg and opts. Each client is
+// independent, so several YunHeTong apps can be served in one process.
+// When cfg carries V4 credentials (AppID/AppKey), a goroutine is started to
+// refresh the platform long time token, call Close to stop it.
+func NewClient(cfg Config, opts ...Option) *Client {
+	if cfg.APIGateway == "" {
+		cfg.APIGateway = YHTAPIGatewayV4
+	}
+	if cfg.AuthGateway == "" {
+		cfg.AuthGateway = YHTAuthGateway
+	}
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: false},
 	}
-	client := http.Client{Transport: tr}
-	return &Client{
-		config:    cfg,
-		tlsClient: client,
-		ltt:       "",
+	c := &Client{
+		config:     cfg,
+		tlsClient:  &http.Client{Transport: tr},
+		logger:     holmesLogger{},
+		interval:   defaultTokenRefreshInterval,
+		retry:      DefaultRetryPolicy,
+		validation: true,
+		userToks:   newUserTokenCache(defaultUserTokenCacheSize, defaultUserTokenTTL),
+	}
+	if cfg.AppID != "" && cfg.AppKey != "" {
+		c.refresher = platformLogin{c}
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	c.tokens = newTokenManager(c.refresher, c.logger, c.interval)
+	if c.refresher != nil {
+		// 开启一个goroutine更新平台长效令牌
+		c.tokens.start()
+	}
+	return c
 }
 
-// updateLongTimeToken 更新长效令牌
-func (c *Client) updateLongTimeToken() {
-	req := yhtAuthLoginReq{
-		AppID:  c.config.AppID,
-		AppKey: c.config.AppKey,
+// Authenticate 阻塞获取平台长效令牌，令牌已存在时直接返回
+func (c *Client) Authenticate(ctx context.Context) error {
+	if c.refresher == nil {
+		return errNoTokenRefresher
 	}
-	jsonData, err := json.Marshal(req)
-	if err != nil {
-		holmes.Debugln(err)
-		return
-	}
-	ret, ltt, err := httpRequestV4(c, "", req.URI(), req.Method(), jsonData, func() interface{} {
-		return &YhtBaseResp{}
+	_, err := c.tokens.Token(ctx)
+	return err
+}
+
+// Close 停止后台刷新平台长效令牌
+func (c *Client) Close() error {
+	c.tokens.Close()
+	return nil
+}
+
+// callV4 使用平台长效令牌请求V4接口，令牌过期或无效时重新登录并重放一次请求
+func (c *Client) callV4(ctx context.Context, uri, method string, jsonData []byte, factory func() interface{}) (interface{}, error) {
+	return c.withToken(ctx, uri, func(token string) (interface{}, error) {
+		ret, _, err := httpRequestV4(ctx, c, token, uri, method, jsonData, factory)
+		return ret, err
 	})
+}
+
+// withToken 使用平台长效令牌执行do，令牌过期或无效时重新登录并重放一次
+func (c *Client) withToken(ctx context.Context, uri string, do func(token string) (interface{}, error)) (interface{}, error) {
+	token, err := c.tokens.Token(ctx)
 	if err != nil {
-		holmes.Debugln(err)
-	} else {
-		resp := ret.(*YhtBaseResp)
-		if 200 == resp.Code {
-			c.ltt = ltt // 保存token
-		} else {
-			holmes.Debugln(resp)
-		}
+		return nil, err
+	}
+	ret, err := do(token)
+	if !IsTokenExpired(err) || c.refresher == nil {
+		return ret, err
 	}
+	c.logger.Debugln("token rejected, login again", uri)
+	if token, err = c.tokens.renew(ctx, token); err != nil {
+		return nil, err
+	}
+	return do(token)
 }
 
-// UserTokenV4 用户登录
-func (c *Client) UserTokenV4(signerID string) (*YhtBaseResp, string, error) {
+// login 使用AppID和AppKey登录，signerID为空时获取平台的长效令牌
+func (c *Client) login(ctx context.Context, signerID string) (*YhtBaseResp, string, error) {
 	req := yhtAuthLoginReq{
 		AppID:    c.config.AppID,
 		AppKey:   c.config.AppKey,
@@ -145,28 +193,76 @@ func (c *Client) UserTokenV4(signerID string) (*YhtBaseResp, string, error) {
 	}
 	jsonData, err := json.Marshal(req)
 	if err != nil {
-		holmes.Debugln(err)
 		return nil, "", err
 	}
-	ret, ltt, err := httpRequestV4(c, "", req.URI(), req.Method(), jsonData, func() interface{} {
+	ret, ltt, err := httpRequestV4(ctx, c, "", req.URI(), req.Method(), jsonData, func() interface{} {
 		return &YhtBaseResp{}
 	})
 	if err != nil {
 		return nil, "", err
 	}
-	return ret.(*YhtBaseResp), ltt, err
+	return ret.(*YhtBaseResp), ltt, nil
+}
+
+// UserTokenV4 用户登录，返回的令牌按signerID缓存至过期前
+func (c *Client) UserTokenV4(signerID string) (*YhtBaseResp, string, error) {
+	return c.UserTokenV4Ctx(context.Background(), signerID)
+}
+
+// UserTokenV4Ctx is like UserTokenV4 but carries ctx through the HTTP request.
+func (c *Client) UserTokenV4Ctx(ctx context.Context, signerID string) (*YhtBaseResp, string, error) {
+	login := c.login
+	if c.userToks != nil {
+		login = func(ctx context.Context, signerID string) (*YhtBaseResp, string, error) {
+			return c.userToks.get(ctx, signerID, c.login)
+		}
+	}
+	resp, ltt, err := login(ctx, signerID)
+	if err != nil {
+		c.logger.Debugln(err)
+		return nil, "", err
+	}
+	return resp, ltt, nil
+}
+
+// InvalidateUserTokenV4 删除signerID缓存的用户令牌，下次调用UserTokenV4时重新登录
+func (c *Client) InvalidateUserTokenV4(signerID string) {
+	if c.userToks != nil {
+		c.userToks.remove(signerID)
+	}
+}
+
+// UserTokenCacheStats 返回用户令牌缓存的统计数据
+func (c *Client) UserTokenCacheStats() UserTokenCacheStats {
+	if c.userToks == nil {
+		return UserTokenCacheStats{}
+	}
+	return c.userToks.stats()
 }
 
 // CreatePersonV4 创建个人用户
 func (c *Client) CreatePersonV4(req *YhtCreatePersonReq) (*YhtCreateUserResp, error) {
+	return c.CreatePersonV4Ctx(context.Background(), req)
+}
+
+// CreatePersonV4Ctx is like CreatePersonV4 but carries ctx through the HTTP request.
+func (c *Client) CreatePersonV4Ctx(ctx context.Context, req *YhtCreatePersonReq) (*YhtCreateUserResp, error) {
 	if nil == req {
-		return nil, errors.New("invalid parameter")
+		return nil, ErrInvalidParam
+	}
+	r := *req
+	r.CertNum = normalizePersonCertNum(r.CertType, r.CertNum)
+	req = &r
+	if c.validation {
+		if err := ValidatePerson(req); err != nil {
+			return nil, err
+		}
 	}
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	ret, _, err := httpRequestV4(c, c.ltt, req.URI(), req.Method(), jsonData, func() interface{} {
+	ret, err := c.callV4(ctx, req.URI(), req.Method(), jsonData, func() interface{} {
 		return &YhtCreateUserResp{}
 	})
 	if err != nil {
@@ -177,14 +273,24 @@ func (c *Client) CreatePersonV4(req *YhtCreatePersonReq) (*YhtCreateUserResp, er
 
 // CreateCompanyV4 创建企业用户
 func (c *Client) CreateCompanyV4(req *YhtCreateCompanyReq) (*YhtCreateUserResp, error) {
+	return c.CreateCompanyV4Ctx(context.Background(), req)
+}
+
+// CreateCompanyV4Ctx is like CreateCompanyV4 but carries ctx through the HTTP request.
+func (c *Client) CreateCompanyV4Ctx(ctx context.Context, req *YhtCreateCompanyReq) (*YhtCreateUserResp, error) {
 	if nil == req {
-		return nil, errors.New("invalid parameter")
+		return nil, ErrInvalidParam
+	}
+	if c.validation {
+		if err := ValidateCompany(req); err != nil {
+			return nil, err
+		}
 	}
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	ret, _, err := httpRequestV4(c, c.ltt, req.URI(), req.Method(), jsonData, func() interface{} {
+	ret, err := c.callV4(ctx, req.URI(), req.Method(), jsonData, func() interface{} {
 		return &YhtCreateUserResp{}
 	})
 	if err != nil {
@@ -195,14 +301,19 @@ func (c *Client) CreateCompanyV4(req *YhtCreateCompanyReq) (*YhtCreateUserResp,
 
 // QuerySignerID 查询与合同平台用户ID
 func (c *Client) QuerySignerID(req *YhtQuerySignerIDReq) (*YhtQuerySignerIDResp, error) {
+	return c.QuerySignerIDCtx(context.Background(), req)
+}
+
+// QuerySignerIDCtx is like QuerySignerID but carries ctx through the HTTP request.
+func (c *Client) QuerySignerIDCtx(ctx context.Context, req *YhtQuerySignerIDReq) (*YhtQuerySignerIDResp, error) {
 	if nil == req {
-		return nil, errors.New("invalid parameter")
+		return nil, ErrInvalidParam
 	}
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	ret, _, err := httpRequestV4(c, c.ltt, req.URI(), req.Method(), jsonData, func() interface{} {
+	ret, err := c.callV4(ctx, req.URI(), req.Method(), jsonData, func() interface{} {
 		return &YhtQuerySignerIDResp{}
 	})
 	if err != nil {
@@ -213,14 +324,24 @@ func (c *Client) QuerySignerID(req *YhtQuerySignerIDReq) (*YhtQuerySignerIDResp,
 
 // CreatePersonMoulageV4 创建个人印章
 func (c *Client) CreatePersonMoulageV4(req *YhtCreatePersonMoulageReq) (*YhtCreateMoulageResp, error) {
+	return c.CreatePersonMoulageV4Ctx(context.Background(), req)
+}
+
+// CreatePersonMoulageV4Ctx is like CreatePersonMoulageV4 but carries ctx through the HTTP request.
+func (c *Client) CreatePersonMoulageV4Ctx(ctx context.Context, req *YhtCreatePersonMoulageReq) (*YhtCreateMoulageResp, error) {
 	if nil == req {
-		return nil, errors.New("invalid parameter")
+		return nil, ErrInvalidParam
+	}
+	if c.validation {
+		if err := ValidatePersonMoulage(req); err != nil {
+			return nil, err
+		}
 	}
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	ret, _, err := httpRequestV4(c, c.ltt, req.URI(), req.Method(), jsonData, func() interface{} {
+	ret, err := c.callV4(ctx, req.URI(), req.Method(), jsonData, func() interface{} {
 		return &YhtCreateMoulageResp{}
 	})
 	if err != nil {
@@ -231,14 +352,24 @@ func (c *Client) CreatePersonMoulageV4(req *YhtCreatePersonMoulageReq) (*YhtCrea
 
 // CreateCompanyMoulageV4 创建企业印章
 func (c *Client) CreateCompanyMoulageV4(req *YhtCreateCompanyMoulageReq) (*YhtCreateMoulageResp, error) {
+	return c.CreateCompanyMoulageV4Ctx(context.Background(), req)
+}
+
+// CreateCompanyMoulageV4Ctx is like CreateCompanyMoulageV4 but carries ctx through the HTTP request.
+func (c *Client) CreateCompanyMoulageV4Ctx(ctx context.Context, req *YhtCreateCompanyMoulageReq) (*YhtCreateMoulageResp, error) {
 	if nil == req {
-		return nil, errors.New("invalid parameter")
+		return nil, ErrInvalidParam
+	}
+	if c.validation {
+		if err := ValidateCompanyMoulage(req); err != nil {
+			return nil, err
+		}
 	}
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	ret, _, err := httpRequestV4(c, c.ltt, req.URI(), req.Method(), jsonData, func() interface{} {
+	ret, err := c.callV4(ctx, req.URI(), req.Method(), jsonData, func() interface{} {
 		return &YhtCreateMoulageResp{}
 	})
 	if err != nil {
@@ -247,16 +378,53 @@ func (c *Client) CreateCompanyMoulageV4(req *YhtCreateCompanyMoulageReq) (*YhtCr
 	return ret.(*YhtCreateMoulageResp), nil
 }
 
+// QueryMoulagesV4 查询用户的印章
+func (c *Client) QueryMoulagesV4(req *YhtQueryMoulagesReq) (*YhtQueryMoulagesResp, error) {
+	return c.QueryMoulagesV4Ctx(context.Background(), req)
+}
+
+// QueryMoulagesV4Ctx is like QueryMoulagesV4 but carries ctx through the HTTP request.
+func (c *Client) QueryMoulagesV4Ctx(ctx context.Context, req *YhtQueryMoulagesReq) (*YhtQueryMoulagesResp, error) {
+	if nil == req || req.SignerID == "" {
+		return nil, ErrInvalidParam
+	}
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := c.callV4(ctx, req.URI(), req.Method(), jsonData, func() interface{} {
+		return &YhtQueryMoulagesResp{}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret.(*YhtQueryMoulagesResp), nil
+}
+
 // CreateContractFromTemplateV4 根据模板创建合同
 func (c *Client) CreateContractFromTemplateV4(req *YhtCreateTemplateContractReq) (*YhtCreateTemplateContractResp, error) {
+	return c.CreateContractFromTemplateV4Ctx(context.Background(), req)
+}
+
+// CreateContractFromTemplateV4Ctx is like CreateContractFromTemplateV4 but carries ctx through the HTTP request.
+func (c *Client) CreateContractFromTemplateV4Ctx(ctx context.Context, req *YhtCreateTemplateContractReq) (*YhtCreateTemplateContractResp, error) {
 	if nil == req {
-		return nil, errors.New("invalid parameter")
+		return nil, ErrInvalidParam
 	}
-	jsonData, err := json.Marshal(req)
+	data, err := templateData(req.ContractData)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.checkTemplate(req.TemplateID, data); err != nil {
+		return nil, err
+	}
+	bound := *req
+	bound.ContractData = data
+	jsonData, err := json.Marshal(bound)
 	if err != nil {
 		return nil, err
 	}
-	ret, _, err := httpRequestV4(c, c.ltt, req.URI(), req.Method(), jsonData, func() interface{} {
+	ret, err := c.callV4(ctx, req.URI(), req.Method(), jsonData, func() interface{} {
 		return &YhtCreateTemplateContractResp{}
 	})
 	if err != nil {
@@ -267,14 +435,26 @@ func (c *Client) CreateContractFromTemplateV4(req *YhtCreateTemplateContractReq)
 
 // AddSignerV4 添加签署者
 func (c *Client) AddSignerV4(req *YhtAddSignerReq) (*YhtBaseResp, error) {
+	return c.AddSignerV4Ctx(context.Background(), req)
+}
+
+// AddSignerV4Ctx is like AddSignerV4 but carries ctx through the HTTP request.
+func (c *Client) AddSignerV4Ctx(ctx context.Context, req *YhtAddSignerReq) (*YhtBaseResp, error) {
 	if nil == req {
-		return nil, errors.New("invalid parameter")
+		return nil, ErrInvalidParam
+	}
+	if c.validation {
+		for _, signer := range req.Signers {
+			if err := ValidateSigner(signer); err != nil {
+				return nil, err
+			}
+		}
 	}
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	ret, _, err := httpRequestV4(c, c.ltt, req.URI(), req.Method(), jsonData, func() interface{} {
+	ret, err := c.callV4(ctx, req.URI(), req.Method(), jsonData, func() interface{} {
 		return &YhtBaseResp{}
 	})
 	if err != nil {
@@ -285,14 +465,102 @@ func (c *Client) AddSignerV4(req *YhtAddSignerReq) (*YhtBaseResp, error) {
 
 // SignContractV4 签署合同（V4版本）
 func (c *Client) SignContractV4(req *YhtSignContractReq) (*YhtBaseResp, error) {
+	return c.SignContractV4Ctx(context.Background(), req)
+}
+
+// SignContractV4Ctx is like SignContractV4 but carries ctx through the HTTP request.
+func (c *Client) SignContractV4Ctx(ctx context.Context, req *YhtSignContractReq) (*YhtBaseResp, error) {
 	if nil == req {
-		return nil, errors.New("invalid parameter")
+		return nil, ErrInvalidParam
+	}
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := c.callV4(ctx, req.URI(), req.Method(), jsonData, func() interface{} {
+		return &YhtBaseResp{}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret.(*YhtBaseResp), nil
+}
+
+// LookupContractDetailV4 查询合同详情
+func (c *Client) LookupContractDetailV4(req *YhtContractDetailReq) (*YhtContractDetailResp, error) {
+	return c.LookupContractDetailV4Ctx(context.Background(), req)
+}
+
+// LookupContractDetailV4Ctx is like LookupContractDetailV4 but carries ctx through the HTTP request.
+func (c *Client) LookupContractDetailV4Ctx(ctx context.Context, req *YhtContractDetailReq) (*YhtContractDetailResp, error) {
+	if nil == req || !validIDType(req.IDType) {
+		return nil, ErrInvalidParam
+	}
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := c.callV4(ctx, req.URI(), req.Method(), jsonData, func() interface{} {
+		return &YhtContractDetailResp{}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret.(*YhtContractDetailResp), nil
+}
+
+// ListContractsV4 分页查询合同列表
+func (c *Client) ListContractsV4(req *YhtListContractsReq) (*YhtListContractsResp, error) {
+	return c.ListContractsV4Ctx(context.Background(), req)
+}
+
+// ListContractsV4Ctx is like ListContractsV4 but carries ctx through the HTTP request.
+func (c *Client) ListContractsV4Ctx(ctx context.Context, req *YhtListContractsReq) (*YhtListContractsResp, error) {
+	if nil == req || req.PageNum < 1 || req.PageSize < 1 {
+		return nil, ErrInvalidParam
+	}
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := c.callV4(ctx, req.URI(), req.Method(), jsonData, func() interface{} {
+		return &YhtListContractsResp{}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret.(*YhtListContractsResp), nil
+}
+
+// DownloadContractV4 下载合同文件
+func (c *Client) DownloadContractV4(req *YhtDownloadContractReq) ([]byte, error) {
+	return c.DownloadContractV4Ctx(context.Background(), req)
+}
+
+// DownloadContractV4Ctx is like DownloadContractV4 but carries ctx through the HTTP request.
+func (c *Client) DownloadContractV4Ctx(ctx context.Context, req *YhtDownloadContractReq) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if _, err := c.DownloadContractTo(ctx, req, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// InvalidateContractV4 作废合同
+func (c *Client) InvalidateContractV4(req *YhtInvalidateContractReq) (*YhtBaseResp, error) {
+	return c.InvalidateContractV4Ctx(context.Background(), req)
+}
+
+// InvalidateContractV4Ctx is like InvalidateContractV4 but carries ctx through the HTTP request.
+func (c *Client) InvalidateContractV4Ctx(ctx context.Context, req *YhtInvalidateContractReq) (*YhtBaseResp, error) {
+	if nil == req || !validIDType(req.IDType) {
+		return nil, ErrInvalidParam
 	}
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
-	ret, _, err := httpRequestV4(c, c.ltt, req.URI(), req.Method(), jsonData, func() interface{} {
+	ret, err := c.callV4(ctx, req.URI(), req.Method(), jsonData, func() interface{} {
 		return &YhtBaseResp{}
 	})
 	if err != nil {
@@ -301,8 +569,57 @@ func (c *Client) SignContractV4(req *YhtSignContractReq) (*YhtBaseResp, error) {
 	return ret.(*YhtBaseResp), nil
 }
 
+// CreateFileContractV4 上传文件创建合同
+func (c *Client) CreateFileContractV4(req *YhtCreateFileContractReq) (*YhtCreateFileContractResp, error) {
+	return c.CreateFileContractV4Ctx(context.Background(), req)
+}
+
+// CreateFileContractV4Ctx is like CreateFileContractV4 but carries ctx through the HTTP request.
+func (c *Client) CreateFileContractV4Ctx(ctx context.Context, req *YhtCreateFileContractReq) (*YhtCreateFileContractResp, error) {
+	if nil == req {
+		return nil, ErrInvalidParam
+	}
+	file := req.contractFile()
+	if file.Reader == nil {
+		return nil, ErrInvalidParam
+	}
+	fields := map[string]string{
+		"contractTitle": req.Title,
+		"contractNo":    req.ContractNo,
+	}
+	rewind := rewinder(file.Reader)
+	ret, err := c.withToken(ctx, req.URI(), func(token string) (interface{}, error) {
+		// 令牌失效重放时需要从头读取文件
+		if err := rewind(); err != nil {
+			return nil, err
+		}
+		return httpMultipartV4(ctx, c, token, req.URI(), fields, file, func() interface{} {
+			return &YhtCreateFileContractResp{}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret.(*YhtCreateFileContractResp), nil
+}
+
 // AuthRealNameMobileV4 运营商三要素认证，认证成功返回nil，否则返回error
 func (c *Client) AuthRealNameMobileV4(idNo, idName, phone string) error {
+	return c.AuthRealNameMobileV4Ctx(context.Background(), idNo, idName, phone)
+}
+
+// AuthRealNameMobileV4Ctx is like AuthRealNameMobileV4 but carries ctx through the HTTP request.
+func (c *Client) AuthRealNameMobileV4Ctx(ctx context.Context, idNo, idName, phone string) error {
+	idNo = validate.NormalizeCertNum(idNo)
+	phone = validate.NormalizeMobile(phone)
+	if c.validation {
+		if err := validateResidentID(idNo); err != nil {
+			return err
+		}
+		if err := validateMobile(phone); err != nil {
+			return err
+		}
+	}
 	uri := "/authentic/personal/mobile/realName"
 	req := map[string]string{
 		"appId":  c.config.AppID,
@@ -311,16 +628,16 @@ func (c *Client) AuthRealNameMobileV4(idNo, idName, phone string) error {
 		"idName": idName,
 		"mobile": phone,
 	}
-	ret, err := httpRequest(c, uri, req, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, uri, req, nil, func() interface{} {
 		return &AuthRealNameResp{}
 	})
 	if err != nil {
 		return err
 	}
 	resp := ret.(*AuthRealNameResp)
-	if 200 != resp.Code {
-		holmes.Debugln(resp)
-		return errors.New(resp.Message())
+	if !resp.Success() {
+		c.logger.Debugln(resp)
+		return info.apiError(resp.Code, 0, decodeMsg(resp.RawMsg), resp.RawMsg)
 	}
 
 	return nil
@@ -328,6 +645,25 @@ func (c *Client) AuthRealNameMobileV4(idNo, idName, phone string) error {
 
 // AuthRealNameBankV4 银行四要素认证
 func (c *Client) AuthRealNameBankV4(idNo, idName, phone, bankCardNo string) error {
+	return c.AuthRealNameBankV4Ctx(context.Background(), idNo, idName, phone, bankCardNo)
+}
+
+// AuthRealNameBankV4Ctx is like AuthRealNameBankV4 but carries ctx through the HTTP request.
+func (c *Client) AuthRealNameBankV4Ctx(ctx context.Context, idNo, idName, phone, bankCardNo string) error {
+	idNo = validate.NormalizeCertNum(idNo)
+	phone = validate.NormalizeMobile(phone)
+	bankCardNo = validate.NormalizeBankCard(bankCardNo)
+	if c.validation {
+		if err := validateResidentID(idNo); err != nil {
+			return err
+		}
+		if err := validateMobile(phone); err != nil {
+			return err
+		}
+		if err := validateBankCard(bankCardNo); err != nil {
+			return err
+		}
+	}
 	uri := "/authentic/personal/bankFour"
 	req := map[string]string{
 		"appId":      c.config.AppID,
@@ -337,16 +673,16 @@ func (c *Client) AuthRealNameBankV4(idNo, idName, phone, bankCardNo string) erro
 		"mobile":     phone,
 		"bankCardNo": bankCardNo,
 	}
-	ret, err := httpRequest(c, uri, req, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, uri, req, nil, func() interface{} {
 		return &AuthRealNameResp{}
 	})
 	if err != nil {
 		return err
 	}
 	resp := ret.(*AuthRealNameResp)
-	if 200 != resp.Code {
-		holmes.Debugln(resp)
-		return errors.New(resp.Message())
+	if !resp.Success() {
+		c.logger.Debugln(resp)
+		return info.apiError(resp.Code, 0, decodeMsg(resp.RawMsg), resp.RawMsg)
 	}
 
 	return nil
@@ -354,6 +690,17 @@ func (c *Client) AuthRealNameBankV4(idNo, idName, phone, bankCardNo string) erro
 
 // AuthRealName authenticates ID number and name via YunHeTong service.
 func (c *Client) AuthRealName(idNum, idName string, portrait bool) (*AuthResponse, error) {
+	return c.AuthRealNameCtx(context.Background(), idNum, idName, portrait)
+}
+
+// AuthRealNameCtx is like AuthRealName but carries ctx through the HTTP request.
+func (c *Client) AuthRealNameCtx(ctx context.Context, idNum, idName string, portrait bool) (*AuthResponse, error) {
+	idNum = validate.NormalizeCertNum(idNum)
+	if c.validation {
+		if err := validateResidentID(idNum); err != nil {
+			return nil, err
+		}
+	}
 	reqType := "1"
 	if portrait {
 		reqType = "2"
@@ -373,7 +720,7 @@ func (c *Client) AuthRealName(idNum, idName string, portrait bool) (*AuthRespons
 		return nil, err
 	}
 
-	ret, err := httpRequest(c, p.URI(), paramMap, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, p.URI(), paramMap, nil, func() interface{} {
 		return &AuthResponse{}
 	})
 
@@ -383,26 +730,48 @@ func (c *Client) AuthRealName(idNum, idName string, portrait bool) (*AuthRespons
 
 	rsp := ret.(*AuthResponse)
 
-	if err = checkAuthErr(rsp.Code, rsp.Msg, rsp.Success); err != nil {
+	if err = checkAuthErr(info, rsp.Code, rsp.Msg, rsp.Success); err != nil {
 		return nil, err
 	}
 
-	info := struct {
+	result := struct {
 		Message string `json:"message"`
 		Status  string `json:"status"`
 	}{}
 
-	if err = json.Unmarshal([]byte(rsp.Data), &info); err != nil {
+	if err = json.Unmarshal([]byte(rsp.Data), &result); err != nil {
 		return nil, err
 	}
 
-	rsp.Message = info.Message
-	rsp.Status = info.Status
+	rsp.Message = result.Message
+	rsp.Status = result.Status
 
 	return rsp, nil
 }
 
+// AuthRealNameBank calls AuthRealNameBankCtx with context.Background().
 func (c *Client) AuthRealNameBank(idNum, idName, bankCard, mobile string) (*AuthResponse, error) {
+	return c.AuthRealNameBankCtx(context.Background(), idNum, idName, bankCard, mobile)
+}
+
+// AuthRealNameBankCtx is like AuthRealNameBank but carries ctx through the HTTP request.
+func (c *Client) AuthRealNameBankCtx(ctx context.Context, idNum, idName, bankCard, mobile string) (*AuthResponse, error) {
+	idNum = validate.NormalizeCertNum(idNum)
+	mobile = validate.NormalizeMobile(mobile)
+	bankCard = validate.NormalizeBankCard(bankCard)
+	if c.validation {
+		if err := validateResidentID(idNum); err != nil {
+			return nil, err
+		}
+		if err := validateBankCard(bankCard); err != nil {
+			return nil, err
+		}
+		if mobile != "" {
+			if err := validateMobile(mobile); err != nil {
+				return nil, err
+			}
+		}
+	}
 	reqType := "3"
 	p := authParams{
 		IDNo:       idNum,
@@ -425,7 +794,7 @@ func (c *Client) AuthRealNameBank(idNum, idName, bankCard, mobile string) (*Auth
 		return nil, err
 	}
 
-	ret, err := httpRequest(c, p.URI(), paramMap, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, p.URI(), paramMap, nil, func() interface{} {
 		return &AuthResponse{}
 	})
 
@@ -435,27 +804,37 @@ func (c *Client) AuthRealNameBank(idNum, idName, bankCard, mobile string) (*Auth
 
 	rsp := ret.(*AuthResponse)
 
-	if err = checkAuthErr(rsp.Code, rsp.Msg, rsp.Success); err != nil {
+	if err = checkAuthErr(info, rsp.Code, rsp.Msg, rsp.Success); err != nil {
 		return nil, err
 	}
 
-	info := struct {
+	result := struct {
 		Message string `json:"message"`
 		Status  string `json:"status"`
 	}{}
 
-	if err = json.Unmarshal([]byte(rsp.Data), &info); err != nil {
+	if err = json.Unmarshal([]byte(rsp.Data), &result); err != nil {
 		return nil, err
 	}
 
-	rsp.Message = info.Message
-	rsp.Status = info.Status
+	rsp.Message = result.Message
+	rsp.Status = result.Status
 
 	return rsp, nil
 }
 
 // AddUser imports user into YunHeTong service.
 func (c *Client) AddUser(userID, phone, name, certNum string, userType string, certType string, autoSign bool) (*AddUserResponse, error) {
+	return c.AddUserCtx(context.Background(), userID, phone, name, certNum, userType, certType, autoSign)
+}
+
+// AddUserCtx is like AddUser but carries ctx through the HTTP request.
+func (c *Client) AddUserCtx(ctx context.Context, userID, phone, name, certNum string, userType string, certType string, autoSign bool) (*AddUserResponse, error) {
+	if c.validation {
+		if err := ValidateUserCert(certType, certNum); err != nil {
+			return nil, err
+		}
+	}
 	createSign := "0"
 	if autoSign {
 		createSign = "1"
@@ -478,7 +857,7 @@ func (c *Client) AddUser(userID, phone, name, certNum string, userType string, c
 		return nil, err
 	}
 
-	ret, err := httpRequest(c, p.URI(), paramMap, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, p.URI(), paramMap, nil, func() interface{} {
 		return &AddUserResponse{}
 	})
 
@@ -488,7 +867,7 @@ func (c *Client) AddUser(userID, phone, name, certNum string, userType string, c
 
 	rsp := ret.(*AddUserResponse)
 
-	if err = checkErr(rsp.Code, rsp.SubCode, rsp.Message); err != nil {
+	if err = checkErr(info, rsp.Code, rsp.SubCode, rsp.Message); err != nil {
 		return nil, err
 	}
 
@@ -497,6 +876,11 @@ func (c *Client) AddUser(userID, phone, name, certNum string, userType string, c
 
 // ModifyPhoneNumber modifies user's cell phone number.
 func (c *Client) ModifyPhoneNumber(phone, token string) (*ModifyPhoneNumberResponse, error) {
+	return c.ModifyPhoneNumberCtx(context.Background(), phone, token)
+}
+
+// ModifyPhoneNumberCtx is like ModifyPhoneNumber but carries ctx through the HTTP request.
+func (c *Client) ModifyPhoneNumberCtx(ctx context.Context, phone, token string) (*ModifyPhoneNumberResponse, error) {
 	p := modifyPhoneNumberParams{
 		CellNum: phone,
 	}
@@ -507,7 +891,7 @@ func (c *Client) ModifyPhoneNumber(phone, token string) (*ModifyPhoneNumberRespo
 		return nil, err
 	}
 
-	ret, err := httpRequest(c, p.URI(), paramMap, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, p.URI(), paramMap, nil, func() interface{} {
 		return &ModifyPhoneNumberResponse{}
 	})
 
@@ -517,7 +901,7 @@ func (c *Client) ModifyPhoneNumber(phone, token string) (*ModifyPhoneNumberRespo
 
 	rsp := ret.(*ModifyPhoneNumberResponse)
 
-	if err = checkErr(rsp.Code, rsp.SubCode, rsp.Message); err != nil {
+	if err = checkErr(info, rsp.Code, rsp.SubCode, rsp.Message); err != nil {
 		return nil, err
 	}
 
@@ -526,6 +910,11 @@ func (c *Client) ModifyPhoneNumber(phone, token string) (*ModifyPhoneNumberRespo
 
 // ModifyUserName modifies user's name.
 func (c *Client) ModifyUserName(name, token string, autoSign bool) (*ModifyUserNameResponse, error) {
+	return c.ModifyUserNameCtx(context.Background(), name, token, autoSign)
+}
+
+// ModifyUserNameCtx is like ModifyUserName but carries ctx through the HTTP request.
+func (c *Client) ModifyUserNameCtx(ctx context.Context, name, token string, autoSign bool) (*ModifyUserNameResponse, error) {
 	var createSign string
 	if autoSign {
 		createSign = "1"
@@ -541,7 +930,7 @@ func (c *Client) ModifyUserName(name, token string, autoSign bool) (*ModifyUserN
 		return nil, err
 	}
 
-	ret, err := httpRequest(c, p.URI(), paramMap, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, p.URI(), paramMap, nil, func() interface{} {
 		return &ModifyUserNameResponse{}
 	})
 
@@ -551,7 +940,7 @@ func (c *Client) ModifyUserName(name, token string, autoSign bool) (*ModifyUserN
 
 	rsp := ret.(*ModifyUserNameResponse)
 
-	if err = checkErr(rsp.Code, rsp.SubCode, rsp.Message); err != nil {
+	if err = checkErr(info, rsp.Code, rsp.SubCode, rsp.Message); err != nil {
 		return nil, err
 	}
 
@@ -560,6 +949,11 @@ func (c *Client) ModifyUserName(name, token string, autoSign bool) (*ModifyUserN
 
 // UserToken gets user's token string.
 func (c *Client) UserToken(userID string) (*UserTokenResponse, error) {
+	return c.UserTokenCtx(context.Background(), userID)
+}
+
+// UserTokenCtx is like UserToken but carries ctx through the HTTP request.
+func (c *Client) UserTokenCtx(ctx context.Context, userID string) (*UserTokenResponse, error) {
 	p := userTokenParams{
 		AppUserID: userID,
 	}
@@ -571,7 +965,7 @@ func (c *Client) UserToken(userID string) (*UserTokenResponse, error) {
 		return nil, err
 	}
 
-	ret, err := httpRequest(c, p.URI(), paramMap, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, p.URI(), paramMap, nil, func() interface{} {
 		return &UserTokenResponse{}
 	})
 
@@ -581,7 +975,7 @@ func (c *Client) UserToken(userID string) (*UserTokenResponse, error) {
 
 	rsp := ret.(*UserTokenResponse)
 
-	if err = checkErr(rsp.Code, rsp.SubCode, rsp.Message); err != nil {
+	if err = checkErr(info, rsp.Code, rsp.SubCode, rsp.Message); err != nil {
 		return nil, err
 	}
 
@@ -590,11 +984,20 @@ func (c *Client) UserToken(userID string) (*UserTokenResponse, error) {
 
 // CreateTemplateContract creates contract based on template.
 func (c *Client) CreateTemplateContract(title, contractNo, templateID, token string, useCer bool, placeHolders M) (*CreateTemplateContractResponse, error) {
+	return c.CreateTemplateContractCtx(context.Background(), title, contractNo, templateID, token, useCer, placeHolders)
+}
+
+// CreateTemplateContractCtx is like CreateTemplateContract but carries ctx through the HTTP request.
+func (c *Client) CreateTemplateContractCtx(ctx context.Context, title, contractNo, templateID, token string, useCer bool, placeHolders M) (*CreateTemplateContractResponse, error) {
 	var cer string
 	if useCer {
 		cer = "1"
 	}
 
+	if err := c.checkTemplate(templateID, placeHolders); err != nil {
+		return nil, err
+	}
+
 	data, err := json.Marshal(placeHolders)
 	if err != nil {
 		return nil, err
@@ -615,7 +1018,7 @@ func (c *Client) CreateTemplateContract(title, contractNo, templateID, token str
 		return nil, err
 	}
 
-	ret, err := httpRequest(c, p.URI(), paramMap, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, p.URI(), paramMap, nil, func() interface{} {
 		return &CreateTemplateContractResponse{}
 	})
 
@@ -625,7 +1028,7 @@ func (c *Client) CreateTemplateContract(title, contractNo, templateID, token str
 
 	rsp := ret.(*CreateTemplateContractResponse)
 
-	if err = checkErr(rsp.Code, rsp.SubCode, rsp.Message); err != nil {
+	if err = checkErr(info, rsp.Code, rsp.SubCode, rsp.Message); err != nil {
 		return nil, err
 	}
 
@@ -633,7 +1036,14 @@ func (c *Client) CreateTemplateContract(title, contractNo, templateID, token str
 }
 
 // CreateFileContract creates contract by uploading file.
+//
+// Deprecated: the sdk gateway is deprecated, use CreateFileContractV4 instead.
 func (c *Client) CreateFileContract(title, contractNo, token string, useCer bool, data []byte) (*CreateFileContractResponse, error) {
+	return c.CreateFileContractCtx(context.Background(), title, contractNo, token, useCer, data)
+}
+
+// CreateFileContractCtx is like CreateFileContract but carries ctx through the HTTP request.
+func (c *Client) CreateFileContractCtx(ctx context.Context, title, contractNo, token string, useCer bool, data []byte) (*CreateFileContractResponse, error) {
 	var cer string
 	if useCer {
 		cer = "1"
@@ -650,7 +1060,8 @@ func (c *Client) CreateFileContract(title, contractNo, token string, useCer bool
 		return nil, err
 	}
 
-	ret, err := httpRequest(c, p.URI(), paramMap, data, func() interface{} {
+	// 旧接口原样上传文件内容，不检测文件类型
+	ret, info, err := httpRequest(ctx, c, p.URI(), paramMap, data, func() interface{} {
 		return &CreateFileContractResponse{}
 	})
 
@@ -659,7 +1070,7 @@ func (c *Client) CreateFileContract(title, contractNo, token string, useCer bool
 	}
 
 	rsp := ret.(*CreateFileContractResponse)
-	if err = checkErr(rsp.Code, rsp.SubCode, rsp.Message); err != nil {
+	if err = checkErr(info, rsp.Code, rsp.SubCode, rsp.Message); err != nil {
 		return nil, err
 	}
 
@@ -675,6 +1086,11 @@ type Partner struct {
 
 // AddPartner adds partners of contract.
 func (c *Client) AddPartner(contractID int64, token string, partners ...Partner) (*AddPartnerResponse, error) {
+	return c.AddPartnerCtx(context.Background(), contractID, token, partners...)
+}
+
+// AddPartnerCtx is like AddPartner but carries ctx through the HTTP request.
+func (c *Client) AddPartnerCtx(ctx context.Context, contractID int64, token string, partners ...Partner) (*AddPartnerResponse, error) {
 	data, err := json.Marshal(partners)
 	if err != nil {
 		return nil, err
@@ -692,7 +1108,7 @@ func (c *Client) AddPartner(contractID int64, token string, partners ...Partner)
 		return nil, err
 	}
 
-	ret, err := httpRequest(c, p.URI(), paramMap, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, p.URI(), paramMap, nil, func() interface{} {
 		return &AddPartnerResponse{}
 	})
 	if err != nil {
@@ -700,7 +1116,7 @@ func (c *Client) AddPartner(contractID int64, token string, partners ...Partner)
 	}
 
 	rsp := ret.(*AddPartnerResponse)
-	if err = checkErr(rsp.Code, rsp.SubCode, rsp.Message); err != nil {
+	if err = checkErr(info, rsp.Code, rsp.SubCode, rsp.Message); err != nil {
 		return nil, err
 	}
 
@@ -709,6 +1125,11 @@ func (c *Client) AddPartner(contractID int64, token string, partners ...Partner)
 
 // SignContract signs contract automatically.
 func (c *Client) SignContract(contractID, token string, signers ...string) (*SignContractResponse, error) {
+	return c.SignContractCtx(context.Background(), contractID, token, signers...)
+}
+
+// SignContractCtx is like SignContract but carries ctx through the HTTP request.
+func (c *Client) SignContractCtx(ctx context.Context, contractID, token string, signers ...string) (*SignContractResponse, error) {
 	data, err := json.Marshal(signers)
 	if err != nil {
 		return nil, err
@@ -726,7 +1147,7 @@ func (c *Client) SignContract(contractID, token string, signers ...string) (*Sig
 		return nil, err
 	}
 
-	ret, err := httpRequest(c, p.URI(), paramMap, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, p.URI(), paramMap, nil, func() interface{} {
 		return &SignContractResponse{}
 	})
 	if err != nil {
@@ -734,7 +1155,7 @@ func (c *Client) SignContract(contractID, token string, signers ...string) (*Sig
 	}
 
 	rsp := ret.(*SignContractResponse)
-	if err = checkErr(rsp.Code, rsp.SubCode, rsp.Message); err != nil {
+	if err = checkErr(info, rsp.Code, rsp.SubCode, rsp.Message); err != nil {
 		return nil, err
 	}
 
@@ -742,7 +1163,14 @@ func (c *Client) SignContract(contractID, token string, signers ...string) (*Sig
 }
 
 // InvalidateContract invalidates contract.
+//
+// Deprecated: the sdk gateway is deprecated, use InvalidateContractV4 instead.
 func (c *Client) InvalidateContract(contractID, token string) (*InvalidateContractResponse, error) {
+	return c.InvalidateContractCtx(context.Background(), contractID, token)
+}
+
+// InvalidateContractCtx is like InvalidateContract but carries ctx through the HTTP request.
+func (c *Client) InvalidateContractCtx(ctx context.Context, contractID, token string) (*InvalidateContractResponse, error) {
 	p := invalidateContractParams{
 		ContractID: contractID,
 	}
@@ -754,7 +1182,7 @@ func (c *Client) InvalidateContract(contractID, token string) (*InvalidateContra
 		return nil, err
 	}
 
-	ret, err := httpRequest(c, p.URI(), paramMap, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, p.URI(), paramMap, nil, func() interface{} {
 		return &InvalidateContractResponse{}
 	})
 	if err != nil {
@@ -762,7 +1190,7 @@ func (c *Client) InvalidateContract(contractID, token string) (*InvalidateContra
 	}
 
 	rsp := ret.(*InvalidateContractResponse)
-	if err = checkErr(rsp.Code, rsp.SubCode, rsp.Message); err != nil {
+	if err = checkErr(info, rsp.Code, rsp.SubCode, rsp.Message); err != nil {
 		return nil, err
 	}
 
@@ -770,7 +1198,14 @@ func (c *Client) InvalidateContract(contractID, token string) (*InvalidateContra
 }
 
 // ListContracts returns a list of contracts finished or invalidated.
+//
+// Deprecated: the sdk gateway is deprecated, use ListContractsV4 instead.
 func (c *Client) ListContracts(pageNum, pageSize int, token string) (*ListContractsResponse, error) {
+	return c.ListContractsCtx(context.Background(), pageNum, pageSize, token)
+}
+
+// ListContractsCtx is like ListContracts but carries ctx through the HTTP request.
+func (c *Client) ListContractsCtx(ctx context.Context, pageNum, pageSize int, token string) (*ListContractsResponse, error) {
 	p := listContractsParams{
 		PageNum:  fmt.Sprintf("%d", pageNum),
 		PageSize: fmt.Sprintf("%d", pageSize),
@@ -783,7 +1218,7 @@ func (c *Client) ListContracts(pageNum, pageSize int, token string) (*ListContra
 		return nil, err
 	}
 
-	ret, err := httpRequest(c, p.URI(), paramMap, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, p.URI(), paramMap, nil, func() interface{} {
 		return &ListContractsResponse{}
 	})
 	if err != nil {
@@ -791,7 +1226,7 @@ func (c *Client) ListContracts(pageNum, pageSize int, token string) (*ListContra
 	}
 
 	rsp := ret.(*ListContractsResponse)
-	if err = checkErr(rsp.Code, rsp.SubCode, rsp.Message); err != nil {
+	if err = checkErr(info, rsp.Code, rsp.SubCode, rsp.Message); err != nil {
 		return nil, err
 	}
 
@@ -799,7 +1234,14 @@ func (c *Client) ListContracts(pageNum, pageSize int, token string) (*ListContra
 }
 
 // LookupContractDetail returns the detail of a contract.
+//
+// Deprecated: the sdk gateway is deprecated, use LookupContractDetailV4 instead.
 func (c *Client) LookupContractDetail(contractID, token string) (*LookupContractDetailResponse, error) {
+	return c.LookupContractDetailCtx(context.Background(), contractID, token)
+}
+
+// LookupContractDetailCtx is like LookupContractDetail but carries ctx through the HTTP request.
+func (c *Client) LookupContractDetailCtx(ctx context.Context, contractID, token string) (*LookupContractDetailResponse, error) {
 	p := lookupContractDetailParams{
 		ContractID: contractID,
 	}
@@ -811,7 +1253,7 @@ func (c *Client) LookupContractDetail(contractID, token string) (*LookupContract
 		return nil, err
 	}
 
-	ret, err := httpRequest(c, p.URI(), paramMap, nil, func() interface{} {
+	ret, info, err := httpRequest(ctx, c, p.URI(), paramMap, nil, func() interface{} {
 		return &LookupContractDetailResponse{}
 	})
 	if err != nil {
@@ -819,7 +1261,7 @@ func (c *Client) LookupContractDetail(contractID, token string) (*LookupContract
 	}
 
 	rsp := ret.(*LookupContractDetailResponse)
-	if err = checkErr(rsp.Code, rsp.SubCode, rsp.Message); err != nil {
+	if err = checkErr(info, rsp.Code, rsp.SubCode, rsp.Message); err != nil {
 		return nil, err
 	}
 
@@ -827,41 +1269,32 @@ func (c *Client) LookupContractDetail(contractID, token string) (*LookupContract
 }
 
 // DownloadContract downloads a contract.
+//
+// Deprecated: the sdk gateway is deprecated, use DownloadContractV4 instead.
 func (c *Client) DownloadContract(contractID, token string) ([]byte, error) {
+	return c.DownloadContractCtx(context.Background(), contractID, token)
+}
+
+// DownloadContractCtx is like DownloadContract but carries ctx through the HTTP request.
+func (c *Client) DownloadContractCtx(ctx context.Context, contractID, token string) ([]byte, error) {
 	p := downloadContractParams{
 		ContractID: contractID,
 	}
 
-	paramMap, err := toMap(p, nil)
-	if err != nil {
-		return nil, err
-	}
-
 	vals := url.Values{}
-	for k, v := range paramMap {
-		vals.Add(k, v)
-	}
-
-	uri := fmt.Sprintf("%s?token=%s&contractId=%s", p.URI(), token, contractID)
-	apiURL := fmt.Sprintf("%s%s", c.config.APIGateway, uri)
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	vals.Add("token", token)
+	vals.Add("contractId", contractID)
+	apiURL := fmt.Sprintf("%s%s?%s", c.config.APIGateway, p.URI(), vals.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
-	rsp, err := c.tlsClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer rsp.Body.Close()
-
-	data, err := ioutil.ReadAll(rsp.Body)
-	if err != nil {
+	buf := &bytes.Buffer{}
+	if _, err = c.download(req, p.URI(), buf); err != nil {
 		return nil, err
 	}
-
-	return data, nil
+	return buf.Bytes(), nil
 }
 
 // AsyncNotifyResult represents the result returned from YunHeTong service.
@@ -873,20 +1306,15 @@ type AsyncNotifyResult struct {
 }
 
 // AsyncNotify returns asynchronous notification from YunHeTong service.
+// Use NotificationHandler to get typed events.
 func (c *Client) AsyncNotify(req *http.Request) (*AsyncNotifyResult, error) {
 	defer req.Body.Close()
-	bodyBytes, err := ioutil.ReadAll(req.Body)
+	data, err := readNotice(req)
 	if err != nil {
 		return nil, err
 	}
-
-	tmp, err := url.QueryUnescape(string(bodyBytes)) // url decode as YHT notification is url encoded
-	if err != nil {
-		return nil, err
-	}
-	jsonStr := strings.Replace(tmp, "notice=", "", -1) // remove "notice=" segment as it is not json format
 	result := &AsyncNotifyResult{}
-	err = json.Unmarshal([]byte(jsonStr), result)
+	err = json.Unmarshal(data, result)
 	if err != nil {
 		return nil, err
 	}
@@ -895,21 +1323,13 @@ func (c *Client) AsyncNotify(req *http.Request) (*AsyncNotifyResult, error) {
 
 // AnswerAsyncNotify returns a json string answering async notification.
 func (c *Client) AnswerAsyncNotify(rsp bool, msg string) string {
-	ret := map[string]interface{}{
-		"response": rsp,
-		"msg":      msg,
-	}
-	data, err := json.Marshal(ret)
-	if err != nil {
-		return ""
-	}
-	return string(data)
+	return answerAsyncNotify(rsp, msg)
 }
 
-// httpRequestV4 云合同V4版本接口请求
-func httpRequestV4(c *Client, token, uri, method string, jsonData []byte, factory func() interface{}) (interface{}, string, error) {
+// httpRequestV4 云合同V4版本接口请求，应答码不为200时返回*APIError
+func httpRequestV4(ctx context.Context, c *Client, token, uri, method string, jsonData []byte, factory func() interface{}) (interface{}, string, error) {
 	apiURL := c.config.APIGateway + uri
-	req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewReader(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(jsonData))
 	if err != nil {
 		return nil, "", err
 	}
@@ -918,35 +1338,62 @@ func httpRequestV4(c *Client, token, uri, method string, jsonData []byte, factor
 		req.Header.Add("token", token)
 	}
 
-	yhtResp, err := c.tlsClient.Do(req)
+	data, header, info, err := c.send(req, uri)
 	if err != nil {
 		return nil, "", err
 	}
 
 	llt := ""
 	if uri == "/auth/login" {
-		if v, ok := yhtResp.Header["Token"]; ok { // 取token
-			if ok {
-				llt = v[0]
-			}
-		}
+		llt = header.Get("Token") // 取token
 	}
 
-	defer yhtResp.Body.Close()
-
-	data, err := ioutil.ReadAll(yhtResp.Body)
+	rsp, err := decodeV4(data, info, factory)
 	if err != nil {
 		return nil, "", err
 	}
-	rsp := factory()
-	if err = json.NewDecoder(bytes.NewReader(data)).Decode(rsp); err != nil {
-		return nil, "", err
+	return rsp, llt, nil
+}
+
+// httpMultipartV4 以multipart/form-data上传文件请求V4接口
+func httpMultipartV4(ctx context.Context, c *Client, token, uri string, fields map[string]string, file ContractFile, factory func() interface{}) (interface{}, error) {
+	body, contentType, err := multipartBody(fields, file)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.APIGateway+uri, body)
+	if err != nil {
+		body.Close()
+		return nil, err
 	}
+	req.Header.Add("Content-Type", contentType)
+	req.Header.Add("token", token)
 
-	return rsp, llt, nil
+	data, _, info, err := c.send(req, uri)
+	if err != nil {
+		return nil, err
+	}
+	return decodeV4(data, info, factory)
 }
 
-func httpRequest(c *Client, uri string, paramMap map[string]string, fileData []byte, factory func() interface{}) (interface{}, error) {
+// decodeV4 解析V4接口应答，应答码不为200时返回*APIError
+func decodeV4(data []byte, info callInfo, factory func() interface{}) (interface{}, error) {
+	rsp := factory()
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(rsp); err != nil {
+		if info.status >= http.StatusBadRequest {
+			return nil, info.apiError(info.status, 0, string(data), nil)
+		}
+		return nil, err
+	}
+	if r, ok := rsp.(respBase); ok && !r.baseResp().Success() {
+		base := r.baseResp()
+		return nil, info.apiError(base.Code, 0, decodeMsg(base.RawMsg), base.RawMsg)
+	}
+	return rsp, nil
+}
+
+func httpRequest(ctx context.Context, c *Client, uri string, paramMap map[string]string, fileData []byte, factory func() interface{}) (interface{}, callInfo, error) {
+	path := uri
 	if token, ok := paramMap["token"]; ok {
 		delete(paramMap, "token")
 		uri = fmt.Sprintf("%s?token=%s", uri, token)
@@ -957,83 +1404,143 @@ func httpRequest(c *Client, uri string, paramMap map[string]string, fileData []b
 	}
 
 	var data []byte
+	var info callInfo
 	var err error
 	if fileData != nil {
-		data, err = c.doMultipartRequest(apiURL, paramMap, fileData)
+		data, info, err = c.doMultipartRequest(ctx, apiURL, path, paramMap, fileData)
 	} else {
-		data, err = c.doHTTPRequest(apiURL, paramMap)
+		data, info, err = c.doHTTPRequest(ctx, apiURL, path, paramMap)
 	}
 
 	if err != nil {
-		return nil, err
+		return nil, info, err
 	}
 
 	rsp := factory()
 	if err = json.NewDecoder(bytes.NewReader(data)).Decode(rsp); err != nil {
-		return nil, err
+		if info.status >= http.StatusBadRequest {
+			return nil, info, info.apiError(info.status, 0, string(data), nil)
+		}
+		return nil, info, err
 	}
 
-	return rsp, nil
+	return rsp, info, nil
 }
 
-func (c *Client) doMultipartRequest(apiURL string, paramMap map[string]string, fileData []byte) ([]byte, error) {
-	buf := &bytes.Buffer{}
-	writer := multipart.NewWriter(buf)
-	for k, v := range paramMap {
-		if err := writer.WriteField(k, v); err != nil {
+// doMultipartRequest 以multipart/form-data请求V3接口，文件内容以流的方式作为file字段上传。
+// 文件类型未知，以application/octet-stream上传；重试时重新生成请求体
+func (c *Client) doMultipartRequest(ctx context.Context, apiURL, uri string, paramMap map[string]string, fileData []byte) ([]byte, callInfo, error) {
+	file := ContractFile{Name: "contract", ContentType: "application/octet-stream"}
+	boundary := multipart.NewWriter(nil).Boundary()
+	newBody := func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		writer := multipart.NewWriter(pw)
+		if err := writer.SetBoundary(boundary); err != nil {
 			return nil, err
 		}
-	}
-	fw, err := writer.CreateFormField("file")
-	if err != nil {
-		return nil, err
-	}
-	if _, err = fw.Write(fileData); err != nil {
-		return nil, err
+		f := file
+		f.Reader = bytes.NewReader(fileData)
+		go func() {
+			pw.CloseWithError(writeMultipart(writer, paramMap, f))
+		}()
+		return pr, nil
 	}
 
-	req, err := http.NewRequest(http.MethodPost, apiURL, buf)
+	info := callInfo{uri: uri, dupCodes: c.dupUserCodes}
+	body, err := newBody()
 	if err != nil {
-		return nil, err
+		return nil, info, err
 	}
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
-
-	rsp, err := c.tlsClient.Do(req)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, body)
 	if err != nil {
-		return nil, err
+		body.Close()
+		return nil, info, err
 	}
+	req.GetBody = newBody
+	req.Header.Add("Content-Type", "multipart/form-data; boundary="+boundary)
 
-	defer rsp.Body.Close()
-
-	data, err := ioutil.ReadAll(rsp.Body)
-	if err != nil {
-		return nil, err
-	}
-	return data, nil
+	data, _, info, err := c.send(req, uri)
+	return data, info, err
 }
 
-func (c *Client) doHTTPRequest(apiURL string, paramMap map[string]string) ([]byte, error) {
+func (c *Client) doHTTPRequest(ctx context.Context, apiURL, uri string, paramMap map[string]string) ([]byte, callInfo, error) {
 	formData := url.Values{}
 	for k, v := range paramMap {
 		formData.Add(k, v)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, apiURL, strings.NewReader(formData.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, strings.NewReader(formData.Encode()))
 	if err != nil {
-		return nil, err
+		return nil, callInfo{uri: uri, dupCodes: c.dupUserCodes}, err
 	}
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
 
-	rsp, err := c.tlsClient.Do(req)
+	data, _, info, err := c.send(req, uri)
+	return data, info, err
+}
+
+// send 发送请求并读取全部应答，网络错误包装为*APIError
+func (c *Client) send(req *http.Request, uri string) ([]byte, http.Header, callInfo, error) {
+	rsp, info, err := c.open(req, uri)
 	if err != nil {
-		return nil, err
+		return nil, nil, info, err
 	}
-
 	defer rsp.Body.Close()
 
 	data, err := ioutil.ReadAll(rsp.Body)
 	if err != nil {
-		return nil, err
+		return nil, rsp.Header, info, info.networkError(err)
+	}
+	return data, rsp.Header, info, nil
+}
+
+// open 发送请求并按重试策略重试，返回的应答由调用方关闭
+func (c *Client) open(req *http.Request, uri string) (*http.Response, callInfo, error) {
+	for attempt := 1; ; attempt++ {
+		rsp, info, err := c.openOnce(req, uri)
+		if attempt >= c.retry.MaxAttempts || !c.retry.retryable(uri, info.status, err) {
+			return rsp, info, err
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return rsp, info, err
+		}
+		if rsp != nil {
+			io.Copy(ioutil.Discard, rsp.Body)
+			rsp.Body.Close()
+		}
+		c.logger.Debugln("retry", uri, info.requestID, info.status, err)
+		if e := sleepCtx(req.Context(), c.retry.backoff(attempt)); e != nil {
+			return nil, info, info.networkError(e)
+		}
+		if req.GetBody != nil {
+			body, e := req.GetBody()
+			if e != nil {
+				return nil, info, e
+			}
+			req.Body = body
+		}
+	}
+}
+
+// openOnce 发送一次请求
+func (c *Client) openOnce(req *http.Request, uri string) (*http.Response, callInfo, error) {
+	info := callInfo{uri: uri, requestID: newRequestID(), dupCodes: c.dupUserCodes}
+	req.Header.Set(RequestIDHeader, info.requestID)
+
+	var wrote int32
+	trace := &httptrace.ClientTrace{
+		WroteHeaders: func() { atomic.StoreInt32(&wrote, 1) },
+	}
+	rsp, err := c.tlsClient.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err != nil {
+		apiErr := info.networkError(err).(*APIError)
+		apiErr.unsent = atomic.LoadInt32(&wrote) == 0
+		return nil, info, apiErr
+	}
+
+	info.status = rsp.StatusCode
+	if id := rsp.Header.Get(RequestIDHeader); id != "" {
+		info.requestID = id
 	}
-	return data, nil
+	return rsp, info, nil
 }
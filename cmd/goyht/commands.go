@@ -0,0 +1,502 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/iotdog/goyht"
+)
+
+// enumFlag 枚举参数，接受取值或中英文名称，例如-cert-type passport
+type enumFlag struct {
+	v interface {
+		json.Unmarshaler
+		Valid() bool
+	}
+	text string
+}
+
+func (f *enumFlag) String() string { return f.text }
+
+func (f *enumFlag) Set(s string) error {
+	data, _ := json.Marshal(s)
+	if err := f.v.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	if !f.v.Valid() {
+		return fmt.Errorf("unknown value %q", s)
+	}
+	f.text = s
+	return nil
+}
+
+// listFlag 可重复的参数
+type listFlag []string
+
+func (f *listFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *listFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+// contractRef 合同ID或自定义编号参数
+type contractRef struct {
+	id, no string
+}
+
+func (r *contractRef) register(fs *flag.FlagSet) {
+	fs.StringVar(&r.id, "id", "", "contract id")
+	fs.StringVar(&r.no, "no", "", "custom contract number, instead of -id")
+}
+
+// resolve 返回合同ID类型和内容
+func (r *contractRef) resolve(fs *flag.FlagSet) (string, string, error) {
+	switch {
+	case r.id != "" && r.no != "":
+		fmt.Fprintln(fs.Output(), "-id and -no are exclusive")
+	case r.id != "":
+		return goyht.YHTIDTypeSystem, r.id, nil
+	case r.no != "":
+		return goyht.YHTIDTypeCustom, r.no, nil
+	default:
+		fmt.Fprintln(fs.Output(), "-id or -no is required")
+	}
+	fs.Usage()
+	return "", "", errUsage
+}
+
+func authLogin(a *app, fs *flag.FlagSet, args []string) error {
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	c, err := a.clientFor()
+	if err != nil {
+		return err
+	}
+	if err = c.Authenticate(a.ctx); err != nil {
+		return err
+	}
+	result := map[string]string{"appId": a.config.AppID, "gateway": gateway(a.config), "status": "ok"}
+	return a.out.print(result, fields("appId", result["appId"], "gateway", result["gateway"], "status", "ok"))
+}
+
+func gateway(cfg goyht.Config) string {
+	if cfg.APIGateway == "" {
+		return goyht.YHTAPIGatewayV4
+	}
+	return cfg.APIGateway
+}
+
+func userCreatePerson(a *app, fs *flag.FlagSet, args []string) error {
+	req := goyht.YhtCreatePersonReq{CertType: goyht.YHTPersonCertTypeIDCard, CAType: "B2"}
+	fs.StringVar(&req.Username, "name", "", "user name")
+	fs.StringVar(&req.CertNum, "cert-num", "", "certificate number")
+	fs.Var(&enumFlag{v: &req.CertType, text: string(req.CertType)}, "cert-type", "certificate type, a code or a label such as passport")
+	fs.Var(&enumFlag{v: &req.IdentityRegion}, "identity-region", "identity region, a code or a label such as \"Hong Kong\"")
+	fs.Var(&enumFlag{v: &req.PhoneRegion}, "phone-region", "phone region, a code or a label")
+	fs.StringVar(&req.Phone, "phone", "", "phone number")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := require(fs, "name", "cert-num"); err != nil {
+		return err
+	}
+	c, err := a.clientFor()
+	if err != nil {
+		return err
+	}
+	rsp, err := c.CreatePersonV4Ctx(a.ctx, &req)
+	if err != nil {
+		return err
+	}
+	return a.out.print(rsp.Data, fields("signerId", strconv.Itoa(rsp.Data.SignerID)))
+}
+
+func userCreateCompany(a *app, fs *flag.FlagSet, args []string) error {
+	req := goyht.YhtCreateCompanyReq{CertType: goyht.YHTCompanyCertTypeUniformSocailCreditCode, CAType: "B2"}
+	fs.StringVar(&req.Username, "name", "", "company name")
+	fs.StringVar(&req.CertNum, "cert-num", "", "unified social credit code")
+	fs.StringVar(&req.Phone, "phone", "", "phone number")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := require(fs, "name", "cert-num"); err != nil {
+		return err
+	}
+	c, err := a.clientFor()
+	if err != nil {
+		return err
+	}
+	rsp, err := c.CreateCompanyV4Ctx(a.ctx, &req)
+	if err != nil {
+		return err
+	}
+	return a.out.print(rsp.Data, fields("signerId", strconv.Itoa(rsp.Data.SignerID)))
+}
+
+func userQuery(a *app, fs *flag.FlagSet, args []string) error {
+	var certNums listFlag
+	fs.Var(&certNums, "cert-num", "certificate number, may be repeated")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := require(fs, "cert-num"); err != nil {
+		return err
+	}
+	c, err := a.clientFor()
+	if err != nil {
+		return err
+	}
+	rsp, err := c.QuerySignerIDCtx(a.ctx, &goyht.YhtQuerySignerIDReq{CertifyNumList: certNums})
+	if err != nil {
+		return err
+	}
+	found := map[string]int{}
+	for _, m := range rsp.Data {
+		for k, v := range m {
+			found[k] = v
+		}
+	}
+	t := &table{header: []string{"CERT NUM", "SIGNER ID"}}
+	for _, n := range certNums {
+		id := "-"
+		if v, ok := found[n]; ok {
+			id = strconv.Itoa(v)
+		}
+		t.add(n, id)
+	}
+	return a.out.print(found, t)
+}
+
+func sealCreate(a *app, fs *flag.FlagSet, args []string) error {
+	var (
+		person  goyht.YhtCreatePersonMoulageReq
+		company goyht.YhtCreateCompanyMoulageReq
+		kind    string
+		color   goyht.FontColor
+		mode    goyht.MoulageMode
+	)
+	fs.StringVar(&kind, "type", "person", "seal type, person or company")
+	fs.StringVar(&person.SignerID, "signer", "", "signer id")
+	fs.Var(&enumFlag{v: &color}, "color", "ink colour, a code or a label such as red")
+	fs.Var(&enumFlag{v: &mode}, "mode", "seal mode, a code or a label such as transparent")
+	fs.Var(&enumFlag{v: &person.BorderType}, "border", "person seal border, a code or a label")
+	fs.Var(&enumFlag{v: &person.FontFamily}, "font", "person seal font, a code or a label")
+	fs.Var(&enumFlag{v: &person.ZoomCode}, "zoom", "person seal size, a code or a label")
+	fs.Var(&enumFlag{v: &company.StyleType}, "style", "company seal shape, a code or a label")
+	fs.StringVar(&company.TextContent, "text", "", "company seal horizontal text")
+	fs.StringVar(&company.KeyContent, "key", "", "company seal anti-counterfeit code, 13 digits")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := require(fs, "signer"); err != nil {
+		return err
+	}
+	c, err := a.clientFor()
+	if err != nil {
+		return err
+	}
+	var rsp *goyht.YhtCreateMoulageResp
+	switch kind {
+	case "person":
+		person.FontColor, person.Mode = color, mode
+		rsp, err = c.CreatePersonMoulageV4Ctx(a.ctx, &person)
+	case "company":
+		company.SignerID, company.FontColor, company.Mode = person.SignerID, color, mode
+		rsp, err = c.CreateCompanyMoulageV4Ctx(a.ctx, &company)
+	default:
+		fmt.Fprintf(fs.Output(), "unknown seal type %q\n", kind)
+		return errUsage
+	}
+	if err != nil {
+		return err
+	}
+	return a.out.print(rsp.Data, fields("moulageId", strconv.Itoa(rsp.Data.MoulageID)))
+}
+
+func contractCreate(a *app, fs *flag.FlagSet, args []string) error {
+	var (
+		req      goyht.YhtCreateTemplateContractReq
+		values   listFlag
+		dataFile string
+	)
+	fs.StringVar(&req.Title, "title", "", "contract title")
+	fs.StringVar(&req.ContractNo, "no", "", "custom contract number")
+	fs.StringVar(&req.TemplateID, "template", "", "template id")
+	fs.Var(&values, "set", "placeholder value as name=value, may be repeated")
+	fs.StringVar(&dataFile, "data", "", "JSON file of placeholder values, - for stdin")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := require(fs, "title", "template"); err != nil {
+		return err
+	}
+	data := goyht.M{}
+	if dataFile != "" {
+		raw, err := a.readInput(dataFile)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(raw, &data); err != nil {
+			return fmt.Errorf("contract data %s: %v", dataFile, err)
+		}
+	}
+	for _, kv := range values {
+		i := strings.IndexByte(kv, '=')
+		if i <= 0 {
+			fmt.Fprintf(fs.Output(), "-set %q should be name=value\n", kv)
+			return errUsage
+		}
+		data[kv[:i]] = kv[i+1:]
+	}
+	req.ContractData = data
+	c, err := a.clientFor()
+	if err != nil {
+		return err
+	}
+	rsp, err := c.CreateContractFromTemplateV4Ctx(a.ctx, &req)
+	if err != nil {
+		return err
+	}
+	return a.out.print(rsp.Data, fields("contractId", strconv.Itoa(rsp.Data.ContractID)))
+}
+
+func contractAddSigner(a *app, fs *flag.FlagSet, args []string) error {
+	var (
+		ref                    contractRef
+		signerID, keyword      string
+		placeholder, coords    string
+		sms, h5, drawSignature bool
+	)
+	ref.register(fs)
+	fs.StringVar(&signerID, "signer", "", "signer id")
+	fs.StringVar(&keyword, "keyword", "", "locate the signature by keyword")
+	fs.StringVar(&placeholder, "placeholder", "", "locate the signature by template placeholder")
+	fs.StringVar(&coords, "coords", "", "locate the signature by coordinates page,x,y")
+	fs.BoolVar(&sms, "sms", false, "require SMS validation")
+	fs.BoolVar(&h5, "h5", false, "sign on the standalone H5 page")
+	fs.BoolVar(&drawSignature, "draw", false, "draw the signature when signing")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	idType, idContent, err := ref.resolve(fs)
+	if err != nil {
+		return err
+	}
+	if err = require(fs, "signer"); err != nil {
+		return err
+	}
+	b := goyht.NewSigner(signerID)
+	if keyword != "" {
+		b.AtKeyword(keyword)
+	}
+	if placeholder != "" {
+		b.AtPlaceholder(placeholder)
+	}
+	if coords != "" {
+		page, x, y, err := goyht.ParseCoordinates(coords)
+		if err != nil {
+			return err
+		}
+		b.AtCoordinates(page, x, y)
+	}
+	if sms {
+		b.WithSMSValidation()
+	}
+	if h5 {
+		b.H5()
+	}
+	if drawSignature {
+		b.DrawSignature()
+	}
+	req, err := goyht.NewAddSignerReq(idType, idContent, b)
+	if err != nil {
+		return err
+	}
+	c, err := a.clientFor()
+	if err != nil {
+		return err
+	}
+	if _, err = c.AddSignerV4Ctx(a.ctx, req); err != nil {
+		return err
+	}
+	s := req.Signers[0]
+	return a.out.print(s, fields("signerId", s.SignerID, "position", s.SignPositionType.Label()+" "+s.PositionContent, "status", "added"))
+}
+
+func contractSign(a *app, fs *flag.FlagSet, args []string) error {
+	var (
+		ref contractRef
+		req goyht.YhtSignContractReq
+	)
+	ref.register(fs)
+	fs.StringVar(&req.SignerID, "signer", "", "signer id")
+	fs.StringVar(&req.MoulageID, "moulage", "", "seal id")
+	fs.Var(&enumFlag{v: &req.SealClass}, "seal-class", "seal class, a code or a label such as \"paging seal\"")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	var err error
+	if req.IDType, req.IDContent, err = ref.resolve(fs); err != nil {
+		return err
+	}
+	if err = require(fs, "signer"); err != nil {
+		return err
+	}
+	c, err := a.clientFor()
+	if err != nil {
+		return err
+	}
+	if _, err = c.SignContractV4Ctx(a.ctx, &req); err != nil {
+		return err
+	}
+	return a.out.print(map[string]string{"status": "signed"}, fields("contract", req.IDContent, "signerId", req.SignerID, "status", "signed"))
+}
+
+func contractDetail(a *app, fs *flag.FlagSet, args []string) error {
+	var ref contractRef
+	ref.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	idType, idContent, err := ref.resolve(fs)
+	if err != nil {
+		return err
+	}
+	c, err := a.clientFor()
+	if err != nil {
+		return err
+	}
+	rsp, err := c.LookupContractDetailV4Ctx(a.ctx, &goyht.YhtContractDetailReq{IDType: idType, IDContent: idContent})
+	if err != nil {
+		return err
+	}
+	d := rsp.Data
+	t := fields(
+		"contractId", strconv.Itoa(d.ContractID),
+		"contractNo", d.ContractNo,
+		"title", d.Title,
+		"status", d.Status.Label(),
+		"created", d.GmtCreate,
+		"modified", d.GmtModify,
+	)
+	for i, s := range d.Signers {
+		t.add(fmt.Sprintf("signer[%d]", i), fmt.Sprintf("%d %s %s", s.SignerID, s.SignStatus.Label(), s.SignTime))
+	}
+	return a.out.print(d, t)
+}
+
+func contractList(a *app, fs *flag.FlagSet, args []string) error {
+	req := goyht.YhtListContractsReq{PageNum: 1, PageSize: 20}
+	var status string
+	fs.IntVar(&req.PageNum, "page", req.PageNum, "page number, from 1")
+	fs.IntVar(&req.PageSize, "size", req.PageSize, "page size")
+	fs.StringVar(&status, "status", "", "contract status code, 0 draft, 1 signing, 2 completed, 3 invalid")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	req.Status = goyht.ContractStatus(status)
+	c, err := a.clientFor()
+	if err != nil {
+		return err
+	}
+	rsp, err := c.ListContractsV4Ctx(a.ctx, &req)
+	if err != nil {
+		return err
+	}
+	t := &table{header: []string{"ID", "NO", "TITLE", "STATUS", "MODIFIED"}}
+	for _, d := range rsp.Data.Contracts {
+		t.add(strconv.Itoa(d.ContractID), d.ContractNo, d.Title, d.Status.Label(), d.GmtModify)
+	}
+	t.add("", "", fmt.Sprintf("total %d", rsp.Data.Total), "", "")
+	return a.out.print(rsp.Data, t)
+}
+
+func contractDownload(a *app, fs *flag.FlagSet, args []string) error {
+	var (
+		ref  contractRef
+		path string
+	)
+	ref.register(fs)
+	fs.StringVar(&path, "out", "", "output file, - for stdout, contract-<id>.pdf by default")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	idType, idContent, err := ref.resolve(fs)
+	if err != nil {
+		return err
+	}
+	c, err := a.clientFor()
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		// 合同编号可能包含路径分隔符，默认文件名只取最后一段
+		path = "contract-" + filepath.Base(idContent) + ".pdf"
+	}
+	req := &goyht.YhtDownloadContractReq{IDType: idType, IDContent: idContent}
+	if path == "-" {
+		_, err = c.DownloadContractTo(a.ctx, req, a.stdout)
+		return err
+	}
+	// 先下载到同目录下的临时文件，成功后再替换，失败时不影响已有的文件
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	result, err := c.DownloadContractTo(a.ctx, req, f)
+	if err == nil {
+		err = f.Chmod(0644)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		return err
+	}
+	return a.out.print(map[string]interface{}{"file": path, "size": result.Size, "sha256": result.SHA256},
+		fields("file", path, "size", strconv.FormatInt(result.Size, 10), "sha256", result.SHA256))
+}
+
+func contractInvalidate(a *app, fs *flag.FlagSet, args []string) error {
+	var (
+		ref contractRef
+		req goyht.YhtInvalidateContractReq
+	)
+	ref.register(fs)
+	fs.StringVar(&req.Remark, "remark", "", "reason of the invalidation")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	var err error
+	if req.IDType, req.IDContent, err = ref.resolve(fs); err != nil {
+		return err
+	}
+	c, err := a.clientFor()
+	if err != nil {
+		return err
+	}
+	if _, err = c.InvalidateContractV4Ctx(a.ctx, &req); err != nil {
+		return err
+	}
+	return a.out.print(map[string]string{"status": "invalidated"}, fields("contract", req.IDContent, "status", "invalidated"))
+}
+
+// readInput 读取文件，-表示标准输入
+func (a *app) readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return ioutil.ReadAll(a.stdin)
+	}
+	return ioutil.ReadFile(path)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/iotdog/goyht"
+)
+
+// loadConfig 读取配置文件、环境变量和-profile选择的运行环境
+func (a *app) loadConfig() (goyht.Config, error) {
+	opts := []goyht.LoadOption{goyht.WithEnv(a.env)}
+	if a.configPath != "" {
+		opts = append(opts, goyht.WithConfigFile(a.configPath))
+	}
+	if a.profile != "" {
+		opts = append(opts, goyht.WithProfile(a.profile))
+	}
+	cfg, err := goyht.LoadConfigCtx(a.ctx, opts...)
+	var ce *goyht.ConfigError
+	if errors.As(err, &ce) && len(ce.Missing) > 0 {
+		return cfg, fmt.Errorf("%v, set them in the config file or the environment, e.g. %s and %s",
+			err, goyht.EnvAppID, goyht.EnvAppKey)
+	}
+	return cfg, err
+}
+
+// clientFor 返回使用配置的凭证创建的客户端
+func (a *app) clientFor() (*goyht.Client, error) {
+	if a.client != nil {
+		return a.client, nil
+	}
+	cfg, err := a.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	a.config = cfg
+	a.client = goyht.NewClient(cfg)
+	return a.client, nil
+}
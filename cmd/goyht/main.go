@@ -0,0 +1,200 @@
+// Command goyht calls the YunHeTong API from the command line, it is meant
+// for operations and support staff looking up contracts, downloading files
+// and replaying notifications.
+//
+// Usage:
+//
+//	goyht [-config file] [-profile name] [-o json|table] <group> <command> [flags]
+//
+// Credentials are loaded by goyht.LoadConfig from the config file (YAML, TOML
+// or JSON) and the environment variables GOYHT_APP_ID, GOYHT_APP_KEY,
+// GOYHT_API_GATEWAY, GOYHT_AUTH_GATEWAY and so on, the environment takes
+// precedence. GOYHT_CONFIG names the config file when -config is not given,
+// -profile or GOYHT_PROFILE selects a profile of the file such as sandbox.
+// Only the production gateways are built in, other profiles must set
+// apiGateway (and authGateway for real-name authentication) in the file or
+// the environment.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/iotdog/goyht"
+)
+
+// env 读取环境变量
+type env func(key string) string
+
+// app 一次命令执行的上下文
+type app struct {
+	ctx    context.Context
+	env    env
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	configPath string
+	profile    string
+	out        *output
+	client     *goyht.Client
+	config     goyht.Config
+}
+
+// command 子命令
+type command struct {
+	usage string
+	run   func(a *app, fs *flag.FlagSet, args []string) error
+}
+
+// commands 命令组 -> 子命令
+var commands = map[string]map[string]command{
+	"auth": {
+		"login": {"check the credentials by logging in", authLogin},
+	},
+	"user": {
+		"create-person":  {"create a personal user", userCreatePerson},
+		"create-company": {"create a company user", userCreateCompany},
+		"query":          {"look up signer ids by certificate numbers", userQuery},
+	},
+	"seal": {
+		"create": {"create a personal or company seal", sealCreate},
+	},
+	"contract": {
+		"create":     {"create a contract from a template", contractCreate},
+		"add-signer": {"add a signer to a contract", contractAddSigner},
+		"sign":       {"sign a contract on behalf of a signer", contractSign},
+		"detail":     {"show a contract and its signers", contractDetail},
+		"list":       {"list contracts", contractList},
+		"download":   {"download the contract file", contractDownload},
+		"invalidate": {"invalidate a contract", contractInvalidate},
+	},
+	"notify": {
+		"parse":  {"parse a notification body", notifyParse},
+		"replay": {"resend failed notifications to a notification endpoint", notifyReplay},
+	},
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Getenv, os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run 执行命令并返回退出码：0成功，1执行失败，2用法错误
+func run(ctx context.Context, args []string, getenv env, stdin io.Reader, stdout, stderr io.Writer) int {
+	a := &app{ctx: ctx, env: getenv, stdin: stdin, stdout: stdout, stderr: stderr, out: &output{w: stdout}}
+	defer func() {
+		if a.client != nil {
+			a.client.Close()
+		}
+	}()
+
+	fs := flag.NewFlagSet("goyht", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	a.globalFlags(fs)
+	fs.Usage = func() { usage(stderr) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	args = fs.Args()
+	if len(args) < 2 {
+		usage(stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]][args[1]]
+	if !ok {
+		fmt.Fprintf(stderr, "goyht: unknown command %q\n", strings.Join(args[:2], " "))
+		usage(stderr)
+		return 2
+	}
+
+	sub := flag.NewFlagSet("goyht "+args[0]+" "+args[1], flag.ContinueOnError)
+	sub.SetOutput(stderr)
+	a.globalFlags(sub)
+	err := cmd.run(a, sub, args[2:])
+	if err != nil {
+		if code := exitCode(err); code != 1 {
+			return code
+		}
+		fmt.Fprintln(stderr, "goyht:", strings.TrimPrefix(err.Error(), "goyht: "))
+		return 1
+	}
+	return 0
+}
+
+// globalFlags 注册所有命令通用的参数，既可以写在命令组之前也可以写在子命令之后
+func (a *app) globalFlags(fs *flag.FlagSet) {
+	fs.StringVar(&a.configPath, "config", a.configPath, "config file, YAML, TOML or JSON")
+	fs.StringVar(&a.profile, "profile", a.profile, "profile of the config file, production by default")
+	fs.Var(a.out, "o", "output format, json or table")
+}
+
+// errUsage 参数错误，错误信息已输出
+var errUsage = errors.New("usage error")
+
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	}
+	return 1
+}
+
+// parseFlags 解析子命令参数，不允许多余的位置参数
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments %v\n", fs.Args())
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// require 检查必填参数
+func require(fs *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		if f := fs.Lookup(name); f == nil || f.Value.String() == "" {
+			fmt.Fprintf(fs.Output(), "-%s is required\n", name)
+			fs.Usage()
+			return errUsage
+		}
+	}
+	return nil
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: goyht [-config file] [-profile name] [-o json|table] <group> <command> [flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	groups := make([]string, 0, len(commands))
+	for g := range commands {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	for _, g := range groups {
+		names := make([]string, 0, len(commands[g]))
+		for n := range commands[g] {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			fmt.Fprintf(w, "  %-26s %s\n", g+" "+n, commands[g][n].usage)
+		}
+	}
+	fmt.Fprintln(w, "\nRun 'goyht <group> <command> -h' for the flags of a command.")
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/iotdog/goyht"
+	"github.com/iotdog/goyht/goyhttest"
+)
+
+type result struct {
+	code           int
+	stdout, stderr string
+}
+
+func runCmd(srv *goyhttest.Server, stdin string, args ...string) result {
+	vars := map[string]string{}
+	if srv != nil {
+		vars = map[string]string{
+			"GOYHT_APP_ID":       srv.AppID,
+			"GOYHT_APP_KEY":      srv.AppKey,
+			"GOYHT_API_GATEWAY":  srv.URL,
+			"GOYHT_AUTH_GATEWAY": srv.URL,
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, func(k string) string { return vars[k] }, strings.NewReader(stdin), &stdout, &stderr)
+	return result{code, stdout.String(), stderr.String()}
+}
+
+func TestContractCommands(t *testing.T) {
+	srv := goyhttest.NewServer()
+	defer srv.Close()
+
+	r := runCmd(srv, "", "user", "create-person", "-name", "张三", "-cert-num", "520103198801011432")
+	if r.code != 0 || !strings.Contains(r.stdout, "signerId") {
+		t.Fatalf("create-person: %+v", r)
+	}
+
+	r = runCmd(srv, "", "-o", "json", "contract", "create", "-title", "Contract", "-template", "92130", "-set", "${lessee}=Mike")
+	if r.code != 0 {
+		t.Fatalf("contract create: %+v", r)
+	}
+	var created struct {
+		ContractID int `json:"contractId"`
+	}
+	if err := json.Unmarshal([]byte(r.stdout), &created); err != nil || created.ContractID == 0 {
+		t.Fatalf("contract create output %q: %v", r.stdout, err)
+	}
+	id := strconv.Itoa(created.ContractID)
+
+	r = runCmd(srv, "", "contract", "detail", "-id", id, "-o", "json")
+	if r.code != 0 || !strings.Contains(r.stdout, `"contractTitle": "Contract"`) {
+		t.Fatalf("contract detail: %+v", r)
+	}
+
+	r = runCmd(srv, "", "contract", "list")
+	if r.code != 0 || !strings.Contains(r.stdout, "total 1") {
+		t.Fatalf("contract list: %+v", r)
+	}
+
+	out := filepath.Join(t.TempDir(), "contract.pdf")
+	r = runCmd(srv, "", "contract", "download", "-id", id, "-out", out)
+	if r.code != 0 {
+		t.Fatalf("contract download: %+v", r)
+	}
+	if fi, err := os.Stat(out); err != nil || fi.Size() == 0 {
+		t.Fatalf("downloaded file: %v", err)
+	}
+	// 下载失败时保留已有的文件
+	r = runCmd(srv, "", "contract", "download", "-id", "999999", "-out", out)
+	if fi, err := os.Stat(out); r.code == 0 || err != nil || fi.Size() == 0 {
+		t.Fatalf("failed download replaced the file: %+v %v", r, err)
+	}
+	if tmp, _ := filepath.Glob(out + ".tmp*"); len(tmp) != 0 {
+		t.Fatalf("temporary files left: %v", tmp)
+	}
+
+	r = runCmd(srv, "", "contract", "invalidate", "-id", id, "-remark", "test")
+	if r.code != 0 || !strings.Contains(r.stdout, "invalidated") {
+		t.Fatalf("contract invalidate: %+v", r)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"contract"},
+		{"contract", "frobnicate"},
+		{"contract", "detail"},
+		{"contract", "detail", "-id", "1", "-no", "x"},
+		{"user", "create-person", "-cert-type", "nope"},
+		{"-o", "yaml", "contract", "list"},
+	} {
+		if r := runCmd(nil, "", args...); r.code != 2 {
+			t.Errorf("%v: exit code %d, want 2, stderr %q", args, r.code, r.stderr)
+		}
+	}
+	if r := runCmd(nil, "", "contract", "list"); r.code != 1 || !strings.Contains(r.stderr, "missing appId, appKey") {
+		t.Errorf("no credentials: %+v", r)
+	}
+}
+
+func TestNotifyParse(t *testing.T) {
+	notice := `{"noticeType":1,"map":{"contractId":1001,"signerId":"7","status":"1"}}`
+	r := runCmd(nil, notice, "notify", "parse", "-o", "json")
+	if r.code != 0 {
+		t.Fatalf("notify parse: %+v", r)
+	}
+	var got map[string]string
+	if err := json.Unmarshal([]byte(r.stdout), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["event"] != "contract signed" || got["contractId"] != "1001" || got["signerId"] != "7" {
+		t.Errorf("notify parse = %v", got)
+	}
+
+	r = runCmd(nil, "notice="+notice, "notify", "parse")
+	if r.code != 0 || !strings.Contains(r.stdout, "1001") {
+		t.Errorf("notify parse form: %+v", r)
+	}
+}
+
+func TestNotifyReplay(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "notices.log")
+	store, err := goyht.OpenFileNotificationStore(storePath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	var got []string
+	h := goyht.NewNotificationHandler(goyht.WithNoticeKey("k"), goyht.WithNotificationStore(store))
+	h.HandleDefault(func(ctx context.Context, ev goyht.Event) error {
+		got = append(got, ev.Base().ID())
+		return nil
+	})
+	app := httptest.NewServer(h)
+	defer app.Close()
+
+	file := filepath.Join(t.TempDir(), "notice.json")
+	notice := `{"noticeType":2,"map":{"noticeId":"n1","contractId":"1001"}}`
+	if err := ioutil.WriteFile(file, []byte(notice), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := runCmd(nil, "", "notify", "replay", "-file", file, "-url", app.URL, "-key", "k")
+	if r.code != 0 || !strings.Contains(r.stdout, "delivered") {
+		t.Fatalf("notify replay: %+v", r)
+	}
+	if len(got) != 1 || got[0] != "n1" {
+		t.Errorf("endpoint received %q", got)
+	}
+
+	// 服务打开存储文件时从文件读取死信重放，服务处理成功后删除死信
+	notice = `{"noticeType":2,"map":{"noticeId":"n2","contractId":"1002"}}`
+	if err = store.Fail(context.Background(), "n2", []byte(notice), errors.New("db down")); err != nil {
+		t.Fatal(err)
+	}
+	r = runCmd(nil, "", "notify", "replay", "-store", storePath, "-url", app.URL, "-key", "k")
+	if r.code != 0 || len(got) != 2 || got[1] != "n2" {
+		t.Fatalf("notify replay store: %+v, received %q", r, got)
+	}
+	if letters, _ := goyht.ReadDeadLetters(storePath); len(letters) != 0 {
+		t.Errorf("delivered notifications left in the store: %+v", letters)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	srv := goyhttest.NewServer()
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "goyht.toml")
+	data := "appId = \"" + srv.AppID + "\"\n\n[profiles.sandbox]\nappKey = \"" + srv.AppKey + "\"\napiGateway = \"" + srv.URL + "\"\nauthGateway = \"" + srv.URL + "\"\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := runCmd(nil, "", "-config", path, "-profile", "sandbox", "auth", "login")
+	if r.code != 0 || !strings.Contains(r.stdout, srv.URL) {
+		t.Fatalf("auth login: %+v", r)
+	}
+	if r = runCmd(nil, "", "-config", path, "auth", "login"); r.code != 1 || !strings.Contains(r.stderr, "missing appKey") {
+		t.Errorf("production profile without key: %+v", r)
+	}
+}
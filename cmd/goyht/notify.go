@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iotdog/goyht"
+)
+
+// noticeBody 取出通知内容，输入可以是通知的JSON或notice=...表单
+func noticeBody(raw []byte) ([]byte, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '{' {
+		return raw, nil
+	}
+	form, err := url.ParseQuery(string(raw))
+	if err != nil {
+		return nil, err
+	}
+	notice := form.Get("notice")
+	if notice == "" {
+		return nil, fmt.Errorf("no notice field in the form body")
+	}
+	return []byte(notice), nil
+}
+
+// eventName 返回事件类型的名称
+func eventName(ev goyht.Event) string {
+	switch ev.(type) {
+	case *goyht.ContractSignedEvent:
+		return "contract signed"
+	case *goyht.ContractCompletedEvent:
+		return "contract completed"
+	case *goyht.ContractInvalidatedEvent:
+		return "contract invalidated"
+	case *goyht.SignerRejectedEvent:
+		return "signer rejected"
+	}
+	return "unknown"
+}
+
+func notifyParse(a *app, fs *flag.FlagSet, args []string) error {
+	var path string
+	fs.StringVar(&path, "file", "-", "notification body, the notice JSON or the form, - for stdin")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	raw, err := a.readInput(path)
+	if err != nil {
+		return err
+	}
+	data, err := noticeBody(raw)
+	if err != nil {
+		return err
+	}
+	ev, err := goyht.ParseNotice(data)
+	if err != nil {
+		return err
+	}
+	n := ev.Base()
+	result := map[string]string{
+		"event":      eventName(ev),
+		"type":       strconv.Itoa(int(n.Type)),
+		"id":         n.ID(),
+		"content":    n.Content,
+		"noticeTime": "",
+	}
+	if !n.Time().IsZero() {
+		result["noticeTime"] = n.Time().Format("2006-01-02 15:04:05")
+	}
+	var ce *goyht.ContractEvent
+	switch e := ev.(type) {
+	case *goyht.ContractSignedEvent:
+		ce = &e.ContractEvent
+		result["signerId"], result["appUserId"] = e.SignerID, e.AppUserID
+	case *goyht.SignerRejectedEvent:
+		ce = &e.ContractEvent
+		result["signerId"], result["appUserId"] = e.SignerID, e.AppUserID
+	case *goyht.ContractCompletedEvent:
+		ce = &e.ContractEvent
+	case *goyht.ContractInvalidatedEvent:
+		ce = &e.ContractEvent
+	}
+	if ce != nil {
+		result["contractId"], result["contractNo"] = ce.ContractID, ce.ContractNo
+		result["status"] = string(ce.Status)
+	}
+
+	t := fields("event", result["event"], "type", result["type"], "id", result["id"])
+	for _, k := range []string{"contractId", "contractNo", "status", "signerId", "appUserId", "noticeTime", "content"} {
+		if v, ok := result[k]; ok && v != "" {
+			if k == "status" {
+				v += " " + goyht.ContractStatus(v).Label()
+			}
+			t.add(k, v)
+		}
+	}
+	return a.out.print(result, t)
+}
+
+func notifyReplay(a *app, fs *flag.FlagSet, args []string) error {
+	var (
+		storePath, file, target, id, key string
+	)
+	fs.StringVar(&storePath, "store", "", "notification store file holding the failed notifications, read without changes")
+	fs.StringVar(&file, "file", "", "a single notification body to send instead of -store, - for stdin")
+	fs.StringVar(&target, "url", "", "notification endpoint of the application")
+	fs.StringVar(&id, "id", "", "replay only the notification with this id")
+	fs.StringVar(&key, "key", "", "key to sign the notifications, the app key by default")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := require(fs, "url"); err != nil {
+		return err
+	}
+	if (storePath == "") == (file == "") {
+		fmt.Fprintln(fs.Output(), "one of -store and -file is required")
+		fs.Usage()
+		return errUsage
+	}
+	if key == "" {
+		cfg, err := a.loadConfig()
+		if err != nil {
+			return err
+		}
+		key = cfg.AppKey
+	}
+
+	var letters []goyht.DeadLetter
+	if file != "" {
+		raw, err := a.readInput(file)
+		if err != nil {
+			return err
+		}
+		data, err := noticeBody(raw)
+		if err != nil {
+			return err
+		}
+		ev, err := goyht.ParseNotice(data)
+		if err != nil {
+			return err
+		}
+		letters = append(letters, goyht.DeadLetter{ID: ev.Base().ID(), Notice: data})
+	} else {
+		// 服务可能正在使用存储文件，只读取死信。服务处理重放的通知成功后自行删除死信
+		var err error
+		if letters, err = goyht.ReadDeadLetters(storePath); err != nil {
+			return err
+		}
+	}
+
+	type outcome struct {
+		ID     string `json:"id"`
+		OK     bool   `json:"ok"`
+		Result string `json:"result"`
+	}
+	var outcomes []outcome
+	t := &table{header: []string{"ID", "RESULT"}}
+	failed := 0
+	for _, d := range letters {
+		if id != "" && d.ID != id {
+			continue
+		}
+		err := postNotice(a, target, key, d.Notice)
+		o := outcome{ID: d.ID, OK: err == nil, Result: "delivered"}
+		if err != nil {
+			o.Result = err.Error()
+			failed++
+		}
+		outcomes = append(outcomes, o)
+		t.add(o.ID, o.Result)
+	}
+	if id != "" && len(outcomes) == 0 {
+		return fmt.Errorf("no failed notification with id %s", id)
+	}
+	if err := a.out.print(outcomes, t); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d notifications not delivered", failed, len(outcomes))
+	}
+	return nil
+}
+
+// postNotice 以云合同的格式发送通知，应用应答response为true时成功
+func postNotice(a *app, target, key string, notice []byte) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	form := url.Values{
+		"notice":                   {string(notice)},
+		goyht.NoticeTimestampField: {timestamp},
+	}
+	if key != "" {
+		form.Set(goyht.NoticeSignField, goyht.SignNotice(key, timestamp, notice))
+	}
+	req, err := http.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(a.ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	body, _ := ioutil.ReadAll(rsp.Body)
+	var answer struct {
+		Response bool   `json:"response"`
+		Msg      string `json:"msg"`
+	}
+	if err = json.Unmarshal(body, &answer); err != nil {
+		return fmt.Errorf("HTTP %d: unexpected answer %q", rsp.StatusCode, body)
+	}
+	if !answer.Response {
+		return fmt.Errorf("HTTP %d: rejected: %s", rsp.StatusCode, answer.Msg)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// output 按-o参数输出结果，默认为表格
+type output struct {
+	w      io.Writer
+	format string
+}
+
+// String implements flag.Value.
+func (o *output) String() string {
+	if o == nil || o.format == "" {
+		return "table"
+	}
+	return o.format
+}
+
+// Set implements flag.Value.
+func (o *output) Set(s string) error {
+	switch s {
+	case "json", "table":
+		o.format = s
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q", s)
+}
+
+// table 表格形式的结果
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// print 以JSON输出v，或以表格输出t
+func (o *output) print(v interface{}, t *table) error {
+	if o.format == "json" {
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	if len(t.header) > 0 {
+		fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	}
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// fields 两列的键值表格
+func fields(kv ...string) *table {
+	t := &table{header: []string{"FIELD", "VALUE"}}
+	for i := 0; i+1 < len(kv); i += 2 {
+		t.add(kv[i], kv[i+1])
+	}
+	return t
+}
@@ -0,0 +1,389 @@
+package goyht
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/iotdog/goyht/internal/conf"
+	"github.com/iotdog/goyht/validate"
+)
+
+// APIVersion 云合同接口版本，决定配置需要哪些字段
+type APIVersion string
+
+// 云合同接口版本
+const (
+	APIV3 APIVersion = "v3" // 需要AppID和Password
+	APIV4 APIVersion = "v4" // 需要AppID和AppKey
+)
+
+// 运行环境。只有生产环境内置了网关地址，云合同没有公开固定的沙箱地址，
+// 沙箱等其他环境须在配置文件的profiles.<环境>或环境变量中配置apiGateway，使用实名认证时还须配置authGateway
+const (
+	ProfileProduction = "production" // 生产环境，是默认的运行环境，未配置的网关使用云合同的正式地址
+	ProfileSandbox    = "sandbox"    // 沙箱环境，网关地址由云合同为应用分配
+)
+
+// LoadConfig读取的环境变量
+const (
+	EnvConfig      = "GOYHT_CONFIG"       // 配置文件路径
+	EnvProfile     = "GOYHT_PROFILE"      // 运行环境
+	EnvAPIVersion  = "GOYHT_API_VERSION"  // 接口版本，v3或v4
+	EnvAppID       = "GOYHT_APP_ID"       // Config.AppID
+	EnvAppKey      = "GOYHT_APP_KEY"      // Config.AppKey
+	EnvPassword    = "GOYHT_PASSWORD"     // Config.Password
+	EnvAPIGateway  = "GOYHT_API_GATEWAY"  // Config.APIGateway
+	EnvAuthID      = "GOYHT_AUTH_ID"      // Config.AuthID
+	EnvAuthPWD     = "GOYHT_AUTH_PWD"     // Config.AuthPWD
+	EnvAuthGateway = "GOYHT_AUTH_GATEWAY" // Config.AuthGateway
+)
+
+// configField 配置项在配置文件、环境变量和密钥中的名称
+type configField struct {
+	name   string // 配置文件中的键，也是SecretProvider中的名称
+	env    string
+	value  *string
+	secret bool // 密钥类配置，String中隐藏，可以由SecretProvider提供
+}
+
+func (c *Config) fields() []configField {
+	return []configField{
+		{"appId", EnvAppID, &c.AppID, false},
+		{"appKey", EnvAppKey, &c.AppKey, true},
+		{"password", EnvPassword, &c.Password, true},
+		{"apiGateway", EnvAPIGateway, &c.APIGateway, false},
+		{"authId", EnvAuthID, &c.AuthID, false},
+		{"authPwd", EnvAuthPWD, &c.AuthPWD, true},
+		{"authGateway", EnvAuthGateway, &c.AuthGateway, false},
+	}
+}
+
+// merge 以o中非空的配置项覆盖c
+func (c *Config) merge(o Config) {
+	dst, src := c.fields(), o.fields()
+	for i := range dst {
+		if *src[i].value != "" {
+			*dst[i].value = *src[i].value
+		}
+	}
+}
+
+// String 返回配置的文本形式，AppKey、Password和AuthPWD以******代替，可以写入日志
+func (c Config) String() string {
+	var b strings.Builder
+	b.WriteString("goyht.Config{")
+	for i, f := range c.fields() {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		v := *f.value
+		if f.secret && v != "" {
+			v = "******"
+		}
+		fmt.Fprintf(&b, "%s:%s", f.name, v)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// GoString implements fmt.GoStringer, it hides the secrets like String.
+func (c Config) GoString() string {
+	return c.String()
+}
+
+// ConfigError 配置不完整或无效。errors.Is(err, ErrInvalidParam)为true
+type ConfigError struct {
+	Version APIVersion
+	Missing []string           // 缺失的配置项
+	Invalid []*ValidationError // 取值无效的配置项
+}
+
+// Error implements the error interface.
+func (e *ConfigError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(e.Missing, ", "))
+	}
+	for _, v := range e.Invalid {
+		parts = append(parts, fmt.Sprintf("invalid %s: %v", v.Field, v.Err))
+	}
+	return fmt.Sprintf("goyht: config for API %s: %s", e.Version, strings.Join(parts, "; "))
+}
+
+// Is reports whether target is ErrInvalidParam.
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrInvalidParam
+}
+
+// Validate 检查version接口需要的配置是否齐全，网关地址是否有效，
+// 配置不符时返回*ConfigError。AuthID和AuthPWD用于实名认证，需要同时配置
+func (c Config) Validate(version APIVersion) error {
+	e := &ConfigError{Version: version}
+	need := func(name, value string) {
+		if value == "" {
+			e.Missing = append(e.Missing, name)
+		}
+	}
+	switch version {
+	case APIV3:
+		need("appId", c.AppID)
+		need("password", c.Password)
+	case APIV4:
+		need("appId", c.AppID)
+		need("appKey", c.AppKey)
+	default:
+		e.Invalid = append(e.Invalid, &ValidationError{
+			Field: "apiVersion",
+			Err:   fmt.Errorf("%w: unknown API version %q, expect v3 or v4", validate.ErrFormat, version),
+		})
+	}
+	if c.AuthID != "" || c.AuthPWD != "" {
+		need("authId", c.AuthID)
+		need("authPwd", c.AuthPWD)
+	}
+	for _, g := range []struct{ name, value string }{{"apiGateway", c.APIGateway}, {"authGateway", c.AuthGateway}} {
+		if g.value == "" {
+			continue
+		}
+		if u, err := url.Parse(g.value); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			e.Invalid = append(e.Invalid, &ValidationError{
+				Field: g.name,
+				Err:   fmt.Errorf("%w: gateway %q is not an http(s) URL", validate.ErrFormat, g.value),
+			})
+		}
+	}
+	if len(e.Missing) > 0 || len(e.Invalid) > 0 {
+		return e
+	}
+	return nil
+}
+
+// ErrSecretNotFound SecretProvider中没有该密钥
+var ErrSecretNotFound = errors.New("goyht: secret not found")
+
+// SecretProvider 提供密钥类配置，例如从密钥管理服务读取。name为appKey、password或authPwd，
+// 没有该密钥时返回ErrSecretNotFound
+type SecretProvider interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// SecretProviderFunc 函数形式的SecretProvider
+type SecretProviderFunc func(ctx context.Context, name string) (string, error)
+
+// Secret implements SecretProvider.
+func (f SecretProviderFunc) Secret(ctx context.Context, name string) (string, error) {
+	return f(ctx, name)
+}
+
+// DirSecretProvider 从目录下与密钥同名的文件读取密钥，例如Docker和Kubernetes挂载的/run/secrets
+type DirSecretProvider string
+
+// Secret implements SecretProvider.
+func (d DirSecretProvider) Secret(ctx context.Context, name string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(string(d), name))
+	if os.IsNotExist(err) {
+		return "", ErrSecretNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// configLoader LoadConfig的选项
+type configLoader struct {
+	file    string
+	getenv  func(string) string
+	secrets SecretProvider
+	profile string
+	version APIVersion
+}
+
+// LoadOption LoadConfig的选项
+type LoadOption func(*configLoader)
+
+// WithConfigFile 读取YAML(.yaml/.yml)、TOML(.toml)或JSON(.json)配置文件，
+// 默认读取环境变量GOYHT_CONFIG指定的文件。支持的YAML和TOML语法见LoadConfig
+func WithConfigFile(path string) LoadOption {
+	return func(l *configLoader) {
+		l.file = path
+	}
+}
+
+// WithEnv 使用getenv读取环境变量，默认为os.Getenv
+func WithEnv(getenv func(key string) string) LoadOption {
+	return func(l *configLoader) {
+		l.getenv = getenv
+	}
+}
+
+// WithSecretProvider 从p读取AppKey、Password和AuthPWD
+func WithSecretProvider(p SecretProvider) LoadOption {
+	return func(l *configLoader) {
+		l.secrets = p
+	}
+}
+
+// WithProfile 选择运行环境，优先于GOYHT_PROFILE和配置文件中的profile
+func WithProfile(name string) LoadOption {
+	return func(l *configLoader) {
+		l.profile = name
+	}
+}
+
+// WithAPIVersion 按version接口校验配置，优先于GOYHT_API_VERSION和配置文件中的apiVersion
+func WithAPIVersion(version APIVersion) LoadOption {
+	return func(l *configLoader) {
+		l.version = version
+	}
+}
+
+// configFile 配置文件的内容
+type configFile struct {
+	Config
+	Profile    string            `json:"profile"`
+	APIVersion APIVersion        `json:"apiVersion"`
+	Profiles   map[string]Config `json:"profiles"`
+}
+
+// LoadConfig 从配置文件、SecretProvider和环境变量读取配置，后者覆盖前者，
+// 并按接口版本校验，配置不符时返回*ConfigError。配置文件的格式为：
+//
+//	profile: sandbox
+//	apiVersion: v4
+//	appId: "2020010100001"
+//	profiles:
+//	  sandbox:
+//	    appKey: sandbox-key
+//	    apiGateway: https://sandbox.example.com/api
+//
+// profiles中所选环境的配置覆盖顶层的配置。只有生产环境内置网关地址，未配置的网关使用云合同的正式地址；
+// 沙箱等其他环境没有内置地址，必须配置apiGateway，配置了authId或authPwd时还须配置authGateway，否则返回ErrInvalidParam。未指定接口版本时，只有Password没有AppKey按V3校验，否则按V4校验。
+//
+// 配置文件支持的YAML和TOML语法与TemplateRegistry.LoadFile相同，只是常用的子集，
+// 例如不支持YAML的锚点和多行文本；TOML的日期时间按字符串读取
+func LoadConfig(opts ...LoadOption) (Config, error) {
+	return LoadConfigCtx(context.Background(), opts...)
+}
+
+// LoadConfigCtx is like LoadConfig but passes ctx to the SecretProvider.
+func LoadConfigCtx(ctx context.Context, opts ...LoadOption) (Config, error) {
+	l := &configLoader{getenv: os.Getenv}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	var file configFile
+	path := l.file
+	if path == "" {
+		path = l.getenv(EnvConfig)
+	}
+	if path != "" {
+		tree, err := readConfigTree(path)
+		if err == nil {
+			err = conf.Decode(tree, &file)
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("goyht: load %s: %w", path, err)
+		}
+	}
+	profile := firstNonEmpty(l.profile, l.getenv(EnvProfile), file.Profile, ProfileProduction)
+	version := APIVersion(strings.ToLower(firstNonEmpty(string(l.version), l.getenv(EnvAPIVersion), string(file.APIVersion))))
+
+	cfg := file.Config
+	cfg.merge(file.Profiles[profile])
+	if l.secrets != nil {
+		for _, f := range cfg.fields() {
+			if !f.secret {
+				continue
+			}
+			v, err := l.secrets.Secret(ctx, f.name)
+			switch {
+			case errors.Is(err, ErrSecretNotFound):
+			case err != nil:
+				return Config{}, fmt.Errorf("goyht: secret %s: %w", f.name, err)
+			default:
+				*f.value = v
+			}
+		}
+	}
+	for _, f := range cfg.fields() {
+		if v := l.getenv(f.env); v != "" {
+			*f.value = v
+		}
+	}
+
+	if version == "" {
+		version = APIV4
+		if cfg.AppKey == "" && cfg.Password != "" {
+			version = APIV3
+		}
+	}
+	if profile == ProfileProduction {
+		if cfg.APIGateway == "" {
+			cfg.APIGateway = YHTAPIGatewayV4
+			if version == APIV3 {
+				cfg.APIGateway = YHTAPIGateway
+			}
+		}
+		if cfg.AuthGateway == "" {
+			cfg.AuthGateway = YHTAuthGateway
+		}
+	} else {
+		if cfg.APIGateway == "" {
+			return Config{}, fmt.Errorf("%w: profile %s has no apiGateway, set it in profiles.%s of the config file or %s",
+				ErrInvalidParam, profile, profile, EnvAPIGateway)
+		}
+		if cfg.AuthGateway == "" && (cfg.AuthID != "" || cfg.AuthPWD != "") {
+			return Config{}, fmt.Errorf("%w: profile %s uses real-name authentication but has no authGateway, set it in profiles.%s of the config file or %s",
+				ErrInvalidParam, profile, profile, EnvAuthGateway)
+		}
+	}
+	if err := cfg.Validate(version); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// readConfigTree 按扩展名解析YAML、TOML或JSON文件
+func readConfigTree(path string) (interface{}, error) {
+	var parse func([]byte) (interface{}, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		parse = conf.ParseYAML
+	case ".toml":
+		parse = conf.ParseTOML
+	case ".json":
+		parse = func(data []byte) (interface{}, error) {
+			var tree interface{}
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.UseNumber()
+			err := dec.Decode(&tree)
+			return tree, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported file format %s", ErrInvalidParam, path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parse(data)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,159 @@
+package goyht
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func envMap(m map[string]string) func(string) string {
+	return func(key string) string { return m[key] }
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "goyht.yaml")
+	ioutil.WriteFile(yamlPath, []byte(`
+profile: sandbox
+appId: app
+appKey: prod-key
+profiles:
+  sandbox:
+    appKey: sandbox-key
+    apiGateway: https://sandbox.example.com/api
+`), 0644)
+
+	cfg, err := LoadConfig(WithConfigFile(yamlPath), WithEnv(envMap(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{AppID: "app", AppKey: "sandbox-key", APIGateway: "https://sandbox.example.com/api"}
+	if cfg != want {
+		t.Errorf("sandbox config = %#v", cfg)
+	}
+
+	// 环境变量覆盖配置文件，生产环境使用默认网关
+	cfg, err = LoadConfig(WithEnv(envMap(map[string]string{
+		EnvConfig:  yamlPath,
+		EnvProfile: ProfileProduction,
+		EnvAppKey:  "env-key",
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = Config{AppID: "app", AppKey: "env-key", APIGateway: YHTAPIGatewayV4, AuthGateway: YHTAuthGateway}
+	if cfg != want {
+		t.Errorf("production config = %#v", cfg)
+	}
+
+	tomlPath := filepath.Join(dir, "goyht.toml")
+	ioutil.WriteFile(tomlPath, []byte(`
+apiVersion = "v3"
+appId = "app"
+
+[profiles.production]
+authId = "auth"
+`), 0644)
+	secrets := SecretProviderFunc(func(ctx context.Context, name string) (string, error) {
+		switch name {
+		case "password":
+			return "pwd", nil
+		case "authPwd":
+			return "auth-pwd", nil
+		}
+		return "", ErrSecretNotFound
+	})
+	cfg, err = LoadConfig(WithConfigFile(tomlPath), WithEnv(envMap(nil)), WithSecretProvider(secrets))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = Config{AppID: "app", Password: "pwd", APIGateway: YHTAPIGateway, AuthID: "auth", AuthPWD: "auth-pwd", AuthGateway: YHTAuthGateway}
+	if cfg != want {
+		t.Errorf("v3 config = %#v", cfg)
+	}
+
+	broken := SecretProviderFunc(func(context.Context, string) (string, error) {
+		return "", errors.New("vault sealed")
+	})
+	if _, err = LoadConfig(WithConfigFile(tomlPath), WithEnv(envMap(nil)), WithSecretProvider(broken)); err == nil || !strings.Contains(err.Error(), "vault sealed") {
+		t.Errorf("secret provider error = %v", err)
+	}
+
+	secretDir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(secretDir, "appKey"), []byte("file-key\n"), 0600)
+	cfg, err = LoadConfig(WithEnv(envMap(map[string]string{EnvAppID: "app"})), WithSecretProvider(DirSecretProvider(secretDir)))
+	if err != nil || cfg.AppKey != "file-key" {
+		t.Errorf("DirSecretProvider: %v, %v", cfg, err)
+	}
+
+	for _, profile := range []string{ProfileSandbox, "staging"} {
+		_, err = LoadConfig(WithEnv(envMap(map[string]string{EnvAppID: "app", EnvAppKey: "key"})), WithProfile(profile))
+		if !errors.Is(err, ErrInvalidParam) || !strings.Contains(err.Error(), "profiles."+profile) {
+			t.Errorf("profile %s without gateway: %v", profile, err)
+		}
+	}
+	// 使用实名认证的沙箱环境须配置authGateway
+	sandboxEnv := map[string]string{EnvAppID: "app", EnvAppKey: "key", EnvAPIGateway: "https://sandbox.example.com/api", EnvAuthID: "auth", EnvAuthPWD: "pwd"}
+	_, err = LoadConfig(WithEnv(envMap(sandboxEnv)), WithProfile(ProfileSandbox))
+	if !errors.Is(err, ErrInvalidParam) || !strings.Contains(err.Error(), "authGateway") {
+		t.Errorf("sandbox without auth gateway: %v", err)
+	}
+	sandboxEnv[EnvAuthGateway] = "https://sandbox.example.com/auth"
+	if _, err = LoadConfig(WithEnv(envMap(sandboxEnv)), WithProfile(ProfileSandbox)); err != nil {
+		t.Errorf("sandbox with auth gateway: %v", err)
+	}
+	if _, err = LoadConfig(WithConfigFile(filepath.Join(dir, "goyht.ini")), WithEnv(envMap(nil))); !errors.Is(err, ErrInvalidParam) {
+		t.Errorf("unsupported file: %v", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cases := []struct {
+		cfg     Config
+		version APIVersion
+		missing []string
+		invalid []string
+	}{
+		{Config{AppID: "app", AppKey: "key"}, APIV4, nil, nil},
+		{Config{AppID: "app", AppKey: "key"}, APIV3, []string{"password"}, nil},
+		{Config{Password: "pwd"}, APIV4, []string{"appId", "appKey"}, nil},
+		{Config{AppID: "app", Password: "pwd", AuthID: "auth"}, APIV3, []string{"authPwd"}, nil},
+		{Config{AppID: "app", AppKey: "key", APIGateway: "api.yunhetong.com", AuthGateway: "ftp://x"}, APIV4, nil, []string{"apiGateway", "authGateway"}},
+		{Config{AppID: "app", AppKey: "key"}, "v5", nil, []string{"apiVersion"}},
+	}
+	for _, c := range cases {
+		err := c.cfg.Validate(c.version)
+		if c.missing == nil && c.invalid == nil {
+			if err != nil {
+				t.Errorf("Validate(%s) = %v", c.version, err)
+			}
+			continue
+		}
+		var ce *ConfigError
+		if !errors.As(err, &ce) || !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("Validate(%s) = %v, want *ConfigError", c.version, err)
+			continue
+		}
+		var invalid []string
+		for _, v := range ce.Invalid {
+			invalid = append(invalid, v.Field)
+		}
+		if !reflect.DeepEqual(ce.Missing, c.missing) || !reflect.DeepEqual(invalid, c.invalid) {
+			t.Errorf("Validate(%s) = %v", c.version, err)
+		}
+	}
+}
+
+func TestConfigString(t *testing.T) {
+	cfg := Config{AppID: "app", AppKey: "secret-key", Password: "secret-pwd", AuthPWD: "secret-auth"}
+	for _, s := range []string{cfg.String(), fmt.Sprintf("%v", cfg), fmt.Sprintf("%+v", &cfg), fmt.Sprintf("%#v", cfg)} {
+		if strings.Contains(s, "secret") || !strings.Contains(s, "appId:app") || !strings.Contains(s, "appKey:******") {
+			t.Errorf("config printed as %q", s)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package goyht
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// ErrTooLarge 合同文件超过大小限制
+var ErrTooLarge = errors.New("goyht: contract file exceeds size limit")
+
+// maxErrorPayload 错误应答最多读取的字节数
+const maxErrorPayload = 64 << 10
+
+// DownloadResult 合同下载结果
+type DownloadResult struct {
+	Size        int64  // 写入的字节数
+	SHA256      string // 文件内容的SHA-256摘要，十六进制
+	ContentType string // 应答的Content-Type
+}
+
+// DownloadContractTo 下载合同文件并以流的方式写入w，不会将整个文件读入内存。
+// 网关返回JSON错误应答时返回*APIError，文件超过WithMaxDownloadSize设置的大小时返回ErrTooLarge，
+// 此时w中可能已写入部分内容。
+func (c *Client) DownloadContractTo(ctx context.Context, req *YhtDownloadContractReq, w io.Writer) (*DownloadResult, error) {
+	if nil == req || !validIDType(req.IDType) || w == nil {
+		return nil, ErrInvalidParam
+	}
+	jsonData, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := c.withToken(ctx, req.URI(), func(token string) (interface{}, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.APIGateway+req.URI(), bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Add("Content-Type", "application/json;charset=UTF-8")
+		httpReq.Header.Add("token", token)
+		return c.download(httpReq, req.URI(), w)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret.(*DownloadResult), nil
+}
+
+// download 发送下载请求并将文件写入w，同时计算摘要并检查大小
+func (c *Client) download(req *http.Request, uri string, w io.Writer) (*DownloadResult, error) {
+	rsp, info, err := c.open(req, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	// 网关出错时以JSON返回错误信息，Content-Type不一定正确，因此同时检查内容
+	body := bufio.NewReader(rsp.Body)
+	head, _ := body.Peek(1)
+	contentType := rsp.Header.Get("Content-Type")
+	if info.status >= http.StatusBadRequest || strings.Contains(contentType, "json") || bytes.HasPrefix(head, []byte("{")) {
+		data, err := ioutil.ReadAll(io.LimitReader(body, maxErrorPayload))
+		if err != nil {
+			return nil, info.networkError(err)
+		}
+		return nil, errorPayload(data, info)
+	}
+
+	limit := c.maxDownload
+	if limit > 0 && rsp.ContentLength > limit {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, rsp.ContentLength)
+	}
+
+	h := sha256.New()
+	src := io.Reader(body)
+	if limit > 0 {
+		src = io.LimitReader(body, limit+1)
+	}
+	n, err := io.Copy(io.MultiWriter(w, h), src)
+	if err != nil {
+		return nil, info.networkError(err)
+	}
+	if limit > 0 && n > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, limit)
+	}
+	if rsp.ContentLength >= 0 && n != rsp.ContentLength {
+		return nil, info.networkError(io.ErrUnexpectedEOF)
+	}
+
+	return &DownloadResult{
+		Size:        n,
+		SHA256:      hex.EncodeToString(h.Sum(nil)),
+		ContentType: contentType,
+	}, nil
+}
+
+// errorPayload 将V3或V4格式的JSON错误应答转换为*APIError
+func errorPayload(data []byte, info callInfo) error {
+	payload := struct {
+		Code    int             `json:"code"`
+		SubCode int             `json:"subCode"`
+		Msg     json.RawMessage `json:"msg"`
+		Message string          `json:"message"`
+	}{}
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Code == 0 {
+		return info.apiError(info.status, 0, string(data), nil)
+	}
+	msg := payload.Message
+	if len(payload.Msg) > 0 {
+		msg = decodeMsg(payload.Msg)
+	}
+	return info.apiError(payload.Code, payload.SubCode, msg, payload.Msg)
+}
@@ -0,0 +1,61 @@
+package goyht
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDownloadContractTo(t *testing.T) {
+	pdf := bytes.Repeat([]byte("%PDF-1.4 contract "), 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("token") != "tok" {
+			// 部分错误应答的Content-Type并不是JSON
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte(`{"code":404,"msg":"合同不存在"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(pdf)
+	}))
+	defer srv.Close()
+
+	req := &YhtDownloadContractReq{IDType: YHTIDTypeSystem, IDContent: "1"}
+	cli := NewClient(Config{}, WithAPIGateway(srv.URL), WithTokenRefresher(staticToken("tok")))
+	defer cli.Close()
+
+	buf := &bytes.Buffer{}
+	ret, err := cli.DownloadContractTo(context.Background(), req, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(pdf)
+	if ret.Size != int64(len(pdf)) || ret.SHA256 != hex.EncodeToString(sum[:]) || !bytes.Equal(buf.Bytes(), pdf) {
+		t.Fatalf("unexpected result %+v", ret)
+	}
+
+	limited := NewClient(Config{}, WithAPIGateway(srv.URL), WithTokenRefresher(staticToken("tok")), WithMaxDownloadSize(1024))
+	defer limited.Close()
+	if _, err = limited.DownloadContractTo(context.Background(), req, &bytes.Buffer{}); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expect too large, got %v", err)
+	}
+
+	bad := NewClient(Config{}, WithAPIGateway(srv.URL), WithTokenRefresher(staticToken("other")))
+	defer bad.Close()
+	_, err = bad.DownloadContractTo(context.Background(), req, &bytes.Buffer{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 404 || apiErr.Msg != "合同不存在" {
+		t.Fatalf("expect api error, got %v", err)
+	}
+}
+
+type staticToken string
+
+func (s staticToken) RefreshToken(ctx context.Context) (string, error) {
+	return string(s), nil
+}
@@ -0,0 +1,222 @@
+package goyht
+
+//go:generate go run enum_gen.go
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// enumValue 枚举值及其中英文名称
+type enumValue struct {
+	code string
+	zh   string
+	en   string
+}
+
+// enumSpec 枚举类型的全部取值。枚举类型的String、Label、EnglishLabel、Valid
+// 和JSON编解码方法由enum_gen.go生成
+type enumSpec []enumValue
+
+func (s enumSpec) lookup(code string) (enumValue, bool) {
+	for _, v := range s {
+		if v.code == code {
+			return v, true
+		}
+	}
+	return enumValue{}, false
+}
+
+func (s enumSpec) valid(code string) bool {
+	_, ok := s.lookup(code)
+	return ok
+}
+
+func (s enumSpec) codes() []string {
+	codes := make([]string, len(s))
+	for i, v := range s {
+		codes[i] = v.code
+	}
+	return codes
+}
+
+func (s enumSpec) zh(code string) string {
+	if v, ok := s.lookup(code); ok {
+		return v.zh
+	}
+	return "未知(" + code + ")"
+}
+
+func (s enumSpec) en(code string) string {
+	if v, ok := s.lookup(code); ok {
+		return v.en
+	}
+	return "unknown(" + code + ")"
+}
+
+// parse 将取值或中英文名称转换为取值，无法识别时原样返回
+func (s enumSpec) parse(text string) string {
+	text = strings.TrimSpace(text)
+	for _, v := range s {
+		if text == v.code || text == v.zh || strings.EqualFold(text, v.en) {
+			return v.code
+		}
+	}
+	return text
+}
+
+// unmarshal 解析JSON字符串或数字，字符串可以是取值或中英文名称
+func (s enumSpec) unmarshal(data []byte) (string, error) {
+	if bytes.Equal(data, []byte("null")) {
+		return "", nil
+	}
+	var text string
+	if len(data) > 0 && data[0] != '"' {
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return "", err
+		}
+		return n.String(), nil
+	}
+	if err := json.Unmarshal(data, &text); err != nil {
+		return "", err
+	}
+	return s.parse(text), nil
+}
+
+// IdentityRegion 个人用户身份地区类型
+type IdentityRegion string
+
+var identityRegions = enumSpec{
+	{string(YHTIdentityRegionMainland), "大陆", "Mainland China"},
+	{string(YHTIdentityRegionHK), "香港", "Hong Kong"},
+	{string(YHTIdentityRegionTaiwan), "台湾", "Taiwan"},
+	{string(YHTIdentityRegionMacao), "澳门", "Macao"},
+	{string(YHTIdentityRegionForeign), "海外", "Overseas"},
+}
+
+// PersonCertType 个人用户证件类型
+type PersonCertType string
+
+var personCertTypes = enumSpec{
+	{string(YHTPersonCertTypeIDCard), "身份证", "Identity card"},
+	{string(YHTPersonCertTypePassport), "护照", "Passport"},
+	{string(YHTPersonCertTypeEEP), "港澳通行证", "Exit-entry permit"},
+	{string(YHTPersonCertTypeMTPForTW), "台胞证", "Mainland travel permit for Taiwan residents"},
+	{string(YHTPersonCertTypeMTPForHM), "港澳居民来往内地通行证", "Mainland travel permit for HK and Macao residents"},
+	{string(YHTPersonCertTypeOther), "其它", "Other"},
+}
+
+// PhoneRegion 个人用户手机号地区类型
+type PhoneRegion string
+
+var phoneRegions = enumSpec{
+	{string(YHTPhoneRegionMainland), "大陆", "Mainland China"},
+	{string(YHTPhoneRegionHKMacao), "香港澳门", "Hong Kong or Macao"},
+	{string(YHTPhoneRegionTaiwan), "台湾", "Taiwan"},
+}
+
+// CompanyCertType 企业用户证件类型
+type CompanyCertType string
+
+var companyCertTypes = enumSpec{
+	{string(YHTCompanyCertTypeUniformSocailCreditCode), "统一社会信用代码", "Unified social credit code"},
+}
+
+// BorderType 个人印章边框类型
+type BorderType string
+
+var borderTypes = enumSpec{
+	{string(YHTPMWithBorder), "有边框", "With border"},
+	{string(YHTPMWithoutBorder), "无边框", "Without border"},
+}
+
+// FontFamily 个人印章字体
+type FontFamily string
+
+var fontFamilies = enumSpec{
+	{string(YHTPMFontKaiti), "楷体", "Kaiti"},
+	{string(YHTPMFontHWFS), "华文仿宋", "STFangsong"},
+	{string(YHTPMFontHWKT), "华文楷体", "STKaiti"},
+	{string(YHTPMFontMSYH), "微软雅黑", "Microsoft YaHei"},
+}
+
+// FontColor 印章颜色
+type FontColor string
+
+var fontColors = enumSpec{
+	{string(YHTMFontColorRed), "红", "Red"},
+	{string(YHTMFontColorBlue), "蓝", "Blue"},
+	{string(YHTMFontColorBlack), "黑", "Black"},
+}
+
+// MoulageMode 印章模式
+type MoulageMode string
+
+var moulageModes = enumSpec{
+	{string(YHTMModeNormal), "常规", "Normal"},
+	{string(YHTMModeTransparent), "透明", "Transparent"},
+	{string(YHTMModeSafe), "脱敏", "Masked"},
+}
+
+// ZoomCode 个人印章缩放类型
+type ZoomCode string
+
+var zoomCodes = enumSpec{
+	{string(YHTPMZoomCodeLarge), "大", "Large"},
+	{string(YHTPMZoomCodeNormal), "中", "Medium"},
+	{string(YHTPMZoomCodeSmall), "小", "Small"},
+}
+
+// SealStyle 企业印章形状
+type SealStyle string
+
+var sealStyles = enumSpec{
+	{string(YHTCMStyleTypeCircle), "圆形", "Circle"},
+	{string(YHTCMStyleTypeEllipse), "椭圆", "Ellipse"},
+}
+
+// SignPositionType 签署定位方式
+type SignPositionType string
+
+var signPositionTypes = enumSpec{
+	{string(YHTSignPositionTypeKeyWord), "关键字定位", "Keyword"},
+	{string(YHTSignPositionTypePlaceHolder), "占位符定位", "Placeholder"},
+	{string(YHTSignPositionTypeCoord), "坐标定位", "Coordinates"},
+}
+
+// SignValidateType 签署验证方式
+type SignValidateType string
+
+var signValidateTypes = enumSpec{
+	{string(YHTSignValidateTypeIgnore), "不校验", "None"},
+	{string(YHTSignValidateTypeSMS), "短信验证", "SMS"},
+}
+
+// SignMode 印章使用类型
+type SignMode string
+
+var signModes = enumSpec{
+	{string(YHTSignModeSpecify), "指定印章", "Specified seal"},
+	{string(YHTSignModeRender), "每次绘制", "Drawn signature"},
+}
+
+// SignForm 合同签署形态
+type SignForm string
+
+var signForms = enumSpec{
+	{string(YHTSignFormJS), "JS集成页面", "Embedded JS page"},
+	{string(YHTSignFormH5), "独立H5页面", "Standalone H5 page"},
+}
+
+// SealClass 签章样式
+type SealClass string
+
+var sealClasses = enumSpec{
+	{string(YHTSealClassNormal), "常规", "Normal"},
+	{string(YHTSealClassQF), "骑缝", "Paging seal"},
+	{string(YHTSealClassWithAbstract), "含摘要", "With abstract"},
+	{string(YHTSealClassWithSignTime), "含签署时间", "With signing time"},
+	{string(YHTSealClassNormalWithQF), "常规+骑缝", "Normal with paging seal"},
+}
@@ -0,0 +1,84 @@
+//go:build ignore
+// +build ignore
+
+// enum_gen.go 生成枚举类型的方法，enum.go和state.go中新增枚举类型后执行go generate
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"text/template"
+)
+
+// enum 枚举类型及其取值表
+type enum struct {
+	Type string // 类型名
+	Spec string // enumSpec变量名
+	Recv string // 方法接收者
+	Noun string // Valid文档中的名词
+}
+
+var enums = []enum{
+	{"IdentityRegion", "identityRegions", "r", "region"},
+	{"PersonCertType", "personCertTypes", "t", "certificate type"},
+	{"PhoneRegion", "phoneRegions", "r", "region"},
+	{"CompanyCertType", "companyCertTypes", "t", "certificate type"},
+	{"BorderType", "borderTypes", "t", "border type"},
+	{"FontFamily", "fontFamilies", "f", "font"},
+	{"FontColor", "fontColors", "c", "colour"},
+	{"MoulageMode", "moulageModes", "m", "mode"},
+	{"ZoomCode", "zoomCodes", "z", "zoom code"},
+	{"SealStyle", "sealStyles", "s", "style"},
+	{"SignPositionType", "signPositionTypes", "t", "position type"},
+	{"SignValidateType", "signValidateTypes", "t", "validation type"},
+	{"SignMode", "signModes", "m", "sign mode"},
+	{"SignForm", "signForms", "f", "sign form"},
+	{"SealClass", "sealClasses", "c", "seal class"},
+	{"ContractStatus", "contractStatuses", "s", "contract status"},
+	{"SignStatus", "signStatuses", "s", "sign status"},
+}
+
+var tmpl = template.Must(template.New("enum").Parse(`// Code generated by enum_gen.go; DO NOT EDIT.
+
+package goyht
+
+import "encoding/json"
+{{range .}}
+// String returns the code of {{.Recv}} used by the platform.
+func ({{.Recv}} {{.Type}}) String() string { return string({{.Recv}}) }
+
+// Label returns the Chinese label of {{.Recv}}.
+func ({{.Recv}} {{.Type}}) Label() string { return {{.Spec}}.zh(string({{.Recv}})) }
+
+// EnglishLabel returns the English label of {{.Recv}}.
+func ({{.Recv}} {{.Type}}) EnglishLabel() string { return {{.Spec}}.en(string({{.Recv}})) }
+
+// Valid reports whether {{.Recv}} is a known {{.Noun}}.
+func ({{.Recv}} {{.Type}}) Valid() bool { return {{.Spec}}.valid(string({{.Recv}})) }
+
+// MarshalJSON implements json.Marshaler.
+func ({{.Recv}} {{.Type}}) MarshalJSON() ([]byte, error) { return json.Marshal(string({{.Recv}})) }
+
+// UnmarshalJSON implements json.Unmarshaler, labels are accepted.
+func ({{.Recv}} *{{.Type}}) UnmarshalJSON(data []byte) error {
+	code, err := {{.Spec}}.unmarshal(data)
+	*{{.Recv}} = {{.Type}}(code)
+	return err
+}
+{{end}}`))
+
+func main() {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, enums); err != nil {
+		log.Fatal(err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = ioutil.WriteFile("enum_methods.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,40 @@
+package goyht
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEnums(t *testing.T) {
+	if YHTPMFontKaiti.String() != "F1" || YHTPMFontKaiti.Label() != "楷体" || YHTSealClassQF.EnglishLabel() != "Paging seal" {
+		t.Fatalf("unexpected labels %s, %s", YHTPMFontKaiti.Label(), YHTSealClassQF.EnglishLabel())
+	}
+	if !YHTPersonCertTypePassport.Valid() || SealClass("F1").Valid() || SealClass("9").Label() != "未知(9)" {
+		t.Fatal("unexpected Valid or String")
+	}
+
+	req := YhtSigner{SignPositionType: YHTSignPositionTypeCoord, SignForm: YHTSignFormH5}
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"signerId":"","signPositionType":"2","positionContent":"","signValidateType":"","signMode":"","signForm":"1"}`; string(data) != want {
+		t.Fatalf("got %s", data)
+	}
+
+	var person YhtCreatePersonReq
+	err = json.Unmarshal([]byte(`{"identityRegion":4,"certifyType":"passport","phoneRegion":"台湾"}`), &person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if person.IdentityRegion != YHTIdentityRegionForeign || person.CertType != YHTPersonCertTypePassport || person.PhoneRegion != YHTPhoneRegionTaiwan {
+		t.Fatalf("unexpected person %+v", person)
+	}
+	var class SealClass
+	if err = json.Unmarshal([]byte(`"x"`), &class); err != nil || class.Valid() {
+		t.Fatalf("unknown values are kept for Valid to report, got %q, %v", string(class), err)
+	}
+	if err = json.Unmarshal([]byte(`{}`), &class); err == nil {
+		t.Fatal("an object is not a seal class")
+	}
+}
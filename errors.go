@@ -0,0 +1,185 @@
+package goyht
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// 云合同应答码
+const (
+	YHTCodeSuccess      = 200
+	YHTCodeInvalidParam = 400
+	YHTCodeTokenInvalid = 401
+	YHTCodeNotFound     = 404
+	YHTCodeServerError  = 500
+)
+
+// 错误类别，使用errors.Is判断*APIError属于哪一类
+var (
+	ErrNetwork       = errors.New("goyht: network failure")
+	ErrTokenInvalid  = errors.New("goyht: token expired or invalid")
+	ErrInvalidParam  = errors.New("goyht: invalid parameter")
+	ErrDuplicateUser = errors.New("goyht: user already exists")
+	ErrRejected      = errors.New("goyht: request rejected")
+	ErrServer        = errors.New("goyht: server error")
+)
+
+// userCreationURIs 创建用户的接口。平台没有为用户已存在定义专门的应答码，
+// 这些接口的错误应答消息包含duplicateUserMarkers时判断为ErrDuplicateUser
+var userCreationURIs = map[string]bool{
+	"/user/person":      true,
+	"/user/company":     true,
+	"/userInfo/addUser": true,
+}
+
+// duplicateUserMarkers 用户已存在时应答消息中包含的关键字
+var duplicateUserMarkers = []string{"已存在", "已注册"}
+
+// RequestIDHeader 请求ID头，客户端为每个请求生成，若网关返回则以网关为准
+const RequestIDHeader = "X-Request-Id"
+
+// APIError 云合同接口错误，包含网络错误和接口返回的错误应答
+type APIError struct {
+	Kind       error           // 错误类别，ErrNetwork等之一
+	StatusCode int             // HTTP状态码，网络错误时为0
+	Code       int             // 应答码
+	SubCode    int             // 子应答码，仅V3接口
+	Msg        string          // 应答消息
+	RawMsg     json.RawMessage // V4接口应答中原始的msg
+	URI        string          // 接口地址，不含令牌
+	RequestID  string          // 请求ID
+	Err        error           // 底层错误
+
+	unsent bool // 请求尚未发出
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("goyht: %s request %s: %v", e.URI, e.RequestID, e.Err)
+	}
+	return fmt.Sprintf("goyht: %s request %s status %d code %d subcode %d msg %s",
+		e.URI, e.RequestID, e.StatusCode, e.Code, e.SubCode, e.Msg)
+}
+
+// Is reports whether target is the kind of e.
+func (e *APIError) Is(target error) bool {
+	return e.Kind == target
+}
+
+// Unwrap returns the underlying error.
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// IsTokenExpired reports whether err is caused by an expired or invalid token.
+func IsTokenExpired(err error) bool {
+	return errors.Is(err, ErrTokenInvalid)
+}
+
+// IsDuplicateUser reports whether err is caused by creating an existing user.
+func IsDuplicateUser(err error) bool {
+	return errors.Is(err, ErrDuplicateUser)
+}
+
+// IsRetryable reports whether the request failed with err may succeed if sent again.
+func IsRetryable(err error) bool {
+	if isContextError(err) {
+		return false
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return apiErr.Kind == ErrNetwork || apiErr.Kind == ErrServer
+}
+
+// isContextError 判断err是否由context取消或超时引起
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// callInfo 一次请求的信息，用于构造*APIError
+type callInfo struct {
+	uri       string
+	status    int
+	requestID string
+	dupCodes  map[int]bool // 表示用户已存在的应答码，见WithDuplicateUserCodes
+}
+
+// networkError 将发送或接收过程中的错误包装为*APIError
+func (ci callInfo) networkError(err error) error {
+	return &APIError{
+		Kind:       ErrNetwork,
+		StatusCode: ci.status,
+		URI:        ci.uri,
+		RequestID:  ci.requestID,
+		Err:        err,
+	}
+}
+
+// apiError 根据HTTP状态码和应答码构造*APIError
+func (ci callInfo) apiError(code, subCode int, msg string, raw json.RawMessage) error {
+	return &APIError{
+		Kind:       ci.classify(code, subCode, msg),
+		StatusCode: ci.status,
+		Code:       code,
+		SubCode:    subCode,
+		Msg:        msg,
+		RawMsg:     raw,
+		URI:        ci.uri,
+		RequestID:  ci.requestID,
+	}
+}
+
+// classify 判断错误类别。用户已存在优先按应答码判断，
+// 仅创建用户的接口才根据应答消息判断
+func (ci callInfo) classify(code, subCode int, msg string) error {
+	switch {
+	case ci.status == http.StatusUnauthorized || code == YHTCodeTokenInvalid:
+		return ErrTokenInvalid
+	case ci.status >= http.StatusInternalServerError || code >= YHTCodeServerError && code < 600:
+		return ErrServer
+	case ci.dupCodes[code] || subCode != 0 && ci.dupCodes[subCode]:
+		return ErrDuplicateUser
+	}
+	if userCreationURIs[ci.uri] {
+		for _, marker := range duplicateUserMarkers {
+			if strings.Contains(msg, marker) {
+				return ErrDuplicateUser
+			}
+		}
+	}
+	if ci.status == http.StatusBadRequest || code == YHTCodeInvalidParam {
+		return ErrInvalidParam
+	}
+	return ErrRejected
+}
+
+// decodeMsg 将V4应答中的msg解码为字符串，msg不是JSON字符串时原样返回
+func decodeMsg(raw json.RawMessage) string {
+	var msg string
+	if err := json.Unmarshal(raw, &msg); err == nil {
+		return msg
+	}
+	return string(raw)
+}
+
+// newRequestID 生成随机的请求ID
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
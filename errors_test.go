@@ -0,0 +1,53 @@
+package goyht
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	code, msg := 400, "证件号已存在"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(RequestIDHeader, "req-1")
+		json.NewEncoder(w).Encode(M{"code": code, "msg": msg})
+	}))
+	defer srv.Close()
+
+	cli := NewClient(Config{}, WithAPIGateway(srv.URL), WithTokenRefresher(staticToken("tok")))
+	_, err := cli.CreatePersonV4(&YhtCreatePersonReq{Username: "Mike", CertNum: "E12345678"})
+	if !IsDuplicateUser(err) || IsRetryable(err) || IsTokenExpired(err) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expect *APIError, got %T", err)
+	}
+	if apiErr.Code != 400 || apiErr.Msg != "证件号已存在" || apiErr.URI != "/user/person" || apiErr.RequestID != "req-1" {
+		t.Fatalf("unexpected error fields %+v", apiErr)
+	}
+
+	// 其他接口的应答消息不作为用户已存在的依据
+	msg = "合同编号已存在"
+	_, err = cli.CreateContractFromTemplateV4(&YhtCreateTemplateContractReq{Title: "t", TemplateID: "1"})
+	if IsDuplicateUser(err) || !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("expect invalid param, got %v", err)
+	}
+
+	code, msg = 10021, "user exists"
+	if _, err = cli.CreatePersonV4(&YhtCreatePersonReq{Username: "Mike", CertNum: "E12345678"}); IsDuplicateUser(err) {
+		t.Fatalf("unexpected duplicate user %v", err)
+	}
+	cli = NewClient(Config{}, WithAPIGateway(srv.URL), WithDuplicateUserCodes(10021), WithTokenRefresher(staticToken("tok")))
+	if _, err = cli.CreatePersonV4(&YhtCreatePersonReq{Username: "Mike", CertNum: "E12345678"}); !IsDuplicateUser(err) {
+		t.Fatalf("expect duplicate user by code, got %v", err)
+	}
+
+	srv.Close()
+	_, err = cli.CreatePersonV4(&YhtCreatePersonReq{Username: "Mike", CertNum: "E12345678"})
+	if !errors.Is(err, ErrNetwork) || !IsRetryable(err) {
+		t.Fatalf("expect network error, got %v", err)
+	}
+}
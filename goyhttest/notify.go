@@ -0,0 +1,111 @@
+package goyhttest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iotdog/goyht"
+)
+
+// Notice 异步通知，以notice=<URL编码的JSON>的形式发送，附带timestamp和sign字段
+type Notice struct {
+	Content      string                 `json:"content"`
+	NoticeType   goyht.NoticeType       `json:"noticeType"`
+	NoticeParams string                 `json:"noticeParams"`
+	InfoMap      map[string]interface{} `json:"map"`
+
+	Response   string `json:"-"` // 应用的应答
+	StatusCode int    `json:"-"` // 应用应答的HTTP状态码
+	Err        error  `json:"-"` // 发送失败的原因
+}
+
+// contractNotice 构造合同相关的通知，signer为nil时表示合同本身的事件
+func (s *Server) contractNotice(noticeType goyht.NoticeType, c *Contract, signer *Signer) Notice {
+	info := map[string]interface{}{
+		"contractId": strconv.Itoa(c.ID),
+		"contractNo": c.ContractNo,
+		"status":     c.Status,
+		"noticeTime": time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if signer != nil {
+		info["signerId"] = strconv.Itoa(signer.SignerID)
+		info["appUserId"] = signer.AppUserID
+	}
+	params, _ := json.Marshal(info)
+	return Notice{
+		Content:      c.Title,
+		NoticeType:   noticeType,
+		NoticeParams: string(params),
+		InfoMap:      info,
+	}
+}
+
+// deliver 将通知发送至NotifyURL
+func (s *Server) deliver(notices []Notice) {
+	if s.NotifyURL == "" {
+		return
+	}
+	for _, n := range notices {
+		s.Notify(n)
+	}
+}
+
+// Notify sends n to NotifyURL signed with AppKey and records the delivery.
+func (s *Server) Notify(n Notice) Notice {
+	body, err := json.Marshal(n)
+	if err == nil {
+		var rsp *http.Response
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		form := url.Values{
+			"notice":                   {string(body)},
+			goyht.NoticeTimestampField: {timestamp},
+			goyht.NoticeSignField:      {goyht.SignNotice(s.AppKey, timestamp, body)},
+		}
+		rsp, err = http.Post(s.NotifyURL, "application/x-www-form-urlencoded;charset=utf-8",
+			strings.NewReader(form.Encode()))
+		if err == nil {
+			data, _ := ioutil.ReadAll(rsp.Body)
+			rsp.Body.Close()
+			n.StatusCode = rsp.StatusCode
+			n.Response = string(data)
+		}
+	}
+	n.Err = err
+
+	s.mu.Lock()
+	s.notices = append(s.notices, n)
+	s.mu.Unlock()
+	return n
+}
+
+// RejectSigner marks signerID as having rejected contract id and sends the notification.
+func (s *Server) RejectSigner(id, signerID int) bool {
+	s.mu.Lock()
+	c, ok := s.contracts[id]
+	if !ok {
+		s.mu.Unlock()
+		return false
+	}
+	var notices []Notice
+	for i := range c.Signers {
+		if c.Signers[i].SignerID == signerID && !c.Signers[i].Signed && !c.Signers[i].Rejected {
+			c.Signers[i].Rejected = true
+			notices = append(notices, s.contractNotice(goyht.NoticeSignerRejected, c, &c.Signers[i]))
+		}
+	}
+	s.mu.Unlock()
+	s.deliver(notices)
+	return len(notices) > 0
+}
+
+// Notices returns the notifications delivered so far.
+func (s *Server) Notices() []Notice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Notice(nil), s.notices...)
+}
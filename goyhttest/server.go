@@ -0,0 +1,438 @@
+// Package goyhttest provides an in-memory YunHeTong server for testing code
+// built on goyht without network access.
+//
+// The server implements the V3 form endpoints, the V4 JSON endpoints and the
+// real-name authentication endpoints used by goyht.Client, keeps all users,
+// seals and contracts in memory so tests can inspect them, supports fault
+// injection per endpoint and delivers async notifications to NotifyURL.
+package goyhttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iotdog/goyht"
+)
+
+// 默认的应用凭证
+const (
+	DefaultAppID    = "test-app-id"
+	DefaultAppKey   = "test-app-key"
+	DefaultPassword = "test-password"
+)
+
+// 合同状态
+const (
+	StatusDraft     = "0" // 草稿，尚未添加签署者
+	StatusSigning   = "1" // 签署中
+	StatusCompleted = "2" // 已完成
+	StatusInvalid   = "3" // 已作废
+)
+
+// User 用户
+type User struct {
+	SignerID  int
+	AppUserID string // V3接口的第三方用户ID
+	Name      string
+	Company   bool
+	CertType  string
+	CertNum   string
+	Phone     string
+}
+
+// Seal 印章
+type Seal struct {
+	MoulageID int
+	SignerID  int
+	Company   bool
+	Request   interface{} // *goyht.YhtCreatePersonMoulageReq 或 *goyht.YhtCreateCompanyMoulageReq
+}
+
+// Signer 合同签署者
+type Signer struct {
+	SignerID     int
+	AppUserID    string
+	PositionType string
+	Position     string // 关键字、占位符或坐标
+	Signed       bool
+	Rejected     bool
+	MoulageID    string
+	SealClass    string
+	SignedAt     time.Time
+}
+
+// Contract 合同
+type Contract struct {
+	ID         int
+	ContractNo string
+	Title      string
+	TemplateID string
+	Data       map[string]interface{}
+	File       []byte
+	Signers    []Signer
+	Status     string
+	Modified   time.Time
+}
+
+// Request 服务器收到的请求
+type Request struct {
+	Method string
+	Path   string
+	Token  string
+}
+
+// Fault 注入到某个接口的故障
+type Fault struct {
+	Status int           // 非0时返回该HTTP状态码
+	Code   int           // 非0时返回该应答码
+	Msg    string        // 应答消息
+	Delay  time.Duration // 处理前的延迟
+	Drop   bool          // 不应答直接断开连接
+	Times  int           // 生效次数，0表示一直生效
+}
+
+// Server 内存中的云合同服务
+type Server struct {
+	*httptest.Server
+
+	AppID     string // V4接口的应用ID
+	AppKey    string // V4接口的应用密钥
+	Password  string // V3接口的应用密码
+	NotifyURL string // 异步通知地址，为空时不发送通知
+
+	// EmptyNotFound 为true时查询不存在的合同详情返回成功应答和空数据，而不是404应答，
+	// 平台的部分环境以这种方式应答
+	EmptyNotFound bool
+
+	mu        sync.Mutex
+	nextID    int
+	tokens    map[string]int    // V4令牌 -> signerID，平台令牌为0
+	v3Tokens  map[string]string // V3令牌 -> appUserId
+	users     map[int]*User
+	seals     map[int]*Seal
+	contracts map[int]*Contract
+	faults    map[string]*Fault
+	requests  []Request
+	notices   []Notice
+}
+
+// NewServer starts and returns a new Server with the default credentials.
+// The caller should call Close when finished.
+func NewServer() *Server {
+	s := &Server{
+		AppID:     DefaultAppID,
+		AppKey:    DefaultAppKey,
+		Password:  DefaultPassword,
+		nextID:    1000,
+		tokens:    map[string]int{},
+		v3Tokens:  map[string]string{},
+		users:     map[int]*User{},
+		seals:     map[int]*Seal{},
+		contracts: map[int]*Contract{},
+		faults:    map[string]*Fault{},
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Config returns a goyht.Config pointing both gateways to s.
+func (s *Server) Config() goyht.Config {
+	return goyht.Config{
+		AppID:       s.AppID,
+		AppKey:      s.AppKey,
+		Password:    s.Password,
+		APIGateway:  s.URL,
+		AuthID:      s.AppID,
+		AuthPWD:     s.Password,
+		AuthGateway: s.URL,
+	}
+}
+
+// Client returns a goyht.Client talking to s.
+func (s *Server) Client(opts ...goyht.Option) *goyht.Client {
+	return goyht.NewClient(s.Config(), opts...)
+}
+
+// InjectFault makes requests to path fail as described by f.
+func (s *Server) InjectFault(path string, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = &f
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = map[string]*Fault{}
+}
+
+// ExpireTokens invalidates all issued tokens.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = map[string]int{}
+	s.v3Tokens = map[string]string{}
+}
+
+// Users returns all users sorted by signer ID.
+func (s *Server) Users() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, *u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].SignerID < users[j].SignerID })
+	return users
+}
+
+// Seals returns all seals sorted by moulage ID.
+func (s *Server) Seals() []Seal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sealList()
+}
+
+// sealList 返回按印章ID排序的印章，调用时须持有锁
+func (s *Server) sealList() []Seal {
+	seals := make([]Seal, 0, len(s.seals))
+	for _, m := range s.seals {
+		seals = append(seals, *m)
+	}
+	sort.Slice(seals, func(i, j int) bool { return seals[i].MoulageID < seals[j].MoulageID })
+	return seals
+}
+
+// Contract returns the contract with id.
+func (s *Server) Contract(id int) (Contract, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.contracts[id]
+	if !ok {
+		return Contract{}, false
+	}
+	return c.copy(), true
+}
+
+// Contracts returns all contracts sorted by ID.
+func (s *Server) Contracts() []Contract {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	contracts := make([]Contract, 0, len(s.contracts))
+	for _, c := range s.contracts {
+		contracts = append(contracts, c.copy())
+	}
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].ID < contracts[j].ID })
+	return contracts
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (c *Contract) copy() Contract {
+	cp := *c
+	cp.Signers = append([]Signer(nil), c.Signers...)
+	return cp
+}
+
+// updateStatus 根据签署情况更新合同状态
+func (c *Contract) updateStatus() {
+	if c.Status == StatusInvalid {
+		return
+	}
+	c.Status = StatusDraft
+	if len(c.Signers) == 0 {
+		return
+	}
+	c.Status = StatusCompleted
+	for _, signer := range c.Signers {
+		if !signer.Signed {
+			c.Status = StatusSigning
+		}
+	}
+}
+
+// status 返回签署者的签署状态
+func (s Signer) status() goyht.SignStatus {
+	switch {
+	case s.Signed:
+		return goyht.SignSigned
+	case s.Rejected:
+		return goyht.SignRejected
+	}
+	return goyht.SignPending
+}
+
+func (s *Server) newID() int {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *Server) newToken(prefix string) string {
+	return fmt.Sprintf("%s-%d-%d", prefix, s.newID(), time.Now().UnixNano())
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("token")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Token: token})
+	fault := s.takeFault(r.URL.Path)
+	s.mu.Unlock()
+
+	if fault != nil {
+		if s.applyFault(w, r, fault) {
+			return
+		}
+	}
+
+	// V4接口以请求头传递令牌，V3接口以查询参数传递令牌
+	v4 := r.Header.Get("token") != "" || strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+	switch r.URL.Path {
+	case "/auth/login":
+		s.login(w, r)
+	case "/user/person":
+		s.createPerson(w, r)
+	case "/user/company":
+		s.createCompany(w, r)
+	case "/user/signerId/certifyNums":
+		s.querySignerID(w, r)
+	case "/user/personMoulage":
+		s.createPersonMoulage(w, r)
+	case "/user/companyMoulage":
+		s.createCompanyMoulage(w, r)
+	case "/user/moulages":
+		s.queryMoulages(w, r)
+	case "/contract/signer":
+		s.addSigner(w, r)
+	case "/contract/sign":
+		s.signContractV4(w, r)
+	case "/contract/templateContract":
+		if v4 {
+			s.createTemplateContractV4(w, r)
+		} else {
+			s.createTemplateContract(w, r)
+		}
+	case "/authentic/personal/mobile/realName", "/authentic/personal/bankFour":
+		s.authRealNameV4(w, r)
+	case "/authentic/authentication":
+		s.authRealName(w, r)
+	case "/userInfo/addUser":
+		s.addUser(w, r)
+	case "/userInfo/modifyCellNum":
+		s.modifyPhoneNumber(w, r)
+	case "/userInfo/modifyUserName":
+		s.modifyUserName(w, r)
+	case "/token/getToken":
+		s.userToken(w, r)
+	case "/contract/fileContract":
+		if v4 {
+			s.createFileContractV4(w, r)
+		} else {
+			s.createFileContract(w, r)
+		}
+	case "/contract/addPartner":
+		s.addPartner(w, r)
+	case "/contract/signContract":
+		s.signContract(w, r)
+	case "/contract/invalid":
+		if v4 {
+			s.invalidateContractV4(w, r)
+		} else {
+			s.invalidateContract(w, r)
+		}
+	case "/contract/list":
+		if v4 {
+			s.listContractsV4(w, r)
+		} else {
+			s.listContracts(w, r)
+		}
+	case "/contract/detail":
+		if v4 {
+			s.lookupContractDetailV4(w, r)
+		} else {
+			s.lookupContractDetail(w, r)
+		}
+	case "/contract/download":
+		if v4 {
+			s.downloadContractV4(w, r)
+		} else {
+			s.downloadContract(w, r)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// takeFault 取出path上的故障，调用时须持有锁
+func (s *Server) takeFault(path string) *Fault {
+	f, ok := s.faults[path]
+	if !ok {
+		return nil
+	}
+	if f.Times > 0 {
+		f.Times--
+		if f.Times == 0 {
+			delete(s.faults, path)
+		}
+	}
+	cp := *f
+	return &cp
+}
+
+// applyFault 执行故障，返回true表示请求已处理完毕
+func (s *Server) applyFault(w http.ResponseWriter, r *http.Request, f *Fault) bool {
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return true
+		}
+	}
+	if f.Drop {
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return true
+			}
+		}
+		panic(http.ErrAbortHandler)
+	}
+	if f.Status != 0 {
+		w.WriteHeader(f.Status)
+		if f.Msg != "" {
+			w.Write([]byte(f.Msg))
+		}
+		return true
+	}
+	if f.Code != 0 {
+		writeJSON(w, map[string]interface{}{
+			"code":    f.Code,
+			"subCode": f.Code,
+			"msg":     f.Msg,
+			"message": f.Msg,
+			"success": false,
+		})
+		return true
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,437 @@
+package goyhttest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/iotdog/goyht"
+	"github.com/iotdog/goyht/goyhttest"
+)
+
+func TestContractLifecycleV4(t *testing.T) {
+	srv := goyhttest.NewServer()
+	defer srv.Close()
+
+	var notices []*goyht.AsyncNotifyResult
+	notify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := (&goyht.Client{}).AsyncNotify(r)
+		if err != nil {
+			t.Error(err)
+		}
+		notices = append(notices, n)
+		fmt.Fprint(w, (&goyht.Client{}).AnswerAsyncNotify(true, ""))
+	}))
+	defer notify.Close()
+	srv.NotifyURL = notify.URL
+
+	cli := srv.Client()
+	defer cli.Close()
+	if err := cli.Authenticate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	person, err := cli.CreatePersonV4(&goyht.YhtCreatePersonReq{
+		Username: "Mike",
+		CertType: goyht.YHTPersonCertTypeIDCard,
+		CertNum:  "520103198801011432",
+		Phone:    "15928009058",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cli.CreatePersonV4(&goyht.YhtCreatePersonReq{Username: "Mike", CertNum: "520103198801011432"})
+	if !goyht.IsDuplicateUser(err) {
+		t.Fatalf("expect duplicate user, got %v", err)
+	}
+	company, err := cli.CreateCompanyV4(&goyht.YhtCreateCompanyReq{
+		Username: "company",
+		CertType: goyht.YHTCompanyCertTypeUniformSocailCreditCode,
+		CertNum:  "915201903470159140",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	personID := strconv.Itoa(person.Data.SignerID)
+	companyID := strconv.Itoa(company.Data.SignerID)
+
+	seal, err := cli.CreateCompanyMoulageV4(&goyht.YhtCreateCompanyMoulageReq{SignerID: companyID, StyleType: goyht.YHTCMStyleTypeCircle})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contract, err := cli.CreateContractFromTemplateV4(&goyht.YhtCreateTemplateContractReq{
+		Title:        "Contract",
+		ContractNo:   "testContract123",
+		TemplateID:   "92130",
+		ContractData: map[string]string{"${lessee}": "Mike"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cli.AddSignerV4(&goyht.YhtAddSignerReq{
+		IDType:    goyht.YHTIDTypeCustom,
+		IDContent: "testContract123",
+		Signers: []goyht.YhtSigner{
+			{SignerID: companyID, SignPositionType: goyht.YHTSignPositionTypePlaceHolder, PositionContent: "56006"},
+			{SignerID: personID, SignPositionType: goyht.YHTSignPositionTypePlaceHolder, PositionContent: "02289"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contractID := strconv.Itoa(contract.Data.ContractID)
+	for _, signer := range []struct{ id, moulage string }{{companyID, strconv.Itoa(seal.Data.MoulageID)}, {personID, ""}} {
+		_, err = cli.SignContractV4(&goyht.YhtSignContractReq{
+			IDType:    goyht.YHTIDTypeSystem,
+			IDContent: contractID,
+			SignerID:  signer.id,
+			MoulageID: signer.moulage,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, ok := srv.Contract(contract.Data.ContractID)
+	if !ok || c.Status != goyhttest.StatusCompleted {
+		t.Fatalf("expect completed contract, got %+v", c)
+	}
+	if len(notices) != 3 || goyht.NoticeType(notices[2].NoticeType) != goyht.NoticeContractCompleted {
+		t.Fatalf("unexpected notices %+v", notices)
+	}
+	for _, n := range srv.Notices() {
+		if n.Response != `{"msg":"","response":true}` {
+			t.Fatalf("unexpected notify response %q", n.Response)
+		}
+	}
+}
+
+func TestContractLifecycleV3(t *testing.T) {
+	srv := goyhttest.NewServer()
+	defer srv.Close()
+	cli := srv.Client()
+
+	if _, err := cli.AddUser("user1", "15928009057", "company", "915201903470159140", goyht.UserTypeEnterprise, goyht.CertTypeLicence, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.AddUser("user2", "15928009058", "Mike", "520103198801011432", goyht.UserTypePersonal, goyht.CertTypeIDCard, false); err != nil {
+		t.Fatal(err)
+	}
+	tok, err := cli.UserToken("user2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := tok.Value.Token
+
+	tRsp, err := cli.CreateTemplateContract("Contract", "testContract123", "92130", token, false, goyht.M{"${lessee}": "Mike"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := tRsp.Value.ContractID
+	contractID := strconv.FormatInt(id, 10)
+	if _, err = cli.AddPartner(id, token, goyht.Partner{AppUserID: "user1", LocationName: "56006"}, goyht.Partner{AppUserID: "user2", Keyword: "乙方"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cli.SignContract(contractID, token, "user1", "user2"); err != nil {
+		t.Fatal(err)
+	}
+
+	detail, err := cli.LookupContractDetail(contractID, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Value.Status != goyhttest.StatusCompleted || len(detail.Value.PartnerList) != 2 {
+		t.Fatalf("unexpected detail %+v", detail.Value)
+	}
+	list, err := cli.ListContracts(1, 1, token)
+	if err != nil || len(list.Value.ContractList) != 1 {
+		t.Fatalf("unexpected list %+v %v", list, err)
+	}
+	// 旧接口原样上传文件，不检测文件类型
+	file, err := cli.CreateFileContract("File Contract", "file-001", token, false, []byte("plain text"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileID, _ := strconv.Atoi(file.Value.ContractID)
+	if c, ok := srv.Contract(fileID); !ok || string(c.File) != "plain text" || len(srv.Contracts()) != 2 {
+		t.Fatalf("file contract not created: %+v", file.Value)
+	}
+	pdf, err := cli.DownloadContract(contractID, token)
+	if err != nil || string(pdf[:4]) != "%PDF" {
+		t.Fatalf("unexpected download %q %v", pdf, err)
+	}
+	if _, err = cli.InvalidateContract(contractID, token); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	srv := goyhttest.NewServer()
+	defer srv.Close()
+	cli := srv.Client()
+	defer cli.Close()
+
+	srv.InjectFault("/user/signerId/certifyNums", goyhttest.Fault{Status: http.StatusServiceUnavailable, Times: 1})
+	if _, err := cli.QuerySignerID(&goyht.YhtQuerySignerIDReq{CertifyNumList: []string{"1"}}); err != nil {
+		t.Fatalf("expect retried request to succeed, got %v", err)
+	}
+
+	srv.ExpireTokens()
+	if _, err := cli.QuerySignerID(&goyht.YhtQuerySignerIDReq{CertifyNumList: []string{"1"}}); err != nil {
+		t.Fatalf("expect relogin after token expired, got %v", err)
+	}
+
+	srv.InjectFault("/user/person", goyhttest.Fault{Code: 500, Msg: "系统繁忙"})
+	_, err := cli.CreatePersonV4(&goyht.YhtCreatePersonReq{Username: "Mike", CertNum: "E12345678"})
+	if !goyht.IsRetryable(err) {
+		t.Fatalf("expect server error, got %v", err)
+	}
+}
+
+func TestContractManagementV4(t *testing.T) {
+	srv := goyhttest.NewServer()
+	defer srv.Close()
+	cli := srv.Client()
+	defer cli.Close()
+
+	created, err := cli.CreateFileContractV4(&goyht.YhtCreateFileContractReq{
+		Title:      "File Contract",
+		ContractNo: "file-001",
+		FileName:   "contract.pdf",
+		File:       []byte("%PDF-1.4 file contract"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	detail, err := cli.LookupContractDetailV4(&goyht.YhtContractDetailReq{IDType: goyht.YHTIDTypeCustom, IDContent: "file-001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.Data.ContractID != created.Data.ContractID || detail.Data.Status != goyhttest.StatusDraft {
+		t.Fatalf("unexpected detail %+v", detail.Data)
+	}
+
+	pdf, err := cli.DownloadContractV4(&goyht.YhtDownloadContractReq{IDType: goyht.YHTIDTypeSystem, IDContent: strconv.Itoa(created.Data.ContractID)})
+	if err != nil || string(pdf) != "%PDF-1.4 file contract" {
+		t.Fatalf("unexpected download %q %v", pdf, err)
+	}
+
+	if _, err = cli.InvalidateContractV4(&goyht.YhtInvalidateContractReq{IDType: goyht.YHTIDTypeCustom, IDContent: "file-001"}); err != nil {
+		t.Fatal(err)
+	}
+	list, err := cli.ListContractsV4(&goyht.YhtListContractsReq{PageNum: 1, PageSize: 10, Status: goyhttest.StatusInvalid})
+	if err != nil || list.Data.Total != 1 {
+		t.Fatalf("unexpected list %+v %v", list, err)
+	}
+
+	_, err = cli.DownloadContractV4(&goyht.YhtDownloadContractReq{IDType: goyht.YHTIDTypeSystem, IDContent: "1"})
+	var apiErr *goyht.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 404 {
+		t.Fatalf("expect not found error, got %v", err)
+	}
+}
+
+func TestWorkflowResume(t *testing.T) {
+	srv := goyhttest.NewServer()
+	defer srv.Close()
+	cli := srv.Client()
+	defer cli.Close()
+
+	// 个人用户已存在，工作流应查询其用户ID
+	spec := workflowSpec("wf-001")
+	if _, err := cli.CreatePersonV4(spec.Parties[1].Person); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := goyht.NewFileWorkflowStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.InjectFault("/contract/sign", goyhttest.Fault{Code: 500, Msg: "系统繁忙", Times: 1})
+	_, err = goyht.NewWorkflow(cli, store).Run(context.Background(), spec)
+	var wfErr *goyht.WorkflowError
+	if !errors.As(err, &wfErr) || wfErr.Step != goyht.StepSign || wfErr.Party != "company" {
+		t.Fatalf("expect sign failure, got %v", err)
+	}
+
+	state, err := goyht.NewWorkflow(cli, store).Run(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := strconv.Atoi(state.ContractID)
+	c, ok := srv.Contract(id)
+	if !state.Done || !ok || c.Status != goyhttest.StatusCompleted || c.ContractNo != "wf-001" {
+		t.Fatalf("unexpected contract %+v state %+v", c, state)
+	}
+	if len(srv.Users()) != 2 || len(srv.Seals()) != 1 || len(srv.Contracts()) != 1 {
+		t.Fatalf("expect no duplicated resources, got %d users %d seals %d contracts",
+			len(srv.Users()), len(srv.Seals()), len(srv.Contracts()))
+	}
+}
+
+// workflowSpec 返回企业盖章、个人签字的工作流
+func workflowSpec(id string) *goyht.WorkflowSpec {
+	return &goyht.WorkflowSpec{
+		ID:       id,
+		Contract: goyht.YhtCreateTemplateContractReq{Title: "Contract", TemplateID: "92130"},
+		Parties: []goyht.Party{
+			{
+				Key:         "company",
+				Company:     &goyht.YhtCreateCompanyReq{Username: "company", CertType: goyht.YHTCompanyCertTypeUniformSocailCreditCode, CertNum: "915201903470159140"},
+				CompanySeal: &goyht.YhtCreateCompanyMoulageReq{StyleType: goyht.YHTCMStyleTypeCircle},
+				Signer:      goyht.YhtSigner{SignPositionType: goyht.YHTSignPositionTypePlaceHolder, PositionContent: "56006"},
+				AutoSign:    true,
+			},
+			{
+				Key:      "person",
+				Person:   &goyht.YhtCreatePersonReq{Username: "Mike", CertType: goyht.YHTPersonCertTypeIDCard, CertNum: "520103198801011432"},
+				Signer:   goyht.YhtSigner{SignPositionType: goyht.YHTSignPositionTypePlaceHolder, PositionContent: "02289"},
+				AutoSign: true,
+			},
+		},
+	}
+}
+
+// lossyStore 创建印章后第一次保存进度失败，模拟保存印章ID前崩溃
+type lossyStore struct {
+	*goyht.MemoryWorkflowStore
+	lost bool
+}
+
+func (s *lossyStore) Save(ctx context.Context, state *goyht.WorkflowState) error {
+	if len(state.MoulageIDs) > 0 && !s.lost {
+		s.lost = true
+		return errors.New("disk full")
+	}
+	return s.MemoryWorkflowStore.Save(ctx, state)
+}
+
+func TestWorkflowIdempotent(t *testing.T) {
+	for _, emptyNotFound := range []bool{false, true} {
+		srv := goyhttest.NewServer()
+		srv.EmptyNotFound = emptyNotFound
+		cli := srv.Client()
+
+		store := &lossyStore{MemoryWorkflowStore: goyht.NewMemoryWorkflowStore()}
+		if _, err := goyht.NewWorkflow(cli, store).Run(context.Background(), workflowSpec("wf-002")); err == nil || !store.lost {
+			t.Fatalf("expect save failure, got %v", err)
+		}
+
+		// 同一工作流并发运行
+		wf := goyht.NewWorkflow(cli, store)
+		errs := make(chan error, 3)
+		for i := 0; i < cap(errs); i++ {
+			go func() {
+				_, err := wf.Run(context.Background(), workflowSpec("wf-002"))
+				errs <- err
+			}()
+		}
+		for i := 0; i < cap(errs); i++ {
+			if err := <-errs; err != nil {
+				t.Fatalf("emptyNotFound %v: %v", emptyNotFound, err)
+			}
+		}
+		if len(srv.Users()) != 2 || len(srv.Seals()) != 1 || len(srv.Contracts()) != 1 {
+			t.Errorf("emptyNotFound %v: expect no duplicated resources, got %d users %d seals %d contracts",
+				emptyNotFound, len(srv.Users()), len(srv.Seals()), len(srv.Contracts()))
+		}
+		cli.Close()
+		srv.Close()
+	}
+}
+
+func TestWorkflowExistingSeal(t *testing.T) {
+	srv := goyhttest.NewServer()
+	defer srv.Close()
+	cli := srv.Client()
+	defer cli.Close()
+
+	// 企业用户在工作流之前已注册并有印章
+	spec := workflowSpec("wf-003")
+	company, err := cli.CreateCompanyV4(spec.Parties[0].Company)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := cli.CreateCompanyMoulageV4(&goyht.YhtCreateCompanyMoulageReq{SignerID: strconv.Itoa(company.Data.SignerID), StyleType: goyht.YHTCMStyleTypeCircle})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 记录开始创建印章后请求失败，印章未创建
+	store := goyht.NewMemoryWorkflowStore()
+	srv.InjectFault("/user/companyMoulage", goyhttest.Fault{Code: 400, Msg: "参数错误", Times: 1})
+	if _, err = goyht.NewWorkflow(cli, store).Run(context.Background(), spec); err == nil {
+		t.Fatal("expect seal creation failure")
+	}
+	state, err := goyht.NewWorkflow(cli, store).Run(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.MoulageIDs["company"] == strconv.Itoa(old.Data.MoulageID) || len(srv.Seals()) != 2 {
+		t.Fatalf("expect a new seal, got %s among %d seals", state.MoulageIDs["company"], len(srv.Seals()))
+	}
+}
+
+func TestWaitForStatus(t *testing.T) {
+	srv := goyhttest.NewServer()
+	defer srv.Close()
+	h := goyht.NewNotificationHandler()
+	notify := httptest.NewServer(h)
+	defer notify.Close()
+	srv.NotifyURL = notify.URL
+	cli := srv.Client()
+	defer cli.Close()
+
+	user, err := cli.CreatePersonV4(&goyht.YhtCreatePersonReq{Username: "Mike", CertNum: "520103198801011432"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signerID := strconv.Itoa(user.Data.SignerID)
+	contract, err := cli.CreateContractFromTemplateV4(&goyht.YhtCreateTemplateContractReq{Title: "Contract", TemplateID: "92130"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	contractID := strconv.Itoa(contract.Data.ContractID)
+	if _, err = cli.AddSignerV4(&goyht.YhtAddSignerReq{IDType: goyht.YHTIDTypeSystem, IDContent: contractID, Signers: []goyht.YhtSigner{{SignerID: signerID}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 轮询间隔远大于测试超时，只有收到通知才能及时返回
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		detail, err := cli.WaitForStatus(ctx, contractID, goyht.ContractCompleted,
+			goyht.WithPollInterval(time.Minute, time.Minute), goyht.WithNotifications(h))
+		if err == nil && detail.Signers[0].SignStatus != goyht.SignSigned {
+			err = fmt.Errorf("unexpected signer %+v", detail.Signers[0])
+		}
+		done <- err
+	}()
+	// 等待第一次轮询完成后再签署
+	for len(srv.Requests()) < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err = cli.SignContractV4(&goyht.YhtSignContractReq{IDType: goyht.YHTIDTypeSystem, IDContent: contractID, SignerID: signerID}); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = cli.InvalidateContractV4(&goyht.YhtInvalidateContractReq{IDType: goyht.YHTIDTypeSystem, IDContent: contractID}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = cli.WaitForStatus(ctx, contractID, goyht.ContractCompleted)
+	if !errors.Is(err, goyht.ErrInvalidTransition) {
+		t.Fatalf("expect unreachable status, got %v", err)
+	}
+}
@@ -0,0 +1,390 @@
+package goyhttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iotdog/goyht"
+)
+
+// V3接口应答码
+const (
+	subCodeInvalidParam = 500
+	subCodeTokenInvalid = 401
+	subCodeNotFound     = 404
+)
+
+// pdfContent 下载合同时返回的文件内容
+var pdfContent = []byte("%PDF-1.4\n% goyhttest contract\n%%EOF\n")
+
+func writeV3(w http.ResponseWriter, subCode int, msg string, value interface{}) {
+	body := map[string]interface{}{
+		"code":    codeSuccess,
+		"subCode": subCode,
+		"message": msg,
+	}
+	if value != nil {
+		body["value"] = value
+	}
+	writeJSON(w, body)
+}
+
+// checkApp 校验V3应用凭证
+func (s *Server) checkApp(w http.ResponseWriter, r *http.Request) bool {
+	if r.FormValue("appId") != s.AppID || r.FormValue("password") != s.Password {
+		writeV3(w, subCodeTokenInvalid, "应用ID或密码错误", nil)
+		return false
+	}
+	return true
+}
+
+// v3User 校验V3令牌并返回令牌所属用户，失败时写入应答
+func (s *Server) v3User(w http.ResponseWriter, r *http.Request) (*User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	appUserID, ok := s.v3Tokens[r.URL.Query().Get("token")]
+	if !ok {
+		writeV3(w, subCodeTokenInvalid, "token失效", nil)
+		return nil, false
+	}
+	for _, u := range s.users {
+		if u.AppUserID == appUserID {
+			return u, true
+		}
+	}
+	writeV3(w, subCodeNotFound, "用户不存在", nil)
+	return nil, false
+}
+
+func (s *Server) addUser(w http.ResponseWriter, r *http.Request) {
+	if !s.checkApp(w, r) {
+		return
+	}
+	appUserID := r.FormValue("appUserId")
+	if appUserID == "" || r.FormValue("certifyNumber") == "" {
+		writeV3(w, subCodeInvalidParam, "用户ID和证件号不能为空", nil)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.AppUserID == appUserID || u.CertNum == r.FormValue("certifyNumber") {
+			writeV3(w, subCodeInvalidParam, "用户已存在", nil)
+			return
+		}
+	}
+	u := &User{
+		SignerID:  s.newID(),
+		AppUserID: appUserID,
+		Name:      r.FormValue("userName"),
+		Company:   r.FormValue("userType") == goyht.UserTypeEnterprise,
+		CertType:  r.FormValue("certifyType"),
+		CertNum:   r.FormValue("certifyNumber"),
+		Phone:     r.FormValue("cellNum"),
+	}
+	s.users[u.SignerID] = u
+	writeV3(w, codeSuccess, "添加成功", nil)
+}
+
+func (s *Server) userToken(w http.ResponseWriter, r *http.Request) {
+	if !s.checkApp(w, r) {
+		return
+	}
+	appUserID := r.FormValue("appUserId")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.AppUserID == appUserID {
+			token := s.newToken("v3")
+			s.v3Tokens[token] = appUserID
+			writeV3(w, codeSuccess, "获取成功", map[string]string{"token": token})
+			return
+		}
+	}
+	writeV3(w, subCodeNotFound, "用户不存在", nil)
+}
+
+func (s *Server) modifyPhoneNumber(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.v3User(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	u.Phone = r.FormValue("cellNum")
+	s.mu.Unlock()
+	writeV3(w, codeSuccess, "修改成功", nil)
+}
+
+func (s *Server) modifyUserName(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.v3User(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	u.Name = r.FormValue("userName")
+	s.mu.Unlock()
+	writeV3(w, codeSuccess, "修改成功", nil)
+}
+
+// addContract 保存合同，调用时须持有锁
+func (s *Server) addContract(c *Contract) {
+	c.ID = s.newID()
+	c.Status = StatusDraft
+	c.Modified = time.Now()
+	s.contracts[c.ID] = c
+}
+
+func (s *Server) createTemplateContract(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.v3User(w, r); !ok {
+		return
+	}
+	data := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(r.FormValue("param")), &data); err != nil {
+		writeV3(w, subCodeInvalidParam, "参数错误", nil)
+		return
+	}
+	c := &Contract{
+		ContractNo: r.FormValue("defContractNo"),
+		Title:      r.FormValue("title"),
+		TemplateID: r.FormValue("templateId"),
+		Data:       data,
+	}
+	s.mu.Lock()
+	s.addContract(c)
+	s.mu.Unlock()
+	writeV3(w, codeSuccess, "创建成功", map[string]int64{"contractId": int64(c.ID)})
+}
+
+func (s *Server) createFileContract(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.v3User(w, r); !ok {
+		return
+	}
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeV3(w, subCodeInvalidParam, "文件格式错误", nil)
+		return
+	}
+	var file []byte
+	if f, _, err := r.FormFile("file"); err == nil {
+		file, _ = ioutil.ReadAll(f)
+		f.Close()
+	}
+	if len(file) == 0 {
+		writeV3(w, subCodeInvalidParam, "文件不能为空", nil)
+		return
+	}
+	c := &Contract{
+		ContractNo: r.FormValue("defContractNo"),
+		Title:      r.FormValue("title"),
+		File:       file,
+	}
+	s.mu.Lock()
+	s.addContract(c)
+	s.mu.Unlock()
+	writeV3(w, codeSuccess, "创建成功", map[string]string{"contractId": strconv.Itoa(c.ID)})
+}
+
+// v3Contract 校验令牌并查找合同，失败时写入应答
+func (s *Server) v3Contract(w http.ResponseWriter, r *http.Request) (*Contract, bool) {
+	if _, ok := s.v3User(w, r); !ok {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.findContract(goyht.YHTIDTypeSystem, r.FormValue("contractId"))
+	if c == nil {
+		writeV3(w, subCodeNotFound, "合同不存在", nil)
+		return nil, false
+	}
+	return c, true
+}
+
+func (s *Server) addPartner(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.v3Contract(w, r)
+	if !ok {
+		return
+	}
+	partners := []goyht.Partner{}
+	if err := json.Unmarshal([]byte(r.FormValue("partners")), &partners); err != nil || len(partners) == 0 {
+		writeV3(w, subCodeInvalidParam, "签署者格式错误", nil)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.Status == StatusInvalid || c.Status == StatusCompleted {
+		writeV3(w, subCodeInvalidParam, "合同状态不允许添加签署者", nil)
+		return
+	}
+	for _, p := range partners {
+		signer := Signer{AppUserID: p.AppUserID, PositionType: string(goyht.YHTSignPositionTypePlaceHolder), Position: p.LocationName}
+		if p.Keyword != "" {
+			signer.PositionType, signer.Position = string(goyht.YHTSignPositionTypeKeyWord), p.Keyword
+		}
+		for _, u := range s.users {
+			if u.AppUserID == p.AppUserID {
+				signer.SignerID = u.SignerID
+			}
+		}
+		c.Signers = append(c.Signers, signer)
+	}
+	c.updateStatus()
+	c.Modified = time.Now()
+	writeV3(w, codeSuccess, "添加成功", nil)
+}
+
+func (s *Server) signContract(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.v3Contract(w, r)
+	if !ok {
+		return
+	}
+	signers := []string{}
+	if err := json.Unmarshal([]byte(r.FormValue("signer")), &signers); err != nil {
+		writeV3(w, subCodeInvalidParam, "签署者格式错误", nil)
+		return
+	}
+	s.mu.Lock()
+	if c.Status != StatusSigning {
+		s.mu.Unlock()
+		writeV3(w, subCodeInvalidParam, "合同状态不允许签署", nil)
+		return
+	}
+	var notices []Notice
+	for _, appUserID := range signers {
+		for i, signer := range c.Signers {
+			if signer.AppUserID == appUserID && !signer.Signed && !signer.Rejected {
+				notices = append(notices, s.sign(c, i, "", "")...)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	writeV3(w, codeSuccess, "签署成功", nil)
+	s.deliver(notices)
+}
+
+func (s *Server) invalidateContract(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.v3Contract(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	notices := s.invalidate(c)
+	s.mu.Unlock()
+	if notices == nil {
+		writeV3(w, subCodeInvalidParam, "合同已作废", nil)
+		return
+	}
+	writeV3(w, codeSuccess, "作废成功", nil)
+	s.deliver(notices)
+}
+
+// invalidate 作废合同，合同已作废时返回nil，调用时须持有锁
+func (s *Server) invalidate(c *Contract) []Notice {
+	if c.Status == StatusInvalid {
+		return nil
+	}
+	c.Status = StatusInvalid
+	c.Modified = time.Now()
+	return []Notice{s.contractNotice(goyht.NoticeContractInvalidated, c, nil)}
+}
+
+func (s *Server) listContracts(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.v3User(w, r); !ok {
+		return
+	}
+	pageNum, _ := strconv.Atoi(r.FormValue("pageNum"))
+	pageSize, _ := strconv.Atoi(r.FormValue("pageSize"))
+	all := s.Contracts()
+
+	list := []map[string]string{}
+	for _, c := range paginate(all, pageNum, pageSize) {
+		partners := make([]string, 0, len(c.Signers))
+		for _, signer := range c.Signers {
+			partners = append(partners, signer.AppUserID)
+		}
+		list = append(list, map[string]string{
+			"id":          strconv.Itoa(c.ID),
+			"title":       c.Title,
+			"status":      c.Status,
+			"appName":     "goyhttest",
+			"gmtModify":   c.Modified.Format("2006-01-02 15:04:05"),
+			"partnerList": strings.Join(partners, ","),
+		})
+	}
+	writeV3(w, codeSuccess, "查询成功", map[string]interface{}{"contractList": list})
+}
+
+// paginate 返回第pageNum页（从1开始）的合同
+func paginate(all []Contract, pageNum, pageSize int) []Contract {
+	if pageNum < 1 {
+		pageNum = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	start := (pageNum - 1) * pageSize
+	if start >= len(all) {
+		return nil
+	}
+	end := start + pageSize
+	if end > len(all) {
+		end = len(all)
+	}
+	return all[start:end]
+}
+
+func (s *Server) lookupContractDetail(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.v3Contract(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	partners := []map[string]string{}
+	for _, signer := range c.Signers {
+		partners = append(partners, map[string]string{
+			"signStatus": string(signer.status()),
+			"userId":     signer.AppUserID,
+		})
+	}
+	writeV3(w, codeSuccess, "查询成功", map[string]interface{}{
+		"partnerList": partners,
+		"title":       c.Title,
+		"status":      c.Status,
+	})
+}
+
+func (s *Server) downloadContract(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.v3Contract(w, r)
+	if !ok {
+		return
+	}
+	s.writePDF(w, c)
+}
+
+// writePDF 返回合同文件
+func (s *Server) writePDF(w http.ResponseWriter, c *Contract) {
+	s.mu.Lock()
+	file := c.File
+	s.mu.Unlock()
+	if len(file) == 0 {
+		file = pdfContent
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Length", strconv.Itoa(len(file)))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%d.pdf", c.ID))
+	w.Write(file)
+}
+
+func (s *Server) authRealName(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("key") != s.AppID || r.FormValue("value") != s.Password {
+		writeJSON(w, map[string]interface{}{"code": codeTokenInvalid, "msg": "认证密钥错误", "success": false})
+		return
+	}
+	data, _ := json.Marshal(map[string]string{"message": "认证通过", "status": "1"})
+	writeJSON(w, map[string]interface{}{"code": codeSuccess, "msg": "", "success": true, "data": string(data)})
+}
@@ -0,0 +1,489 @@
+package goyhttest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iotdog/goyht"
+)
+
+// V4接口应答码
+const (
+	codeSuccess      = 200
+	codeInvalidParam = 400
+	codeTokenInvalid = 401
+	codeNotFound     = 404
+)
+
+func writeV4(w http.ResponseWriter, code int, msg string, data interface{}) {
+	body := map[string]interface{}{
+		"code": code,
+		"msg":  msg,
+	}
+	if data != nil {
+		body["data"] = data
+	}
+	writeJSON(w, body)
+}
+
+// decodeV4 解析JSON请求并校验平台令牌，失败时写入应答并返回false
+func (s *Server) decodeV4(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if !s.checkToken(w, r) {
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeV4(w, codeInvalidParam, "参数错误："+err.Error(), nil)
+		return false
+	}
+	return true
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		AppID    string `json:"appId"`
+		AppKey   string `json:"appKey"`
+		SignerID string `json:"signerId"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeV4(w, codeInvalidParam, "参数错误", nil)
+		return
+	}
+	if req.AppID != s.AppID || req.AppKey != s.AppKey {
+		writeV4(w, codeTokenInvalid, "应用ID或密钥错误", nil)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	signerID := 0
+	if req.SignerID != "" {
+		signerID, _ = strconv.Atoi(req.SignerID)
+		if _, ok := s.users[signerID]; !ok {
+			writeV4(w, codeNotFound, "用户不存在", nil)
+			return
+		}
+	}
+	token := s.newToken("ltt")
+	s.tokens[token] = signerID
+	w.Header().Set("token", token)
+	writeV4(w, codeSuccess, "登录成功", nil)
+}
+
+// createUser 创建用户，证件号重复时返回错误，调用时须持有锁
+func (s *Server) createUser(w http.ResponseWriter, u *User) {
+	for _, exist := range s.users {
+		if exist.CertNum == u.CertNum {
+			writeV4(w, codeInvalidParam, "用户已存在", nil)
+			return
+		}
+	}
+	u.SignerID = s.newID()
+	s.users[u.SignerID] = u
+	writeV4(w, codeSuccess, "创建成功", goyht.SignerIDResp{SignerID: u.SignerID})
+}
+
+func (s *Server) createPerson(w http.ResponseWriter, r *http.Request) {
+	req := goyht.YhtCreatePersonReq{}
+	if !s.decodeV4(w, r, &req) {
+		return
+	}
+	if req.Username == "" || req.CertNum == "" {
+		writeV4(w, codeInvalidParam, "用户名和证件号不能为空", nil)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.createUser(w, &User{
+		Name:     req.Username,
+		CertType: string(req.CertType),
+		CertNum:  req.CertNum,
+		Phone:    req.Phone,
+	})
+}
+
+func (s *Server) createCompany(w http.ResponseWriter, r *http.Request) {
+	req := goyht.YhtCreateCompanyReq{}
+	if !s.decodeV4(w, r, &req) {
+		return
+	}
+	if req.Username == "" || req.CertNum == "" {
+		writeV4(w, codeInvalidParam, "企业名称和证件号不能为空", nil)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.createUser(w, &User{
+		Name:     req.Username,
+		Company:  true,
+		CertType: string(req.CertType),
+		CertNum:  req.CertNum,
+		Phone:    req.Phone,
+	})
+}
+
+func (s *Server) querySignerID(w http.ResponseWriter, r *http.Request) {
+	req := goyht.YhtQuerySignerIDReq{}
+	if !s.decodeV4(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := []map[string]int{}
+	for _, certNum := range req.CertifyNumList {
+		for _, u := range s.users {
+			if u.CertNum == certNum {
+				data = append(data, map[string]int{certNum: u.SignerID})
+			}
+		}
+	}
+	writeV4(w, codeSuccess, "查询成功", data)
+}
+
+// createSeal 创建印章，调用时须持有锁
+func (s *Server) createSeal(w http.ResponseWriter, signerID string, company bool, req interface{}) {
+	id, _ := strconv.Atoi(signerID)
+	u, ok := s.users[id]
+	if !ok {
+		writeV4(w, codeNotFound, "用户不存在", nil)
+		return
+	}
+	if u.Company != company {
+		writeV4(w, codeInvalidParam, "用户类型与印章类型不符", nil)
+		return
+	}
+	seal := &Seal{MoulageID: s.newID(), SignerID: id, Company: company, Request: req}
+	s.seals[seal.MoulageID] = seal
+	writeV4(w, codeSuccess, "创建成功", goyht.MoulageIDResp{MoulageID: seal.MoulageID})
+}
+
+func (s *Server) createPersonMoulage(w http.ResponseWriter, r *http.Request) {
+	req := &goyht.YhtCreatePersonMoulageReq{}
+	if !s.decodeV4(w, r, req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.createSeal(w, req.SignerID, false, req)
+}
+
+func (s *Server) createCompanyMoulage(w http.ResponseWriter, r *http.Request) {
+	req := &goyht.YhtCreateCompanyMoulageReq{}
+	if !s.decodeV4(w, r, req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.createSeal(w, req.SignerID, true, req)
+}
+
+func (s *Server) queryMoulages(w http.ResponseWriter, r *http.Request) {
+	req := goyht.YhtQueryMoulagesReq{}
+	if !s.decodeV4(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, _ := strconv.Atoi(req.SignerID)
+	if _, ok := s.users[id]; !ok {
+		writeV4(w, codeNotFound, "用户不存在", nil)
+		return
+	}
+	data := []goyht.MoulageIDResp{}
+	for _, seal := range s.sealList() {
+		if seal.SignerID == id {
+			data = append(data, goyht.MoulageIDResp{MoulageID: seal.MoulageID})
+		}
+	}
+	writeV4(w, codeSuccess, "查询成功", data)
+}
+
+func (s *Server) createTemplateContractV4(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Title        string                 `json:"contractTitle"`
+		ContractNo   string                 `json:"contractNo"`
+		TemplateID   string                 `json:"templateId"`
+		ContractData map[string]interface{} `json:"contractData"`
+	}{}
+	if !s.decodeV4(w, r, &req) {
+		return
+	}
+	if req.TemplateID == "" {
+		writeV4(w, codeInvalidParam, "模板ID不能为空", nil)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.ContractNo != "" && s.findContract(goyht.YHTIDTypeCustom, req.ContractNo) != nil {
+		writeV4(w, codeInvalidParam, "合同编号已存在", nil)
+		return
+	}
+	c := &Contract{
+		ID:         s.newID(),
+		ContractNo: req.ContractNo,
+		Title:      req.Title,
+		TemplateID: req.TemplateID,
+		Data:       req.ContractData,
+		Status:     StatusDraft,
+		Modified:   time.Now(),
+	}
+	s.contracts[c.ID] = c
+	writeV4(w, codeSuccess, "创建成功", goyht.ContractIDResp{ContractID: c.ID})
+}
+
+// findContract 按合同ID类型查找合同，调用时须持有锁
+func (s *Server) findContract(idType, idContent string) *Contract {
+	if idType == goyht.YHTIDTypeCustom {
+		for _, c := range s.contracts {
+			if c.ContractNo == idContent {
+				return c
+			}
+		}
+		return nil
+	}
+	id, _ := strconv.Atoi(idContent)
+	return s.contracts[id]
+}
+
+func (s *Server) addSigner(w http.ResponseWriter, r *http.Request) {
+	req := goyht.YhtAddSignerReq{}
+	if !s.decodeV4(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.findContract(req.IDType, req.IDContent)
+	if c == nil {
+		writeV4(w, codeNotFound, "合同不存在", nil)
+		return
+	}
+	if c.Status == StatusInvalid || c.Status == StatusCompleted {
+		writeV4(w, codeInvalidParam, "合同状态不允许添加签署者", nil)
+		return
+	}
+	for _, signer := range req.Signers {
+		id, _ := strconv.Atoi(signer.SignerID)
+		if _, ok := s.users[id]; !ok {
+			writeV4(w, codeNotFound, "用户不存在", nil)
+			return
+		}
+	}
+	for _, signer := range req.Signers {
+		id, _ := strconv.Atoi(signer.SignerID)
+		c.Signers = append(c.Signers, Signer{
+			SignerID:     id,
+			PositionType: string(signer.SignPositionType),
+			Position:     signer.PositionContent,
+		})
+	}
+	c.updateStatus()
+	c.Modified = time.Now()
+	writeV4(w, codeSuccess, "添加成功", nil)
+}
+
+func (s *Server) signContractV4(w http.ResponseWriter, r *http.Request) {
+	req := goyht.YhtSignContractReq{}
+	if !s.decodeV4(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	c := s.findContract(req.IDType, req.IDContent)
+	if c == nil {
+		s.mu.Unlock()
+		writeV4(w, codeNotFound, "合同不存在", nil)
+		return
+	}
+	id, _ := strconv.Atoi(req.SignerID)
+	idx := -1
+	for i, signer := range c.Signers {
+		if signer.SignerID == id && !signer.Signed && !signer.Rejected {
+			idx = i
+		}
+	}
+	if c.Status != StatusSigning || idx < 0 {
+		s.mu.Unlock()
+		writeV4(w, codeInvalidParam, "签署者不存在或已签署", nil)
+		return
+	}
+	if req.MoulageID != "" {
+		mid, _ := strconv.Atoi(req.MoulageID)
+		if seal, ok := s.seals[mid]; !ok || seal.SignerID != id {
+			s.mu.Unlock()
+			writeV4(w, codeInvalidParam, "印章不存在", nil)
+			return
+		}
+	}
+	notices := s.sign(c, idx, req.MoulageID, string(req.SealClass))
+	s.mu.Unlock()
+
+	writeV4(w, codeSuccess, "签署成功", nil)
+	s.deliver(notices)
+}
+
+// sign 签署合同，返回待发送的通知，调用时须持有锁
+func (s *Server) sign(c *Contract, idx int, moulageID, sealClass string) []Notice {
+	signer := &c.Signers[idx]
+	signer.Signed = true
+	signer.MoulageID = moulageID
+	signer.SealClass = sealClass
+	signer.SignedAt = time.Now()
+	c.updateStatus()
+	c.Modified = signer.SignedAt
+
+	notices := []Notice{s.contractNotice(goyht.NoticeContractSigned, c, signer)}
+	if c.Status == StatusCompleted {
+		notices = append(notices, s.contractNotice(goyht.NoticeContractCompleted, c, nil))
+	}
+	return notices
+}
+
+func (s *Server) authRealNameV4(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("appId") != s.AppID || r.FormValue("appKey") != s.AppKey {
+		writeV4(w, codeTokenInvalid, "应用ID或密钥错误", nil)
+		return
+	}
+	if r.FormValue("idNo") == "" || r.FormValue("idName") == "" || r.FormValue("mobile") == "" {
+		writeV4(w, codeInvalidParam, "认证信息不完整", nil)
+		return
+	}
+	s.mu.Lock()
+	id := s.newID()
+	s.mu.Unlock()
+	writeV4(w, codeSuccess, "认证成功", goyht.AuthSerialNumResp{ID: strconv.Itoa(id)})
+}
+
+// checkToken 校验平台令牌，失败时写入应答并返回false
+func (s *Server) checkToken(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	_, ok := s.tokens[r.Header.Get("token")]
+	s.mu.Unlock()
+	if !ok {
+		writeV4(w, codeTokenInvalid, "token失效", nil)
+	}
+	return ok
+}
+
+// contractV4 解析合同ID请求并查找合同，失败时写入应答
+func (s *Server) contractV4(w http.ResponseWriter, r *http.Request, req interface{}, idType, idContent *string) (*Contract, bool) {
+	if !s.decodeV4(w, r, req) {
+		return nil, false
+	}
+	s.mu.Lock()
+	c := s.findContract(*idType, *idContent)
+	s.mu.Unlock()
+	if c == nil {
+		writeV4(w, codeNotFound, "合同不存在", nil)
+		return nil, false
+	}
+	return c, true
+}
+
+// detail 返回合同详情，调用时须持有锁
+func (c *Contract) detail() goyht.YhtContractDetail {
+	d := goyht.YhtContractDetail{
+		ContractID: c.ID,
+		ContractNo: c.ContractNo,
+		Title:      c.Title,
+		Status:     goyht.ContractStatus(c.Status),
+		GmtModify:  c.Modified.Format("2006-01-02 15:04:05"),
+		Signers:    []goyht.YhtContractSigner{},
+	}
+	for _, signer := range c.Signers {
+		ds := goyht.YhtContractSigner{SignerID: signer.SignerID, SignStatus: signer.status()}
+		if signer.Signed {
+			ds.SignTime = signer.SignedAt.Format("2006-01-02 15:04:05")
+		}
+		d.Signers = append(d.Signers, ds)
+	}
+	return d
+}
+
+func (s *Server) lookupContractDetailV4(w http.ResponseWriter, r *http.Request) {
+	req := goyht.YhtContractDetailReq{}
+	if !s.decodeV4(w, r, &req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.findContract(req.IDType, req.IDContent)
+	switch {
+	case c != nil:
+		writeV4(w, codeSuccess, "查询成功", c.detail())
+	case s.EmptyNotFound:
+		writeV4(w, codeSuccess, "查询成功", nil)
+	default:
+		writeV4(w, codeNotFound, "合同不存在", nil)
+	}
+}
+
+func (s *Server) listContractsV4(w http.ResponseWriter, r *http.Request) {
+	req := goyht.YhtListContractsReq{}
+	if !s.decodeV4(w, r, &req) {
+		return
+	}
+	all := []Contract{}
+	for _, c := range s.Contracts() {
+		if req.Status == "" || string(req.Status) == c.Status {
+			all = append(all, c)
+		}
+	}
+	page := goyht.YhtContractPage{Total: len(all), Contracts: []goyht.YhtContractDetail{}}
+	for _, c := range paginate(all, req.PageNum, req.PageSize) {
+		page.Contracts = append(page.Contracts, c.detail())
+	}
+	writeV4(w, codeSuccess, "查询成功", page)
+}
+
+func (s *Server) downloadContractV4(w http.ResponseWriter, r *http.Request) {
+	req := goyht.YhtDownloadContractReq{}
+	c, ok := s.contractV4(w, r, &req, &req.IDType, &req.IDContent)
+	if !ok {
+		return
+	}
+	s.writePDF(w, c)
+}
+
+func (s *Server) invalidateContractV4(w http.ResponseWriter, r *http.Request) {
+	req := goyht.YhtInvalidateContractReq{}
+	c, ok := s.contractV4(w, r, &req, &req.IDType, &req.IDContent)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	notices := s.invalidate(c)
+	s.mu.Unlock()
+	if notices == nil {
+		writeV4(w, codeInvalidParam, "合同已作废", nil)
+		return
+	}
+	writeV4(w, codeSuccess, "作废成功", nil)
+	s.deliver(notices)
+}
+
+func (s *Server) createFileContractV4(w http.ResponseWriter, r *http.Request) {
+	if !s.checkToken(w, r) {
+		return
+	}
+	f, header, err := r.FormFile("file")
+	if err != nil {
+		writeV4(w, codeInvalidParam, "文件不能为空", nil)
+		return
+	}
+	defer f.Close()
+	file, err := ioutil.ReadAll(f)
+	if err != nil || len(file) == 0 || header.Filename == "" {
+		writeV4(w, codeInvalidParam, "文件不能为空", nil)
+		return
+	}
+	c := &Contract{
+		ContractNo: r.FormValue("contractNo"),
+		Title:      r.FormValue("contractTitle"),
+		File:       file,
+	}
+	s.mu.Lock()
+	s.addContract(c)
+	s.mu.Unlock()
+	writeV4(w, codeSuccess, "创建成功", goyht.ContractIDResp{ContractID: c.ID})
+}
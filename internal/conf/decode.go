@@ -0,0 +1,243 @@
+// Package conf parses the configuration formats used by goyht, YAML, TOML
+// and JSON, into plain trees of map[string]interface{}, []interface{} and
+// scalars, and decodes such trees into structs using their json tags.
+//
+// Only the subset of YAML needed for configuration files is supported:
+// block mappings and sequences, flow sequences and mappings on one line,
+// quoted and plain scalars and comments. ParseTOML documents its own subset.
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnmarshalJSON decodes JSON data into v with the weak typing of Decode.
+func UnmarshalJSON(data []byte, v interface{}) error {
+	var tree interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&tree); err != nil {
+		return err
+	}
+	return Decode(tree, v)
+}
+
+// UnmarshalYAML decodes YAML data into v with the weak typing of Decode.
+func UnmarshalYAML(data []byte, v interface{}) error {
+	tree, err := ParseYAML(data)
+	if err != nil {
+		return err
+	}
+	return Decode(tree, v)
+}
+
+// Decode stores tree into the value pointed to by v. Struct fields are
+// matched by their json tag or name case-insensitively. Scalars are
+// converted weakly: numbers may fill string fields, strings holding numbers
+// or durations such as "5s" may fill numeric fields.
+func Decode(tree interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("conf: decode needs a non-nil pointer, got %T", v)
+	}
+	return decode(tree, rv.Elem(), "")
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func decode(node interface{}, rv reflect.Value, path string) error {
+	if node == nil {
+		return nil
+	}
+	if rv.CanAddr() {
+		if u, ok := rv.Addr().Interface().(json.Unmarshaler); ok {
+			data, err := json.Marshal(plain(node))
+			if err != nil {
+				return err
+			}
+			return u.UnmarshalJSON(data)
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decode(node, rv.Elem(), path)
+	case reflect.Interface:
+		rv.Set(reflect.ValueOf(plain(node)))
+		return nil
+	case reflect.Struct:
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return typeError(path, "mapping", node)
+		}
+		return decodeStruct(m, rv, path)
+	case reflect.Map:
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return typeError(path, "mapping", node)
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		for k, item := range m {
+			ev := reflect.New(rv.Type().Elem()).Elem()
+			if err := decode(item, ev, join(path, k)); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), ev)
+		}
+		return nil
+	case reflect.Slice:
+		items, ok := node.([]interface{})
+		if !ok {
+			return typeError(path, "sequence", node)
+		}
+		sl := reflect.MakeSlice(rv.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decode(item, sl.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(sl)
+		return nil
+	}
+
+	s, ok := scalar(node)
+	if !ok {
+		return typeError(path, rv.Kind().String(), node)
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return typeError(path, "bool", node)
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Type() == durationType {
+			if d, err := time.ParseDuration(s); err == nil {
+				rv.SetInt(int64(d))
+				return nil
+			}
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return typeError(path, "integer", node)
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return typeError(path, "unsigned integer", node)
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return typeError(path, "number", node)
+		}
+		rv.SetFloat(f)
+	default:
+		return typeError(path, rv.Kind().String(), node)
+	}
+	return nil
+}
+
+func decodeStruct(m map[string]interface{}, rv reflect.Value, path string) error {
+	typ := rv.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := sf.Name
+		if tag, ok := sf.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			if t := strings.Split(tag, ",")[0]; t != "" {
+				name = t
+			}
+		}
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			if _, tagged := sf.Tag.Lookup("json"); !tagged {
+				if err := decodeStruct(m, rv.Field(i), path); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		for k, item := range m {
+			if strings.EqualFold(k, name) {
+				if err := decode(item, rv.Field(i), join(path, k)); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// scalar returns the string form of a scalar node.
+func scalar(node interface{}) (string, bool) {
+	switch x := node.(type) {
+	case string:
+		return x, true
+	case json.Number:
+		return x.String(), true
+	case bool:
+		return strconv.FormatBool(x), true
+	case int64:
+		return strconv.FormatInt(x, 10), true
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// plain converts json.Number values to int64 or float64 for interface{} fields.
+func plain(node interface{}) interface{} {
+	switch x := node.(type) {
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n
+		}
+		f, _ := x.Float64()
+		return f
+	case map[string]interface{}:
+		for k, v := range x {
+			x[k] = plain(v)
+		}
+	case []interface{}:
+		for i, v := range x {
+			x[i] = plain(v)
+		}
+	}
+	return node
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func typeError(path, expect string, node interface{}) error {
+	if path == "" {
+		path = "root"
+	}
+	return fmt.Errorf("conf: %s: expect %s, got %v", path, expect, node)
+}
@@ -0,0 +1,354 @@
+package conf
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseTOML parses a TOML document into the same kind of tree as ParseYAML.
+// Supported are key/value pairs with bare, quoted and dotted keys, [table]
+// and [[array of tables]] headers, basic strings with the TOML 1.0 escapes,
+// literal strings, integers, floats, booleans, arrays (which may span lines)
+// and inline tables. Dates and times are kept as strings, multi-line strings
+// are not supported.
+func ParseTOML(data []byte) (interface{}, error) {
+	root := map[string]interface{}{}
+	cur := root
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		num := i + 1
+		text := strings.TrimSpace(stripComment(strings.TrimRight(lines[i], "\r"), '='))
+		if text == "" {
+			continue
+		}
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("conf: toml line %d: %s", num, fmt.Sprintf(format, args...))
+		}
+
+		if strings.HasPrefix(text, "[") {
+			array := strings.HasPrefix(text, "[[")
+			name := strings.TrimPrefix(text, "[")
+			if array {
+				name = strings.TrimPrefix(name, "[")
+				if !strings.HasSuffix(name, "]]") {
+					return nil, errorf("unterminated table header %q", text)
+				}
+				name = strings.TrimSuffix(name, "]]")
+			} else {
+				if !strings.HasSuffix(name, "]") {
+					return nil, errorf("unterminated table header %q", text)
+				}
+				name = strings.TrimSuffix(name, "]")
+			}
+			keys, err := splitDotted(name)
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			if !array {
+				if cur, err = tomlTable(root, keys); err != nil {
+					return nil, errorf("%v", err)
+				}
+				continue
+			}
+			parent, err := tomlTable(root, keys[:len(keys)-1])
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			last := keys[len(keys)-1]
+			var list []interface{}
+			switch v := parent[last].(type) {
+			case nil:
+			case []interface{}:
+				list = v
+			default:
+				return nil, errorf("key %s is not an array of tables", last)
+			}
+			cur = map[string]interface{}{}
+			parent[last] = append(list, cur)
+			continue
+		}
+
+		eq := indexOutsideQuotes(text, '=')
+		if eq < 0 {
+			return nil, errorf("expect \"key = value\", got %q", text)
+		}
+		keys, err := splitDotted(text[:eq])
+		if err != nil {
+			return nil, errorf("%v", err)
+		}
+		value := strings.TrimSpace(text[eq+1:])
+		// An array may span lines until its brackets pair up.
+		for !balanced(value) && i+1 < len(lines) {
+			i++
+			value += " " + strings.TrimSpace(stripComment(strings.TrimRight(lines[i], "\r"), '='))
+		}
+		t, err := tomlTable(cur, keys[:len(keys)-1])
+		if err != nil {
+			return nil, errorf("%v", err)
+		}
+		last := keys[len(keys)-1]
+		if _, dup := t[last]; dup {
+			return nil, errorf("duplicate key %s", last)
+		}
+		if t[last], err = parseTOMLValue(value); err != nil {
+			return nil, errorf("%v", err)
+		}
+	}
+	return root, nil
+}
+
+// UnmarshalTOML decodes TOML data into v with the weak typing of Decode.
+func UnmarshalTOML(data []byte, v interface{}) error {
+	tree, err := ParseTOML(data)
+	if err != nil {
+		return err
+	}
+	return Decode(tree, v)
+}
+
+// tomlTable returns the table at keys below m, creating missing tables. A
+// key holding an array of tables refers to its last table.
+func tomlTable(m map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case nil:
+			t := map[string]interface{}{}
+			m[k] = t
+			m = t
+		case map[string]interface{}:
+			m = v
+		case []interface{}:
+			if len(v) == 0 {
+				return nil, fmt.Errorf("key %s is not a table", k)
+			}
+			t, ok := v[len(v)-1].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("key %s is not a table", k)
+			}
+			m = t
+		default:
+			return nil, fmt.Errorf("key %s is not a table", k)
+		}
+	}
+	return m, nil
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlEscapes are the escapes of TOML 1.0 basic strings.
+var tomlEscapes = escapes{
+	'b': '\b', 't': '\t', 'n': '\n', 'f': '\f', 'r': '\r', '"': '"', '\\': '\\',
+	'u': -4, 'U': -8,
+}
+
+// splitDotted splits a key such as a."b.c".d into its parts.
+func splitDotted(s string) ([]string, error) {
+	var keys []string
+	s = strings.TrimSpace(s)
+	for {
+		var key string
+		switch {
+		case strings.HasPrefix(s, "\""):
+			end := closingQuote(s)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid key %q", s)
+			}
+			k, err := unquote(s[:end+1], tomlEscapes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %q", s)
+			}
+			key, s = k, s[end+1:]
+		case strings.HasPrefix(s, "'"):
+			end := strings.IndexByte(s[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid key %q", s)
+			}
+			key, s = s[1:end+1], s[end+2:]
+		default:
+			end := strings.IndexByte(s, '.')
+			if end < 0 {
+				end = len(s)
+			}
+			key = strings.TrimSpace(s[:end])
+			if !bareKey.MatchString(key) {
+				return nil, fmt.Errorf("invalid key %q", s[:end])
+			}
+			s = s[end:]
+		}
+		keys = append(keys, key)
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return keys, nil
+		}
+		if s[0] != '.' {
+			return nil, fmt.Errorf("invalid key near %q", s)
+		}
+		s = strings.TrimSpace(s[1:])
+	}
+}
+
+// closingQuote returns the index of the quote closing the basic string at
+// the start of s, or -1.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// indexOutsideQuotes returns the index of the first c outside quotes.
+func indexOutsideQuotes(s string, c byte) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' && quote == '"' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			if s[i] == c {
+				return i
+			}
+			quote = s[i]
+		case s[i] == c:
+			return i
+		}
+	}
+	return -1
+}
+
+// balanced reports whether the brackets and braces in s outside quotes pair up.
+func balanced(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+var tomlDateTime = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}([T ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:\d{2})?)?|\d{2}:\d{2}:\d{2}(\.\d+)?)$`)
+
+// parseTOMLValue parses the value of a key.
+func parseTOMLValue(s string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(s, `"""`), strings.HasPrefix(s, "'''"):
+		return nil, fmt.Errorf("multi-line strings are not supported")
+	case strings.HasPrefix(s, "\""):
+		if end := closingQuote(s); end != len(s)-1 {
+			return nil, fmt.Errorf("invalid string %s", s)
+		}
+		v, err := unquote(s, tomlEscapes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s: %v", s, err)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || strings.IndexByte(s[1:], '\'') != len(s)-2 {
+			return nil, fmt.Errorf("invalid string %s", s)
+		}
+		return s[1 : len(s)-1], nil
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated array %q", s)
+		}
+		parts, err := splitFlow(s[1:len(s)-1], '=')
+		if err != nil {
+			return nil, err
+		}
+		// A trailing comma is allowed.
+		if n := len(parts); n > 0 && parts[n-1] == "" {
+			parts = parts[:n-1]
+		}
+		items := []interface{}{}
+		for _, part := range parts {
+			v, err := parseTOMLValue(part)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	case strings.HasPrefix(s, "{"):
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("unterminated inline table %q", s)
+		}
+		parts, err := splitFlow(s[1:len(s)-1], '=')
+		if err != nil {
+			return nil, err
+		}
+		m := map[string]interface{}{}
+		for _, part := range parts {
+			eq := indexOutsideQuotes(part, '=')
+			if eq < 0 {
+				return nil, fmt.Errorf("expect \"key = value\" in inline table, got %q", part)
+			}
+			keys, err := splitDotted(part[:eq])
+			if err != nil {
+				return nil, err
+			}
+			t, err := tomlTable(m, keys[:len(keys)-1])
+			if err != nil {
+				return nil, err
+			}
+			last := keys[len(keys)-1]
+			if _, dup := t[last]; dup {
+				return nil, fmt.Errorf("duplicate key %s", last)
+			}
+			if t[last], err = parseTOMLValue(strings.TrimSpace(part[eq+1:])); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+
+	switch s {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if tomlDateTime.MatchString(s) {
+		return s, nil
+	}
+	num := strings.ReplaceAll(s, "_", "")
+	digits := strings.TrimLeft(num, "+-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '9' {
+		return nil, fmt.Errorf("leading zeros are not allowed in %s", s)
+	}
+	if n, err := strconv.ParseInt(num, 0, 64); err == nil {
+		return n, nil
+	}
+	switch digits {
+	case "inf", "nan":
+		f, _ := strconv.ParseFloat(num, 64)
+		return f, nil
+	}
+	if f, err := strconv.ParseFloat(num, 64); err == nil && strings.ContainsAny(num, "0123456789") {
+		return f, nil
+	}
+	return nil, fmt.Errorf("invalid value %q", s)
+}
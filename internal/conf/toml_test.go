@@ -0,0 +1,99 @@
+package conf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	src := `
+# goyht
+appId = "app"   # inline comment
+escaped = "tab\tcaf\u00e9 # not a comment"
+"app.key" = 'C:\keys'
+card = "0123"
+ratio = 1_000.5
+profile.name = "sandbox"
+
+[profiles.sandbox]
+apiGateway = "https://sandbox.example.com/api"
+retries = 0x10
+hosts = [
+  "a",
+  "b", # second
+]
+
+[[templates]]
+templateId = 92130
+locations = ["甲方签章", "乙方签章"]
+placeholders = [{name = "rent", type = "money", required = true}]
+
+[[templates]]
+templateId = "56006"
+signed = 2026-01-02T15:04:05Z
+`
+	tree, err := ParseTOML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"appId":   "app",
+		"escaped": "tab\tcafé # not a comment",
+		"app.key": `C:\keys`,
+		"card":    "0123",
+		"ratio":   1000.5,
+		"profile": map[string]interface{}{"name": "sandbox"},
+		"profiles": map[string]interface{}{
+			"sandbox": map[string]interface{}{
+				"apiGateway": "https://sandbox.example.com/api",
+				"retries":    int64(16),
+				"hosts":      []interface{}{"a", "b"},
+			},
+		},
+		"templates": []interface{}{
+			map[string]interface{}{
+				"templateId": int64(92130),
+				"locations":  []interface{}{"甲方签章", "乙方签章"},
+				"placeholders": []interface{}{
+					map[string]interface{}{"name": "rent", "type": "money", "required": true},
+				},
+			},
+			map[string]interface{}{"templateId": "56006", "signed": "2026-01-02T15:04:05Z"},
+		},
+	}
+	if !reflect.DeepEqual(tree, want) {
+		t.Fatalf("tree = %#v", tree)
+	}
+
+	var v struct {
+		AppID    string `json:"appId"`
+		Profiles map[string]struct {
+			APIGateway string `json:"apiGateway"`
+			Retries    int
+		} `json:"profiles"`
+	}
+	if err = UnmarshalTOML([]byte(src), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.AppID != "app" || v.Profiles["sandbox"].Retries != 16 {
+		t.Errorf("UnmarshalTOML = %+v", v)
+	}
+
+	for _, bad := range []string{
+		"a = 1\na = 2",
+		"a = 1\n[a]",
+		"a = \"\"\"text\"\"\"",
+		"a = [1, 2",
+		"a = 012",
+		"a = what",
+		"[a",
+		"bad key = 1",
+		"novalue",
+		`a = "\e"`,
+		`a = "\x41"`,
+	} {
+		if _, err := ParseTOML([]byte(bad)); err == nil {
+			t.Errorf("ParseTOML(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,430 @@
+package conf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// yamlLine is a significant source line with its indentation.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// ParseYAML parses a YAML document into a tree of map[string]interface{},
+// []interface{}, string, int64, float64, bool and nil values.
+//
+// Only the subset of YAML used by configuration files is supported: a single
+// document of block mappings and sequences indented with spaces, flow
+// sequences and mappings on one line, plain, single- and double-quoted
+// scalars and comments. Double-quoted scalars accept the YAML escapes, a
+// quote inside a plain scalar, as in O'Brien, is part of the scalar. Block
+// scalars (| and >), anchors, aliases and tags are rejected, as are
+// multi-line flow collections and plain scalars.
+func ParseYAML(data []byte) (interface{}, error) {
+	lines, err := yamlLines(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	p := &yamlParser{lines: lines}
+	v, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf(p.lines[p.pos], "unexpected indentation")
+	}
+	return v, nil
+}
+
+func yamlLines(src string) ([]yamlLine, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(src, "\n") {
+		raw = strings.TrimRight(raw, " \t\r")
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("conf: yaml line %d: tabs are not allowed for indentation", i+1)
+		}
+		text = strings.TrimSpace(stripComment(text, ':'))
+		if text == "" || text == "---" || text == "..." {
+			continue
+		}
+		if strings.HasPrefix(text, "%") {
+			continue
+		}
+		lines = append(lines, yamlLine{num: i + 1, indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: text})
+	}
+	return lines, nil
+}
+
+// stripComment removes a trailing comment outside quotes. sep is the
+// separator of keys and values, ':' for YAML and '=' for TOML.
+func stripComment(s string, sep byte) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && quoteStarts(s, i, sep):
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// quoteStarts reports whether the quote at s[i] opens a quoted scalar. In
+// YAML it must start the text, an item of a flow collection, a sequence entry
+// or a value, elsewhere, as in O'Brien, it is part of a plain scalar. TOML
+// has no plain scalars, so every quote opens a string.
+func quoteStarts(s string, i int, sep byte) bool {
+	if sep != ':' {
+		return true
+	}
+	j := i - 1
+	for j >= 0 && (s[j] == ' ' || s[j] == '\t') {
+		j--
+	}
+	if j < 0 {
+		return true
+	}
+	switch s[j] {
+	case '[', '{', ',':
+		return true
+	case '-':
+		return j < i-1 && strings.Trim(s[:j], "- ") == ""
+	case ':':
+		// A value indicator is followed by a space.
+		return j < i-1
+	}
+	return false
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) errorf(l yamlLine, format string, args ...interface{}) error {
+	return fmt.Errorf("conf: yaml line %d: %s", l.num, fmt.Sprintf(format, args...))
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block parses the mapping or sequence starting at the current line.
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if isSeqItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	items := []interface{}{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent != indent || !isSeqItem(l.text) {
+			break
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		if rest == "" {
+			p.pos++
+			v, err := p.nested(indent, false)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+			continue
+		}
+		if _, _, ok := splitKey(rest); ok && !isFlow(rest) {
+			// "- key: value" starts a mapping indented past the dash.
+			p.lines[p.pos] = yamlLine{num: l.num, indent: l.indent + len(l.text) - len(rest), text: rest}
+			v, err := p.mapping(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+			continue
+		}
+		v, err := parseScalar(rest)
+		if err != nil {
+			return nil, p.errorf(l, "%v", err)
+		}
+		items = append(items, v)
+		p.pos++
+	}
+	return items, nil
+}
+
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf(l, "unexpected indentation")
+		}
+		if isSeqItem(l.text) {
+			break
+		}
+		key, value, ok := splitKey(l.text)
+		if !ok {
+			return nil, p.errorf(l, "expect \"key: value\", got %q", l.text)
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf(l, "duplicate key %q", key)
+		}
+		p.pos++
+		if value == "" {
+			v, err := p.nested(indent, true)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+			continue
+		}
+		if value == "|" || value == ">" || strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			return nil, p.errorf(l, "block scalars are not supported")
+		}
+		v, err := parseScalar(value)
+		if err != nil {
+			return nil, p.errorf(l, "%v", err)
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// nested parses the value of an empty key or dash. A mapping value may be
+// a sequence at the same indentation as its key.
+func (p *yamlParser) nested(indent int, sameIndentSeq bool) (interface{}, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent {
+		return p.block(next.indent)
+	}
+	if sameIndentSeq && next.indent == indent && isSeqItem(next.text) {
+		return p.sequence(indent)
+	}
+	return nil, nil
+}
+
+// splitKey splits "key: value" at the first colon followed by a space or the
+// end of line, outside quotes.
+func splitKey(text string) (string, string, bool) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && i == 0:
+			quote = c
+		case c == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			key := strings.TrimSpace(text[:i])
+			if key == "" {
+				return "", "", false
+			}
+			if k, err := parseScalar(key); err == nil {
+				if s, ok := scalar(k); ok {
+					key = s
+				}
+			}
+			return key, strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+func isFlow(s string) bool {
+	return strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") ||
+		strings.HasPrefix(s, "\"") || strings.HasPrefix(s, "'")
+}
+
+// parseScalar parses a plain, quoted or flow scalar.
+func parseScalar(s string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated flow sequence %q", s)
+		}
+		parts, err := splitFlow(s[1:len(s)-1], ':')
+		if err != nil {
+			return nil, err
+		}
+		items := []interface{}{}
+		for _, part := range parts {
+			v, err := parseScalar(part)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	case strings.HasPrefix(s, "{"):
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("unterminated flow mapping %q", s)
+		}
+		parts, err := splitFlow(s[1:len(s)-1], ':')
+		if err != nil {
+			return nil, err
+		}
+		m := map[string]interface{}{}
+		for _, part := range parts {
+			key, value, ok := splitKey(part)
+			if !ok {
+				return nil, fmt.Errorf("expect \"key: value\" in flow mapping, got %q", part)
+			}
+			v, err := parseScalar(value)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+		}
+		return m, nil
+	case strings.HasPrefix(s, "\""):
+		return unquote(s, yamlEscapes)
+	case strings.HasPrefix(s, "'"):
+		// A quote inside a single-quoted scalar is written twice.
+		if len(s) < 2 || !strings.HasSuffix(s, "'") || strings.Contains(strings.ReplaceAll(s[1:len(s)-1], "''", ""), "'") {
+			return nil, fmt.Errorf("invalid quoted string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+
+	if s != "" && strings.IndexByte("&*!", s[0]) >= 0 {
+		return nil, fmt.Errorf("anchors, aliases and tags are not supported: %q", s)
+	}
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	// Numbers with leading zeros, such as codes and card numbers, stay strings.
+	if len(s) > 1 && s[0] == '0' && s[1] != '.' {
+		return s, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && strings.ContainsAny(s, "0123456789") {
+		return f, nil
+	}
+	return s, nil
+}
+
+// escapes maps the character after a backslash in a double-quoted string to
+// the character it stands for, or to -n for n hex digits giving a code point.
+type escapes map[byte]rune
+
+// yamlEscapes are the escapes of YAML 1.2 double-quoted scalars.
+var yamlEscapes = escapes{
+	'0': 0, 'a': '\a', 'b': '\b', 't': '\t', '\t': '\t', 'n': '\n', 'v': '\v',
+	'f': '\f', 'r': '\r', 'e': 0x1b, ' ': ' ', '"': '"', '/': '/', '\\': '\\',
+	'N': 0x85, '_': 0xa0, 'L': 0x2028, 'P': 0x2029,
+	'x': -2, 'u': -4, 'U': -8,
+}
+
+// unquote decodes the double-quoted string s, which must end at the closing
+// quote. Escapes other than those in esc are rejected.
+func unquote(s string, esc escapes) (string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			if i != len(s)-1 {
+				return "", fmt.Errorf("unexpected text after quoted string %s", s)
+			}
+			return b.String(), nil
+		case '\\':
+			if i+1 == len(s) {
+				break
+			}
+			r, ok := esc[s[i+1]]
+			if !ok {
+				return "", fmt.Errorf("invalid escape \\%c in %s", s[i+1], s)
+			}
+			i++
+			if r >= 0 {
+				b.WriteRune(r)
+				continue
+			}
+			n := int(-r)
+			if i+n >= len(s) {
+				return "", fmt.Errorf("invalid escape in %s", s)
+			}
+			v, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(v)) {
+				return "", fmt.Errorf("invalid escape \\%c%s in %s", s[i], s[i+1:i+1+n], s)
+			}
+			b.WriteRune(rune(v))
+			i += n
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated quoted string %s", s)
+}
+
+// splitFlow splits the inside of a flow collection at top-level commas. sep
+// is the separator of keys and values, see stripComment.
+func splitFlow(s string, sep byte) ([]string, error) {
+	var (
+		parts []string
+		depth int
+		quote byte
+		start int
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && quoteStarts(s, i, sep):
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if quote != 0 || depth != 0 {
+		return nil, fmt.Errorf("unbalanced flow collection %q", s)
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" || len(parts) > 0 {
+		parts = append(parts, last)
+	}
+	return parts, nil
+}
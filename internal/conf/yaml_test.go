@@ -0,0 +1,102 @@
+package conf
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseYAML(t *testing.T) {
+	src := `
+# templates
+templates:
+- templateId: "92130"   # quoted id
+  name: 'Lessor''s lease'
+  signers: [56006, "02289"]
+  fields:
+    - name: rent
+      required: true
+      maxLength: 10
+    -
+      name: paystay
+      enum: [月付, 季付]
+timeout: 5s
+card: 0123
+flow: {a: 1, b: [x, y]}
+empty:
+owner: O'Brien # a quote inside a plain scalar
+names: [O'Brien, 'x, y']
+escaped: "a\/b\e\x41\u00e9\N"
+`
+	tree, err := ParseYAML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"templates": []interface{}{
+			map[string]interface{}{
+				"templateId": "92130",
+				"name":       "Lessor's lease",
+				"signers":    []interface{}{int64(56006), "02289"},
+				"fields": []interface{}{
+					map[string]interface{}{"name": "rent", "required": true, "maxLength": int64(10)},
+					map[string]interface{}{"name": "paystay", "enum": []interface{}{"月付", "季付"}},
+				},
+			},
+		},
+		"timeout": "5s",
+		"card":    "0123",
+		"flow":    map[string]interface{}{"a": int64(1), "b": []interface{}{"x", "y"}},
+		"empty":   nil,
+		"owner":   "O'Brien",
+		"names":   []interface{}{"O'Brien", "x, y"},
+		"escaped": "a/b\x1bAé\u0085",
+	}
+	if !reflect.DeepEqual(tree, want) {
+		t.Fatalf("tree = %#v", tree)
+	}
+
+	for _, bad := range []string{"a: 1\n  b: 2", "a: 1\na: 2", "a: |\n  text", "a: [1, 2", "a: &x 1", "b: *x", "c: !!str 1",
+		`a: "\q"`, `a: "\'"`, `a: "\101"`, `a: "x" y`, `a: 'it's'`, `a: "open`} {
+		if _, err := ParseYAML([]byte(bad)); err == nil {
+			t.Errorf("ParseYAML(%q) should fail", bad)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	type field struct {
+		Name      string   `json:"name"`
+		Required  bool     `json:"required"`
+		MaxLength int      `json:"maxLength"`
+		Enum      []string `json:"enum"`
+	}
+	var v struct {
+		Templates []struct {
+			TemplateID string   `json:"templateId"`
+			Signers    []string `json:"signers"`
+			Fields     []field  `json:"fields"`
+		} `json:"templates"`
+		Timeout time.Duration
+		Extra   map[string]interface{} `json:"flow"`
+	}
+	src := "templates:\n- templateId: 92130\n  signers: [56006]\n  fields:\n  - {name: rent, required: true, maxLength: \"10\"}\ntimeout: 5s\nflow: {a: 1}\n"
+	if err := UnmarshalYAML([]byte(src), &v); err != nil {
+		t.Fatal(err)
+	}
+	tpl := v.Templates[0]
+	if tpl.TemplateID != "92130" || tpl.Signers[0] != "56006" || tpl.Fields[0].MaxLength != 10 || !tpl.Fields[0].Required {
+		t.Fatalf("decoded = %+v", tpl)
+	}
+	if v.Timeout != 5*time.Second || v.Extra["a"] != int64(1) {
+		t.Fatalf("timeout = %v, extra = %v", v.Timeout, v.Extra)
+	}
+
+	var j struct{ N int }
+	if err := UnmarshalJSON([]byte(`{"n": 3}`), &j); err != nil || j.N != 3 {
+		t.Fatalf("UnmarshalJSON = %v, %+v", err, j)
+	}
+	if err := UnmarshalJSON([]byte(`{"n": "x"}`), &j); err == nil {
+		t.Fatal("decoding a string into an int should fail")
+	}
+}
@@ -0,0 +1,307 @@
+package goyht
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// NoticeType 异步通知类型
+type NoticeType int
+
+// 异步通知类型
+const (
+	NoticeContractSigned      NoticeType = 1 // 签署者完成签署
+	NoticeContractCompleted   NoticeType = 2 // 合同全部签署完成
+	NoticeContractInvalidated NoticeType = 3 // 合同作废
+	NoticeSignerRejected      NoticeType = 4 // 签署者拒签
+)
+
+// Notice 云合同异步通知
+type Notice struct {
+	Type    NoticeType             `json:"noticeType"`
+	Content string                 `json:"content"`
+	Params  string                 `json:"noticeParams"`
+	Info    map[string]interface{} `json:"map"`
+	Raw     []byte                 `json:"-"` // 通知的原始JSON
+}
+
+// Base returns n, it makes every event type carry its notice.
+func (n *Notice) Base() *Notice {
+	return n
+}
+
+// info 返回通知参数中key对应的值，数字转换为字符串
+func (n *Notice) info(key string) string {
+	switch v := n.Info[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Event 解析后的异步通知事件，为*ContractSignedEvent等类型之一
+type Event interface {
+	Base() *Notice
+}
+
+// ContractEvent 合同相关事件的公共字段
+type ContractEvent struct {
+	*Notice
+	ContractID string // 云合同平台合同ID
+	ContractNo string // 自定义合同编号
+	Status     ContractStatus
+	NoticeTime string // 通知时间
+}
+
+// ContractSignedEvent 签署者完成签署
+type ContractSignedEvent struct {
+	ContractEvent
+	SignerID  string // V4接口的用户ID
+	AppUserID string // V3接口的第三方用户ID
+}
+
+// ContractCompletedEvent 合同全部签署完成
+type ContractCompletedEvent struct {
+	ContractEvent
+}
+
+// ContractInvalidatedEvent 合同作废
+type ContractInvalidatedEvent struct {
+	ContractEvent
+}
+
+// SignerRejectedEvent 签署者拒签
+type SignerRejectedEvent struct {
+	ContractEvent
+	SignerID  string
+	AppUserID string
+}
+
+// UnknownEvent 未知类型的通知
+type UnknownEvent struct {
+	*Notice
+}
+
+// ParseNotice 解析通知的JSON内容
+func ParseNotice(data []byte) (Event, error) {
+	n := &Notice{Raw: data}
+	if err := json.Unmarshal(data, n); err != nil {
+		return nil, fmt.Errorf("%w: malformed notice: %v", ErrInvalidParam, err)
+	}
+	if n.Info == nil && n.Params != "" {
+		json.Unmarshal([]byte(n.Params), &n.Info)
+	}
+
+	ce := ContractEvent{
+		Notice:     n,
+		ContractID: n.info("contractId"),
+		ContractNo: n.info("contractNo"),
+		Status:     ContractStatus(n.info("status")),
+		NoticeTime: n.info("noticeTime"),
+	}
+	switch n.Type {
+	case NoticeContractSigned:
+		return &ContractSignedEvent{ContractEvent: ce, SignerID: n.info("signerId"), AppUserID: n.info("appUserId")}, nil
+	case NoticeContractCompleted:
+		return &ContractCompletedEvent{ContractEvent: ce}, nil
+	case NoticeContractInvalidated:
+		return &ContractInvalidatedEvent{ContractEvent: ce}, nil
+	case NoticeSignerRejected:
+		return &SignerRejectedEvent{ContractEvent: ce, SignerID: n.info("signerId"), AppUserID: n.info("appUserId")}, nil
+	}
+	return &UnknownEvent{Notice: n}, nil
+}
+
+// readNotice 读取请求中notice表单字段的内容
+func readNotice(r *http.Request) ([]byte, error) {
+	if r.Header.Get("Content-Type") == "" {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidParam, err)
+	}
+	notice := r.PostForm.Get("notice")
+	if notice == "" {
+		return nil, fmt.Errorf("%w: missing notice", ErrInvalidParam)
+	}
+	return []byte(notice), nil
+}
+
+// EventHandler 处理异步通知事件，返回error时应答云合同处理失败，云合同将重新发送通知
+type EventHandler func(ctx context.Context, ev Event) error
+
+// NotificationHandler 云合同异步通知的http.Handler，校验并解析通知后分发给注册的回调，
+// 并根据回调的结果自动应答云合同。已处理过的通知不再分发，直接应答成功；
+// 正在处理的通知应答失败，由云合同稍后重发
+type NotificationHandler struct {
+	mu       sync.RWMutex
+	handlers map[NoticeType][]EventHandler
+	fallback []EventHandler
+
+	appKey      string
+	allowed     []*net.IPNet
+	proxyHeader string
+	window      time.Duration
+	dedupe      DedupeStore
+	store       NotificationStore
+	watchers    watchers
+	now         func() time.Time
+}
+
+// NewNotificationHandler returns a *NotificationHandler without callbacks.
+// Replayed notifications are rejected with a MemoryDedupeStore by default.
+func NewNotificationHandler(opts ...NotifyOption) *NotificationHandler {
+	h := &NotificationHandler{
+		handlers: map[NoticeType][]EventHandler{},
+		dedupe:   NewMemoryDedupeStore(DefaultDedupeTTL),
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Handle registers fn for notices of type t.
+func (h *NotificationHandler) Handle(t NoticeType, fn EventHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[t] = append(h.handlers[t], fn)
+}
+
+// HandleDefault registers fn for notices without a callback of their type.
+func (h *NotificationHandler) HandleDefault(fn EventHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fallback = append(h.fallback, fn)
+}
+
+// OnContractSigned registers fn for NoticeContractSigned.
+func (h *NotificationHandler) OnContractSigned(fn func(ctx context.Context, ev *ContractSignedEvent) error) {
+	h.Handle(NoticeContractSigned, func(ctx context.Context, ev Event) error {
+		return fn(ctx, ev.(*ContractSignedEvent))
+	})
+}
+
+// OnContractCompleted registers fn for NoticeContractCompleted.
+func (h *NotificationHandler) OnContractCompleted(fn func(ctx context.Context, ev *ContractCompletedEvent) error) {
+	h.Handle(NoticeContractCompleted, func(ctx context.Context, ev Event) error {
+		return fn(ctx, ev.(*ContractCompletedEvent))
+	})
+}
+
+// OnContractInvalidated registers fn for NoticeContractInvalidated.
+func (h *NotificationHandler) OnContractInvalidated(fn func(ctx context.Context, ev *ContractInvalidatedEvent) error) {
+	h.Handle(NoticeContractInvalidated, func(ctx context.Context, ev Event) error {
+		return fn(ctx, ev.(*ContractInvalidatedEvent))
+	})
+}
+
+// OnSignerRejected registers fn for NoticeSignerRejected.
+func (h *NotificationHandler) OnSignerRejected(fn func(ctx context.Context, ev *SignerRejectedEvent) error) {
+	h.Handle(NoticeSignerRejected, func(ctx context.Context, ev Event) error {
+		return fn(ctx, ev.(*SignerRejectedEvent))
+	})
+}
+
+// Dispatch calls the callbacks registered for ev in order and stops at the first error.
+func (h *NotificationHandler) Dispatch(ctx context.Context, ev Event) error {
+	h.notifyWatchers(ev)
+
+	h.mu.RLock()
+	handlers, ok := h.handlers[ev.Base().Type]
+	if !ok {
+		handlers = h.fallback
+	}
+	handlers = append([]EventHandler(nil), handlers...)
+	h.mu.RUnlock()
+
+	for _, fn := range handlers {
+		if err := fn(ctx, ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *NotificationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := readNotice(r)
+	if err != nil {
+		writeAnswer(w, http.StatusBadRequest, false, err.Error())
+		return
+	}
+	if err = h.verifyRequest(r, data); err != nil {
+		writeAnswer(w, http.StatusForbidden, false, err.Error())
+		return
+	}
+	ev, err := ParseNotice(data)
+	if err != nil {
+		writeAnswer(w, http.StatusBadRequest, false, err.Error())
+		return
+	}
+	if err = h.verifyTime(r, ev.Base()); err != nil {
+		writeAnswer(w, http.StatusForbidden, false, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	id := ev.Base().ID()
+	if h.dedupe != nil {
+		state, err := h.dedupe.Reserve(ctx, id)
+		if err != nil {
+			writeAnswer(w, http.StatusInternalServerError, false, err.Error())
+			return
+		}
+		switch state {
+		case NoticeProcessed:
+			writeAnswer(w, http.StatusOK, true, "duplicate")
+			return
+		case NoticePending:
+			// 处理结果未知，应答失败使云合同稍后重发
+			writeAnswer(w, http.StatusConflict, false, "in progress")
+			return
+		}
+	}
+	if err = h.process(ctx, id, ev); err != nil {
+		writeAnswer(w, http.StatusOK, false, err.Error())
+		return
+	}
+	writeAnswer(w, http.StatusOK, true, "")
+}
+
+// writeAnswer 写入应答云合同异步通知的JSON
+func writeAnswer(w http.ResponseWriter, status int, rsp bool, msg string) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	w.Write([]byte(answerAsyncNotify(rsp, msg)))
+}
+
+// answerAsyncNotify 返回应答异步通知的JSON
+func answerAsyncNotify(rsp bool, msg string) string {
+	ret := map[string]interface{}{
+		"response": rsp,
+		"msg":      msg,
+	}
+	data, err := json.Marshal(ret)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
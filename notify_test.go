@@ -0,0 +1,182 @@
+package goyht
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postNotice(h http.Handler, notice string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader("notice="+url.QueryEscape(notice)))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestNotificationHandler(t *testing.T) {
+	h := NewNotificationHandler()
+	var signed *ContractSignedEvent
+	h.OnContractSigned(func(ctx context.Context, ev *ContractSignedEvent) error {
+		signed = ev
+		return nil
+	})
+	h.OnContractCompleted(func(ctx context.Context, ev *ContractCompletedEvent) error {
+		return errors.New("busy")
+	})
+
+	// 内容中包含"notice="和"+"时也应正确解析
+	w := postNotice(h, `{"noticeType":1,"content":"a+b notice=x","map":{"contractId":1001,"signerId":"7","status":"1"}}`)
+	if w.Body.String() != `{"msg":"","response":true}` {
+		t.Fatalf("unexpected answer %q", w.Body.String())
+	}
+	if signed == nil || signed.ContractID != "1001" || signed.SignerID != "7" || signed.Content != "a+b notice=x" {
+		t.Fatalf("unexpected event %+v", signed)
+	}
+
+	w = postNotice(h, `{"noticeType":2,"map":{"contractId":"1001"}}`)
+	if w.Body.String() != `{"msg":"busy","response":false}` {
+		t.Fatalf("unexpected answer %q", w.Body.String())
+	}
+
+	w = postNotice(h, `{"noticeType":`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expect bad request, got %d", w.Code)
+	}
+
+	ev, err := ParseNotice([]byte(`{"noticeType":99}`))
+	if _, ok := ev.(*UnknownEvent); !ok || err != nil {
+		t.Fatalf("expect unknown event, got %T %v", ev, err)
+	}
+}
+
+func TestNotificationVerification(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, noticeLocation)
+	calls := 0
+	h := NewNotificationHandler(WithNoticeKey("key"), WithAllowedIPs("10.0.0.0/8", "bad"),
+		WithProxyHeader("X-Forwarded-For"), WithFreshness(5*time.Minute))
+	h.now = func() time.Time { return now }
+	h.HandleDefault(func(ctx context.Context, ev Event) error {
+		calls++
+		return nil
+	})
+
+	notice := `{"noticeType":2,"map":{"contractId":"1001","noticeTime":"2026-01-02 15:02:00"}}`
+	post := func(sign, timestamp, ip string) int {
+		form := url.Values{"notice": {notice}}
+		if sign != "" {
+			form.Set(NoticeSignField, sign)
+		}
+		if timestamp != "" {
+			form.Set(NoticeTimestampField, timestamp)
+		}
+		r := httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(form.Encode()))
+		r.Header.Set("X-Forwarded-For", "1.2.3.4, "+ip)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte(notice))
+	sign := hex.EncodeToString(mac.Sum(nil))
+	if sign != SignNotice("key", "", []byte(notice)) {
+		t.Fatalf("unexpected sign %s", SignNotice("key", "", []byte(notice)))
+	}
+	if code := post(sign, "", "192.168.1.1"); code != http.StatusForbidden {
+		t.Fatalf("expect forbidden ip, got %d", code)
+	}
+	if code := post("", "", "10.1.2.3"); code != http.StatusForbidden {
+		t.Fatalf("expect missing sign rejected, got %d", code)
+	}
+	if code := post("00ff", "", "10.1.2.3"); code != http.StatusForbidden {
+		t.Fatalf("expect forbidden sign, got %d", code)
+	}
+	digest := md5.Sum([]byte(notice + "key"))
+	if code := post(hex.EncodeToString(digest[:]), "", "10.1.2.3"); code != http.StatusForbidden {
+		t.Fatalf("expect MD5 sign rejected, got %d", code)
+	}
+	if code := post(strings.ToUpper(sign), "", "10.1.2.3"); code != http.StatusOK || calls != 1 {
+		t.Fatalf("expect accepted notice, got %d %d", code, calls)
+	}
+	if code := post(sign, "", "10.1.2.3"); code != http.StatusOK || calls != 1 {
+		t.Fatalf("expect replay not dispatched, got %d %d", code, calls)
+	}
+
+	now = now.Add(time.Hour)
+	h.dedupe = nil
+	if code := post(sign, "", "10.1.2.3"); code != http.StatusForbidden {
+		t.Fatalf("expect stale notice rejected, got %d", code)
+	}
+	// 重放者更换时间戳后签名不再匹配
+	fresh := strconv.FormatInt(now.Unix(), 10)
+	if code := post(sign, fresh, "10.1.2.3"); code != http.StatusForbidden {
+		t.Fatalf("expect replay with a new timestamp rejected, got %d", code)
+	}
+	if code := post(SignNotice("key", fresh, []byte(notice)), fresh, "10.1.2.3"); code != http.StatusOK || calls != 2 {
+		t.Fatalf("expect signed timestamp accepted, got %d %d", code, calls)
+	}
+}
+
+func TestNotificationConcurrentRedelivery(t *testing.T) {
+	h := NewNotificationHandler()
+	started, release := make(chan struct{}), make(chan struct{})
+	calls := 0
+	h.HandleDefault(func(ctx context.Context, ev Event) error {
+		calls++
+		close(started)
+		<-release
+		return nil
+	})
+
+	notice := `{"noticeType":2,"map":{"noticeId":"n1","contractId":"1001"}}`
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postNotice(h, notice) }()
+	<-started
+	// 第一次投递仍在处理时，重发的通知不应再次分发，也不应答成功
+	if w := postNotice(h, notice); w.Code != http.StatusConflict || w.Body.String() != `{"msg":"in progress","response":false}` {
+		t.Fatalf("unexpected answer %q", w.Body.String())
+	}
+	close(release)
+	if w := <-done; w.Body.String() != `{"msg":"","response":true}` {
+		t.Fatalf("unexpected answer %q", w.Body.String())
+	}
+	if calls != 1 {
+		t.Fatalf("expect one dispatch, got %d", calls)
+	}
+	if w := postNotice(h, notice); w.Body.String() != `{"msg":"duplicate","response":true}` {
+		t.Fatalf("unexpected answer %q", w.Body.String())
+	}
+}
+
+func TestMemoryDedupeStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryDedupeStore(time.Minute)
+	s.now = func() time.Time { return now }
+	for i := 0; i < minSweepSize; i++ {
+		s.Add(ctx, strconv.Itoa(i))
+	}
+	// 达到清理阈值前不扫描，过期的ID之后批量删除
+	now = now.Add(2 * time.Minute)
+	s.Add(ctx, "new")
+	if len(s.ids) != 1 {
+		t.Fatalf("expect expired ids swept, %d left", len(s.ids))
+	}
+	if state, _ := s.Reserve(ctx, "0"); state != NoticeReserved {
+		t.Fatalf("expect expired id reserved, got %v", state)
+	}
+	if state, _ := s.Reserve(ctx, "new"); state != NoticeProcessed {
+		t.Fatalf("expect processed id, got %v", state)
+	}
+}
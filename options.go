@@ -0,0 +1,153 @@
+package goyht
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/leesper/holmes"
+)
+
+// Option configures a Client created by NewClient.
+type Option func(*Client)
+
+// Logger is the logging interface used by Client, holmes is used by default.
+type Logger interface {
+	Debugln(args ...interface{})
+	Errorln(args ...interface{})
+}
+
+type holmesLogger struct{}
+
+func (holmesLogger) Debugln(args ...interface{}) { holmes.Debugln(args...) }
+func (holmesLogger) Errorln(args ...interface{}) { holmes.Errorln(args...) }
+
+// TokenRefresher 获取平台长效令牌，客户端定期调用以刷新令牌
+type TokenRefresher interface {
+	RefreshToken(ctx context.Context) (string, error)
+}
+
+// platformLogin 默认的令牌刷新方式，使用AppID和AppKey登录
+type platformLogin struct {
+	c *Client
+}
+
+// RefreshToken .
+func (p platformLogin) RefreshToken(ctx context.Context) (string, error) {
+	_, ltt, err := p.c.login(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	return ltt, nil
+}
+
+// WithAPIGateway sets the API gateway, YHTAPIGatewayV4 by default.
+func WithAPIGateway(gateway string) Option {
+	return func(c *Client) {
+		c.config.APIGateway = gateway
+	}
+}
+
+// WithAuthGateway sets the real-name authentication gateway, YHTAuthGateway by default.
+func WithAuthGateway(gateway string) Option {
+	return func(c *Client) {
+		c.config.AuthGateway = gateway
+	}
+}
+
+// WithHTTPClient sets the http.Client used to send requests.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		if hc != nil {
+			c.tlsClient = hc
+		}
+	}
+}
+
+// WithLogger sets the logger.
+func WithLogger(l Logger) Option {
+	return func(c *Client) {
+		if l != nil {
+			c.logger = l
+		}
+	}
+}
+
+// WithTokenRefreshInterval sets the interval of refreshing the platform long
+// time token, 14 minutes by default. A random jitter is applied to it.
+func WithTokenRefreshInterval(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.interval = d
+		}
+	}
+}
+
+// WithTokenRefresher sets how the platform long time token is obtained,
+// a nil refresher disables the background refreshing.
+func WithTokenRefresher(r TokenRefresher) Option {
+	return func(c *Client) {
+		c.refresher = r
+	}
+}
+
+// WithUserTokenCache sets the capacity and lifetime of the per-signer token
+// cache used by UserTokenV4, a non-positive size disables the cache.
+func WithUserTokenCache(size int, ttl time.Duration) Option {
+	return func(c *Client) {
+		if size <= 0 {
+			c.userToks = nil
+			return
+		}
+		if ttl <= 0 {
+			ttl = defaultUserTokenTTL
+		}
+		c.userToks = newUserTokenCache(size, ttl)
+	}
+}
+
+// WithRetryPolicy sets the retry policy, DefaultRetryPolicy by default.
+// Use RetryPolicy{} to disable retries.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// WithMaxDownloadSize limits the size of downloaded contract files, downloads
+// exceeding n bytes fail with ErrTooLarge. There is no limit by default.
+func WithMaxDownloadSize(n int64) Option {
+	return func(c *Client) {
+		c.maxDownload = n
+	}
+}
+
+// WithValidation turns the local validation of request parameters on or
+// off, it is on by default. Invalid parameters fail with a *ValidationError
+// before any request is sent.
+func WithValidation(enabled bool) Option {
+	return func(c *Client) {
+		c.validation = enabled
+	}
+}
+
+// WithTemplateRegistry validates the contract data of templates registered
+// in r before a contract is created from them, see TemplateSchema.Validate.
+// Templates missing from r are not checked.
+func WithTemplateRegistry(r *TemplateRegistry) Option {
+	return func(c *Client) {
+		c.templates = r
+	}
+}
+
+// WithDuplicateUserCodes sets the response codes (code or subCode) that mean
+// the user to create already exists, such errors satisfy IsDuplicateUser.
+// Without them only the messages of the user creation APIs are checked.
+func WithDuplicateUserCodes(codes ...int) Option {
+	return func(c *Client) {
+		c.dupUserCodes = make(map[int]bool, len(codes))
+		for _, code := range codes {
+			c.dupUserCodes[code] = true
+		}
+	}
+}
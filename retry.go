@@ -0,0 +1,97 @@
+package goyht
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy 请求重试策略
+type RetryPolicy struct {
+	MaxAttempts int                              // 最大尝试次数（含首次请求），不大于1时不重试
+	BaseDelay   time.Duration                    // 首次重试前的等待时间，之后每次加倍，不小于MinRetryDelay
+	MaxDelay    time.Duration                    // 最长等待时间，不大于0时不限制
+	RetryOn     func(status int, err error) bool // 判断可否重试，err为网络错误的*APIError，nil时使用默认判断
+}
+
+// MinRetryDelay 重试和轮询的最短等待时间，避免BaseDelay或MaxDelay为0时连续请求
+const MinRetryDelay = 10 * time.Millisecond
+
+// DefaultRetryPolicy 默认重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+}
+
+// idempotentURIs 可安全重复请求的接口，其它接口只在请求尚未发出时重试
+var idempotentURIs = map[string]bool{
+	"/auth/login":                true,
+	"/user/signerId/certifyNums": true,
+	"/user/moulages":             true,
+	"/contract/detail":           true,
+	"/contract/list":             true,
+	"/contract/download":         true,
+}
+
+// retryable 判断请求是否可以重试
+func (p RetryPolicy) retryable(uri string, status int, err error) bool {
+	if !idempotentURIs[uri] {
+		return isUnsent(err)
+	}
+	if p.RetryOn != nil {
+		return p.RetryOn(status, err)
+	}
+	return defaultRetryOn(status, err)
+}
+
+// defaultRetryOn 网络错误、限流及网关错误时重试
+func defaultRetryOn(status int, err error) bool {
+	if err != nil {
+		return IsRetryable(err)
+	}
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff 返回第attempt次重试前的等待时间，带随机抖动
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	if d < MinRetryDelay {
+		d = MinRetryDelay
+	}
+	for i := 1; i < attempt && d < math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d < MinRetryDelay {
+		d = MinRetryDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// isUnsent 判断错误是否发生在请求发出之前，此时任何请求都可以安全重试
+func isUnsent(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.unsent
+}
+
+// sleepCtx 等待d或ctx结束
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+package goyht
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(M{"code": 200, "msg": "ok"})
+	}))
+	defer srv.Close()
+
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	cli := NewClient(Config{}, WithAPIGateway(srv.URL), WithRetryPolicy(policy), WithTokenRefresher(staticToken("tok")))
+
+	// 查询接口可安全重试
+	if _, err := cli.QuerySignerID(&YhtQuerySignerIDReq{CertifyNumList: []string{"1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if hits != 3 {
+		t.Fatalf("expect 3 attempts, got %d", hits)
+	}
+
+	// 创建接口在请求已发出后不重试
+	atomic.StoreInt32(&hits, 0)
+	if _, err := cli.CreatePersonV4(&YhtCreatePersonReq{CertNum: "E12345678"}); err == nil {
+		t.Fatal("expect error")
+	}
+	if hits != 1 {
+		t.Fatalf("expect 1 attempt, got %d", hits)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	cases := []struct {
+		policy   RetryPolicy
+		attempt  int
+		min, max time.Duration
+	}{
+		{RetryPolicy{}, 1, MinRetryDelay / 2, MinRetryDelay},
+		{RetryPolicy{BaseDelay: -time.Second, MaxDelay: time.Nanosecond}, 3, MinRetryDelay / 2, MinRetryDelay},
+		{RetryPolicy{BaseDelay: 100 * time.Millisecond}, 3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{RetryPolicy{BaseDelay: time.Second, MaxDelay: 2 * time.Second}, 100, time.Second, 2 * time.Second},
+	}
+	for _, c := range cases {
+		if d := c.policy.backoff(c.attempt); d < c.min || d > c.max {
+			t.Errorf("%+v attempt %d: backoff %v not in [%v, %v]", c.policy, c.attempt, d, c.min, c.max)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package goyht
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/iotdog/goyht/validate"
+)
+
+// checkEnum 校验可选参数的取值，为空时使用平台默认值
+func checkEnum(field, value string, spec enumSpec) error {
+	if value == "" || spec.valid(value) {
+		return nil
+	}
+	return &ValidationError{Field: field, Err: fmt.Errorf("%w: %q is not one of %v", validate.ErrFormat, value, spec.codes())}
+}
+
+// ValidatePersonMoulage 校验个人印章参数
+func ValidatePersonMoulage(req *YhtCreatePersonMoulageReq) error {
+	if req.SignerID == "" {
+		return &ValidationError{Field: "signerId", Err: fmt.Errorf("%w: empty", validate.ErrFormat)}
+	}
+	return checkPersonMoulageStyle(req)
+}
+
+// checkPersonMoulageStyle 校验个人印章的边框、字体、颜色、模式和缩放比例
+func checkPersonMoulageStyle(req *YhtCreatePersonMoulageReq) error {
+	checks := []struct {
+		field, value string
+		spec         enumSpec
+	}{
+		{"borderType", string(req.BorderType), borderTypes},
+		{"fontFamily", string(req.FontFamily), fontFamilies},
+		{"color", string(req.FontColor), fontColors},
+		{"mode", string(req.Mode), moulageModes},
+		{"zoomCode", string(req.ZoomCode), zoomCodes},
+	}
+	for _, c := range checks {
+		if err := checkEnum(c.field, c.value, c.spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateCompanyMoulage 校验企业印章参数，防伪码须为13位数字
+func ValidateCompanyMoulage(req *YhtCreateCompanyMoulageReq) error {
+	if req.SignerID == "" {
+		return &ValidationError{Field: "signerId", Err: fmt.Errorf("%w: empty", validate.ErrFormat)}
+	}
+	return checkCompanyMoulageStyle(req)
+}
+
+// checkCompanyMoulageStyle 校验企业印章的防伪码、样式、颜色和模式
+func checkCompanyMoulageStyle(req *YhtCreateCompanyMoulageReq) error {
+	if req.KeyContent != "" {
+		ok := len(req.KeyContent) == 13
+		for i := 0; ok && i < len(req.KeyContent); i++ {
+			ok = req.KeyContent[i] >= '0' && req.KeyContent[i] <= '9'
+		}
+		if !ok {
+			return &ValidationError{Field: "keyContent", Err: fmt.Errorf("%w: anti-counterfeit code must be 13 digits", validate.ErrFormat)}
+		}
+	}
+	if err := checkEnum("styleType", string(req.StyleType), sealStyles); err != nil {
+		return err
+	}
+	if err := checkEnum("color", string(req.FontColor), fontColors); err != nil {
+		return err
+	}
+	return checkEnum("mode", string(req.Mode), moulageModes)
+}
+
+// 印章预览图的边长，单位像素
+const (
+	DefaultSealPreviewSize = 240  // 默认边长
+	MaxSealPreviewSize     = 1024 // 最大边长，避免分配过大的图片
+)
+
+// sealInks 印章颜色
+var sealInks = map[FontColor]color.NRGBA{
+	YHTMFontColorRed:   {0xD9, 0x1E, 0x18, 0xFF},
+	YHTMFontColorBlue:  {0x1A, 0x3F, 0xB8, 0xFF},
+	YHTMFontColorBlack: {0x20, 0x20, 0x20, 0xFF},
+}
+
+// sealZoom 个人印章缩放类型对应的比例
+var sealZoom = map[ZoomCode]float64{
+	YHTPMZoomCodeLarge:  1,
+	YHTPMZoomCodeNormal: 0.85,
+	YHTPMZoomCodeSmall:  0.7,
+}
+
+// digitGlyphs 3x5点阵数字，用于绘制防伪码
+var digitGlyphs = [10][5]string{
+	{"###", "#.#", "#.#", "#.#", "###"},
+	{".#.", "##.", ".#.", ".#.", "###"},
+	{"###", "..#", "###", "#..", "###"},
+	{"###", "..#", "###", "..#", "###"},
+	{"#.#", "#.#", "###", "..#", "..#"},
+	{"###", "#..", "###", "..#", "###"},
+	{"###", "#..", "###", "#.#", "###"},
+	{"###", "..#", "..#", "..#", "..#"},
+	{"###", "#.#", "###", "#.#", "###"},
+	{"###", "#.#", "###", "..#", "###"},
+}
+
+// sealCanvas 绘制印章的画布
+type sealCanvas struct {
+	img  *image.NRGBA
+	ink  color.NRGBA
+	size float64
+}
+
+func newSealCanvas(size int, colorCode FontColor, mode MoulageMode) *sealCanvas {
+	ink, ok := sealInks[colorCode]
+	if !ok {
+		ink = sealInks[YHTMFontColorRed]
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	switch mode {
+	case YHTMModeTransparent:
+	case YHTMModeSafe:
+		// 脱敏模式以半透明的颜色绘制
+		ink.A = 0x80
+	default:
+		for i := range img.Pix {
+			img.Pix[i] = 0xFF
+		}
+	}
+	return &sealCanvas{img: img, ink: ink, size: float64(size)}
+}
+
+// fill 填充满足inside的像素
+func (c *sealCanvas) fill(minX, minY, maxX, maxY float64, inside func(x, y float64) bool) {
+	b := c.img.Bounds()
+	for y := int(math.Max(minY, 0)); y <= int(math.Min(maxY, float64(b.Max.Y-1))); y++ {
+		for x := int(math.Max(minX, 0)); x <= int(math.Min(maxX, float64(b.Max.X-1))); x++ {
+			if inside(float64(x)+0.5, float64(y)+0.5) {
+				c.img.SetNRGBA(x, y, c.ink)
+			}
+		}
+	}
+}
+
+// ring 绘制椭圆环
+func (c *sealCanvas) ring(cx, cy, rx, ry, width float64) {
+	c.fill(cx-rx, cy-ry, cx+rx, cy+ry, func(x, y float64) bool {
+		outer := sq((x-cx)/rx) + sq((y-cy)/ry)
+		inner := sq((x-cx)/(rx-width)) + sq((y-cy)/(ry-width))
+		return outer <= 1 && inner >= 1
+	})
+}
+
+// rect 绘制实心矩形
+func (c *sealCanvas) rect(x0, y0, x1, y1 float64) {
+	c.fill(x0, y0, x1, y1, func(x, y float64) bool {
+		return x >= x0 && x <= x1 && y >= y0 && y <= y1
+	})
+}
+
+// frame 绘制矩形边框
+func (c *sealCanvas) frame(x0, y0, x1, y1, width float64) {
+	c.rect(x0, y0, x1, y0+width)
+	c.rect(x0, y1-width, x1, y1)
+	c.rect(x0, y0, x0+width, y1)
+	c.rect(x1-width, y0, x1, y1)
+}
+
+// glyph 以空心方块近似绘制一个字
+func (c *sealCanvas) glyph(cx, cy, size float64) {
+	h := size / 2
+	c.frame(cx-h, cy-h, cx+h, cy+h, math.Max(1, size/8))
+	c.rect(cx-h, cy-size/16, cx+h, cy+size/16)
+}
+
+// star 绘制五角星
+func (c *sealCanvas) star(cx, cy, r float64) {
+	pts := make([][2]float64, 10)
+	for i := range pts {
+		radius := r
+		if i%2 == 1 {
+			radius = r * 0.382
+		}
+		a := -math.Pi/2 + float64(i)*math.Pi/5
+		pts[i] = [2]float64{cx + radius*math.Cos(a), cy + radius*math.Sin(a)}
+	}
+	c.fill(cx-r, cy-r, cx+r, cy+r, func(x, y float64) bool {
+		in := false
+		for i, j := 0, len(pts)-1; i < len(pts); j, i = i, i+1 {
+			if (pts[i][1] > y) != (pts[j][1] > y) &&
+				x < (pts[j][0]-pts[i][0])*(y-pts[i][1])/(pts[j][1]-pts[i][1])+pts[i][0] {
+				in = !in
+			}
+		}
+		return in
+	})
+}
+
+// digits 以点阵绘制一行数字
+func (c *sealCanvas) digits(cx, cy float64, s string, dot float64) {
+	x := cx - float64(len(s)*4-1)*dot/2
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			for row, line := range digitGlyphs[r-'0'] {
+				for col, p := range line {
+					if p == '#' {
+						px, py := x+float64(col)*dot, cy-2.5*dot+float64(row)*dot
+						c.rect(px, py, px+dot, py+dot)
+					}
+				}
+			}
+		}
+		x += 4 * dot
+	}
+}
+
+func sq(v float64) float64 {
+	return v * v
+}
+
+// RenderSealPreview 根据创建印章的请求离线绘制印章的近似预览图，以PNG格式写入w。
+// req为*YhtCreatePersonMoulageReq或*YhtCreateCompanyMoulageReq，name为印章上的姓名或企业名称，
+// size为图片边长，不大于0时使用DefaultSealPreviewSize，大于MaxSealPreviewSize时返回ErrInvalidParam。
+// 预览用于注册印章之前，不要求SignerID；企业印章须有防伪码。
+//
+// 标准库没有中文字体，姓名和横向文案的每个字以方块表示，防伪码以点阵数字绘制
+func RenderSealPreview(w io.Writer, req interface{}, name string, size int) error {
+	if size <= 0 {
+		size = DefaultSealPreviewSize
+	}
+	if size > MaxSealPreviewSize {
+		return fmt.Errorf("%w: seal preview size %d exceeds %d", ErrInvalidParam, size, MaxSealPreviewSize)
+	}
+	var img image.Image
+	switch r := req.(type) {
+	case *YhtCreatePersonMoulageReq:
+		if err := checkPersonMoulageStyle(r); err != nil {
+			return err
+		}
+		img = renderPersonSeal(r, name, size)
+	case *YhtCreateCompanyMoulageReq:
+		if r.KeyContent == "" {
+			return &ValidationError{Field: "keyContent", Err: fmt.Errorf("%w: empty", validate.ErrFormat)}
+		}
+		if err := checkCompanyMoulageStyle(r); err != nil {
+			return err
+		}
+		img = renderCompanySeal(r, name, size)
+	default:
+		return fmt.Errorf("%w: unsupported seal request %T", ErrInvalidParam, req)
+	}
+	return png.Encode(w, img)
+}
+
+// renderPersonSeal 个人印章：方形，姓名按两列排列
+func renderPersonSeal(req *YhtCreatePersonMoulageReq, name string, size int) image.Image {
+	c := newSealCanvas(size, req.FontColor, req.Mode)
+	zoom, ok := sealZoom[req.ZoomCode]
+	if !ok {
+		zoom = sealZoom[YHTPMZoomCodeNormal]
+	}
+	side := c.size * 0.9 * zoom
+	x0, y0 := (c.size-side)/2, (c.size-side)/2
+	if req.BorderType != YHTPMWithoutBorder {
+		c.frame(x0, y0, x0+side, y0+side, side/16)
+	}
+
+	chars := []rune(name)
+	if len(chars) == 0 {
+		return c.img
+	}
+	cols := 1
+	if len(chars) > 2 {
+		cols = 2
+	}
+	rows := (len(chars) + cols - 1) / cols
+	cell := side * 0.8 / float64(rows)
+	if w := side * 0.8 / float64(cols); w < cell {
+		cell = w
+	}
+	// 印章从右往左竖排
+	for i := range chars {
+		col, row := cols-1-i/rows, i%rows
+		cx := c.size/2 + (float64(col)-float64(cols-1)/2)*cell
+		cy := c.size/2 + (float64(row)-float64(rows-1)/2)*cell
+		c.glyph(cx, cy, cell*0.8)
+	}
+	return c.img
+}
+
+// renderCompanySeal 企业印章：圆形或椭圆，名称沿上方弧线排列，中间为五角星，下方为横向文案和防伪码
+func renderCompanySeal(req *YhtCreateCompanyMoulageReq, name string, size int) image.Image {
+	c := newSealCanvas(size, req.FontColor, req.Mode)
+	cx, cy := c.size/2, c.size/2
+	rx, ry := c.size*0.47, c.size*0.47
+	if req.StyleType == YHTCMStyleTypeEllipse {
+		ry = c.size * 0.33
+	}
+	c.ring(cx, cy, rx, ry, c.size/30)
+	c.star(cx, cy, ry*0.32)
+
+	chars := []rune(name)
+	glyph := ry * 0.24
+	if n := len(chars); n > 0 {
+		// 名称分布在上方210度的弧线上
+		span := math.Pi * 7 / 6
+		for i := range chars {
+			a := -math.Pi/2 - span/2 + span*(float64(i)+0.5)/float64(n)
+			c.glyph(cx+(rx-glyph)*math.Cos(a), cy+(ry-glyph)*math.Sin(a), glyph*0.8)
+		}
+	}
+	if text := []rune(req.TextContent); len(text) > 0 {
+		small := glyph * 0.8
+		for i := range text {
+			c.glyph(cx+(float64(i)-float64(len(text)-1)/2)*small*1.1, cy+ry*0.5, small*0.9)
+		}
+	}
+	if req.KeyContent != "" {
+		c.digits(cx, cy+ry*0.8, req.KeyContent, math.Max(1, c.size/120))
+	}
+	return c.img
+}
@@ -0,0 +1,69 @@
+package goyht
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"testing"
+
+	"github.com/iotdog/goyht/validate"
+)
+
+func TestValidateMoulage(t *testing.T) {
+	if err := ValidatePersonMoulage(&YhtCreatePersonMoulageReq{SignerID: "1", FontFamily: "F9"}); !errors.Is(err, validate.ErrFormat) {
+		t.Fatalf("expect invalid font, got %v", err)
+	}
+	err := ValidateCompanyMoulage(&YhtCreateCompanyMoulageReq{SignerID: "1", KeyContent: "12345"})
+	var vErr *ValidationError
+	if !errors.As(err, &vErr) || vErr.Field != "keyContent" {
+		t.Fatalf("expect invalid key content, got %v", err)
+	}
+}
+
+func TestRenderSealPreview(t *testing.T) {
+	var buf bytes.Buffer
+	// 预览不要求SignerID
+	req := &YhtCreateCompanyMoulageReq{StyleType: YHTCMStyleTypeCircle, TextContent: "合同专用章", KeyContent: "1234567890123", FontColor: YHTMFontColorBlue}
+	if err := RenderSealPreview(&buf, req, "某某科技有限公司", 200); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 圆环和五角星中心使用印章颜色，四角为白色背景
+	for _, p := range [][2]int{{100, 9}, {100, 100}} {
+		if r, g, b, _ := img.At(p[0], p[1]).RGBA(); b>>8 != 0xB8 || r>>8 != 0x1A || g>>8 != 0x3F {
+			t.Fatalf("unexpected color at %v: %v", p, img.At(p[0], p[1]))
+		}
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r>>8 != 0xFF {
+		t.Fatalf("expect white background, got %v", img.At(0, 0))
+	}
+
+	buf.Reset()
+	person := &YhtCreatePersonMoulageReq{BorderType: YHTPMWithBorder, Mode: YHTMModeTransparent}
+	if err = RenderSealPreview(&buf, person, "李四", 0); err != nil {
+		t.Fatal(err)
+	}
+	if img, err = png.Decode(&buf); err != nil || img.Bounds().Dx() != DefaultSealPreviewSize {
+		t.Fatalf("unexpected person seal %v", err)
+	}
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Fatal("expect transparent background")
+	}
+	if err = RenderSealPreview(&buf, "seal", "", 0); !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("expect invalid param, got %v", err)
+	}
+	if err = RenderSealPreview(&buf, person, "李四", MaxSealPreviewSize+1); !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("expect oversized preview rejected, got %v", err)
+	}
+	if err = RenderSealPreview(&buf, &YhtCreatePersonMoulageReq{ZoomCode: "9"}, "李四", 0); !errors.Is(err, validate.ErrFormat) {
+		t.Fatalf("expect invalid zoom code rejected, got %v", err)
+	}
+	req.KeyContent = ""
+	var vErr *ValidationError
+	if err = RenderSealPreview(&buf, req, "某某科技有限公司", 0); !errors.As(err, &vErr) || vErr.Field != "keyContent" {
+		t.Fatalf("expect empty key content rejected, got %v", err)
+	}
+}
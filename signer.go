@@ -0,0 +1,221 @@
+package goyht
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/iotdog/goyht/validate"
+)
+
+// FormatCoordinates 返回坐标定位的PositionContent，格式为"页码,x,y"，
+// 页码从1开始，x、y为页面左上角起的坐标
+func FormatCoordinates(page int, x, y float64) string {
+	return strconv.Itoa(page) + "," +
+		strconv.FormatFloat(x, 'f', -1, 64) + "," +
+		strconv.FormatFloat(y, 'f', -1, 64)
+}
+
+// ParseCoordinates 解析坐标定位的PositionContent，见FormatCoordinates
+func ParseCoordinates(s string) (page int, x, y float64, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("coordinates %q should be \"page,x,y\"", s)
+	}
+	if page, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil || page < 1 {
+		return 0, 0, 0, fmt.Errorf("coordinates %q: page should be a positive integer", s)
+	}
+	if x, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil || x < 0 {
+		return 0, 0, 0, fmt.Errorf("coordinates %q: x should be a non-negative number", s)
+	}
+	if y, err = strconv.ParseFloat(strings.TrimSpace(parts[2]), 64); err != nil || y < 0 {
+		return 0, 0, 0, fmt.Errorf("coordinates %q: y should be a non-negative number", s)
+	}
+	return page, x, y, nil
+}
+
+// ValidateSigner 校验签署者的定位方式和签署选项，未填写的选项由平台使用默认值
+func ValidateSigner(s YhtSigner) error {
+	switch s.SignPositionType {
+	case "":
+		if s.PositionContent != "" {
+			return &ValidationError{Field: "signPositionType", Err: fmt.Errorf("%w: required with positionContent", validate.ErrFormat)}
+		}
+	case YHTSignPositionTypeKeyWord, YHTSignPositionTypePlaceHolder:
+		if strings.TrimSpace(s.PositionContent) == "" {
+			return &ValidationError{Field: "positionContent", Err: fmt.Errorf("%w: keyword or placeholder is empty", validate.ErrFormat)}
+		}
+	case YHTSignPositionTypeCoord:
+		if _, _, _, err := ParseCoordinates(s.PositionContent); err != nil {
+			return &ValidationError{Field: "positionContent", Err: fmt.Errorf("%w: %v", validate.ErrFormat, err)}
+		}
+	default:
+		return &ValidationError{Field: "signPositionType", Err: fmt.Errorf("%w: unknown value %q", validate.ErrFormat, s.SignPositionType)}
+	}
+	if err := checkEnum("signValidateType", string(s.SignValidateType), signValidateTypes); err != nil {
+		return err
+	}
+	if err := checkEnum("signMode", string(s.SignMode), signModes); err != nil {
+		return err
+	}
+	return checkEnum("signForm", string(s.SignForm), signForms)
+}
+
+// SignerBuilder 逐步构建签署者，最后由Build生成YhtSigner或由Partner生成V3签署方。
+// 构建过程中的错误在Build或Partner时返回：
+//
+//	signer, err := goyht.NewSigner(signerID).AtCoordinates(1, 120, 560).WithSMSValidation().H5().Build()
+type SignerBuilder struct {
+	signer YhtSigner
+	err    error
+}
+
+// NewSigner 返回签署者的构建器，在工作流中使用时signerID可以为空
+func NewSigner(signerID string) *SignerBuilder {
+	return &SignerBuilder{signer: YhtSigner{SignerID: signerID}}
+}
+
+// at 设置定位方式，只能设置一次
+func (b *SignerBuilder) at(positionType SignPositionType, content string) *SignerBuilder {
+	if b.err != nil {
+		return b
+	}
+	if b.signer.SignPositionType != "" {
+		b.err = &ValidationError{Field: "signPositionType", Err: fmt.Errorf("%w: sign position is set twice", validate.ErrFormat)}
+		return b
+	}
+	b.signer.SignPositionType = positionType
+	b.signer.PositionContent = content
+	return b
+}
+
+// AtKeyword 按关键字定位签署位置
+func (b *SignerBuilder) AtKeyword(keyword string) *SignerBuilder {
+	return b.at(YHTSignPositionTypeKeyWord, keyword)
+}
+
+// AtPlaceholder 按模板中的签名占位符定位签署位置
+func (b *SignerBuilder) AtPlaceholder(name string) *SignerBuilder {
+	return b.at(YHTSignPositionTypePlaceHolder, name)
+}
+
+// AtCoordinates 按坐标定位签署位置，page从1开始
+func (b *SignerBuilder) AtCoordinates(page int, x, y float64) *SignerBuilder {
+	if b.err == nil && (page < 1 || x < 0 || y < 0) {
+		b.err = &ValidationError{
+			Field: "positionContent",
+			Err:   fmt.Errorf("%w: invalid coordinates page %d, x %v, y %v", validate.ErrFormat, page, x, y),
+		}
+	}
+	return b.at(YHTSignPositionTypeCoord, FormatCoordinates(page, x, y))
+}
+
+// WithSMSValidation 签署时需要短信验证
+func (b *SignerBuilder) WithSMSValidation() *SignerBuilder {
+	b.signer.SignValidateType = YHTSignValidateTypeSMS
+	return b
+}
+
+// DrawSignature 每次签署时手绘签名，默认使用指定印章
+func (b *SignerBuilder) DrawSignature() *SignerBuilder {
+	b.signer.SignMode = YHTSignModeRender
+	return b
+}
+
+// H5 使用独立H5页面签署，默认为JS集成页面
+func (b *SignerBuilder) H5() *SignerBuilder {
+	b.signer.SignForm = YHTSignFormH5
+	return b
+}
+
+// Build 校验并返回签署者，必须设置一种定位方式
+func (b *SignerBuilder) Build() (YhtSigner, error) {
+	if b.err != nil {
+		return YhtSigner{}, b.err
+	}
+	if b.signer.SignPositionType == "" {
+		return YhtSigner{}, &ValidationError{Field: "signPositionType", Err: fmt.Errorf("%w: sign position is not set", validate.ErrFormat)}
+	}
+	s := b.signer
+	if s.SignValidateType == "" {
+		s.SignValidateType = YHTSignValidateTypeIgnore
+	}
+	if s.SignMode == "" {
+		s.SignMode = YHTSignModeSpecify
+	}
+	if s.SignForm == "" {
+		s.SignForm = YHTSignFormJS
+	}
+	if err := ValidateSigner(s); err != nil {
+		return YhtSigner{}, err
+	}
+	return s, nil
+}
+
+// Partner 返回V3接口的签署方。V3接口只支持关键字和签名占位符定位，
+// 不支持坐标定位、短信验证、手绘签名和H5页面
+func (b *SignerBuilder) Partner(appUserID string) (Partner, error) {
+	s, err := b.Build()
+	if err != nil {
+		return Partner{}, err
+	}
+	if appUserID == "" {
+		return Partner{}, &ValidationError{Field: "appUserId", Err: fmt.Errorf("%w: empty", validate.ErrFormat)}
+	}
+	switch {
+	case s.SignValidateType != YHTSignValidateTypeIgnore:
+		err = fmt.Errorf("%w: SMS validation is not supported by partners", validate.ErrFormat)
+	case s.SignMode != YHTSignModeSpecify:
+		err = fmt.Errorf("%w: drawn signatures are not supported by partners", validate.ErrFormat)
+	case s.SignForm != YHTSignFormJS:
+		err = fmt.Errorf("%w: H5 signing is not supported by partners", validate.ErrFormat)
+	}
+	if err != nil {
+		return Partner{}, &ValidationError{Field: "signer", Err: err}
+	}
+	p := Partner{AppUserID: appUserID}
+	switch s.SignPositionType {
+	case YHTSignPositionTypeKeyWord:
+		p.Keyword = s.PositionContent
+	case YHTSignPositionTypePlaceHolder:
+		p.LocationName = s.PositionContent
+	default:
+		return Partner{}, &ValidationError{Field: "positionContent", Err: fmt.Errorf("%w: coordinates are not supported by partners", validate.ErrFormat)}
+	}
+	return p, nil
+}
+
+// idTypes 合同ID类型
+var idTypes = enumSpec{
+	{YHTIDTypeSystem, "云合同平台合同ID", "Platform contract ID"},
+	{YHTIDTypeCustom, "自定义合同编号", "Custom contract number"},
+}
+
+// NewAddSignerReq 构建添加签署者请求，idType为YHTIDTypeSystem或YHTIDTypeCustom，
+// 每个签署者都须填写SignerID
+func NewAddSignerReq(idType, idContent string, signers ...*SignerBuilder) (*YhtAddSignerReq, error) {
+	if err := checkEnum("idType", idType, idTypes); err != nil {
+		return nil, err
+	}
+	if idType == "" {
+		return nil, &ValidationError{Field: "idType", Err: fmt.Errorf("%w: empty", validate.ErrFormat)}
+	}
+	if idContent == "" {
+		return nil, &ValidationError{Field: "idContent", Err: fmt.Errorf("%w: empty", validate.ErrFormat)}
+	}
+	if len(signers) == 0 {
+		return nil, &ValidationError{Field: "signers", Err: fmt.Errorf("%w: empty", validate.ErrFormat)}
+	}
+	req := &YhtAddSignerReq{IDType: idType, IDContent: idContent}
+	for _, b := range signers {
+		s, err := b.Build()
+		if err != nil {
+			return nil, err
+		}
+		if s.SignerID == "" {
+			return nil, &ValidationError{Field: "signerId", Err: fmt.Errorf("%w: empty", validate.ErrFormat)}
+		}
+		req.Signers = append(req.Signers, s)
+	}
+	return req, nil
+}
@@ -0,0 +1,55 @@
+package goyht
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSignerBuilder(t *testing.T) {
+	req, err := NewAddSignerReq(YHTIDTypeSystem, "1001",
+		NewSigner("1").AtCoordinates(2, 120.5, 560).WithSMSValidation().H5(),
+		NewSigner("2").AtPlaceholder("56006"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(req)
+	want := `{"idType":"0","idContent":"1001","signers":[` +
+		`{"signerId":"1","signPositionType":"2","positionContent":"2,120.5,560","signValidateType":"1","signMode":"0","signForm":"1"},` +
+		`{"signerId":"2","signPositionType":"1","positionContent":"56006","signValidateType":"0","signMode":"0","signForm":"0"}]}`
+	if string(data) != want {
+		t.Fatalf("got %s", data)
+	}
+	if page, x, y, err := ParseCoordinates(req.Signers[0].PositionContent); err != nil || page != 2 || x != 120.5 || y != 560 {
+		t.Fatalf("ParseCoordinates = %d, %v, %v, %v", page, x, y, err)
+	}
+
+	p, err := NewSigner("").AtKeyword("乙方").Partner("user2")
+	if err != nil || p != (Partner{AppUserID: "user2", Keyword: "乙方"}) {
+		t.Fatalf("Partner = %+v, %v", p, err)
+	}
+
+	for name, b := range map[string]*SignerBuilder{
+		"no position":       NewSigner("1"),
+		"two positions":     NewSigner("1").AtKeyword("甲方").AtPlaceholder("56006"),
+		"bad coordinates":   NewSigner("1").AtCoordinates(0, 10, 10),
+		"empty placeholder": NewSigner("1").AtPlaceholder(" "),
+	} {
+		if _, err := b.Build(); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("%s: expect invalid param, got %v", name, err)
+		}
+	}
+	if _, err = NewSigner("1").AtCoordinates(1, 10, 10).Partner("user1"); err == nil {
+		t.Error("coordinates should not make a partner")
+	}
+	if _, err = NewSigner("1").AtKeyword("甲方").H5().Partner("user1"); err == nil {
+		t.Error("H5 should not make a partner")
+	}
+	if _, err = NewAddSignerReq(YHTIDTypeSystem, "1001", NewSigner("").AtKeyword("甲方")); err == nil {
+		t.Error("signer without id should fail")
+	}
+	if err = ValidateSigner(YhtSigner{SignPositionType: YHTSignPositionTypeCoord, PositionContent: "1;10;10"}); err == nil {
+		t.Error("malformed coordinates should fail")
+	}
+}
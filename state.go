@@ -0,0 +1,228 @@
+package goyht
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrInvalidTransition 合同或签署者状态不允许变更为目标状态
+var ErrInvalidTransition = errors.New("goyht: invalid status transition")
+
+// ContractStatus 合同状态
+type ContractStatus string
+
+// 合同状态
+const (
+	ContractDraft     ContractStatus = "0" // 草稿，尚未添加签署者
+	ContractSigning   ContractStatus = "1" // 签署中
+	ContractCompleted ContractStatus = "2" // 已完成
+	ContractInvalid   ContractStatus = "3" // 已作废
+)
+
+var contractStatuses = enumSpec{
+	{string(ContractDraft), "草稿", "Draft"},
+	{string(ContractSigning), "签署中", "Signing"},
+	{string(ContractCompleted), "已完成", "Completed"},
+	{string(ContractInvalid), "已作废", "Invalidated"},
+}
+
+// contractTransitions 合同状态允许变更到的状态
+var contractTransitions = map[ContractStatus][]ContractStatus{
+	ContractDraft:     {ContractSigning, ContractInvalid},
+	ContractSigning:   {ContractCompleted, ContractInvalid},
+	ContractCompleted: {ContractInvalid},
+}
+
+// Terminal reports whether s can not change any more.
+func (s ContractStatus) Terminal() bool {
+	return s == ContractInvalid
+}
+
+// CanTransitionTo reports whether a contract in s may become next directly.
+func (s ContractStatus) CanTransitionTo(next ContractStatus) bool {
+	for _, t := range contractTransitions[s] {
+		if t == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Reachable reports whether a contract in s may become target eventually.
+func (s ContractStatus) Reachable(target ContractStatus) bool {
+	if s == target {
+		return true
+	}
+	for _, t := range contractTransitions[s] {
+		if t.Reachable(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition returns an error wrapping ErrInvalidTransition if s can not become next.
+func (s ContractStatus) Transition(next ContractStatus) error {
+	if !s.CanTransitionTo(next) {
+		return fmt.Errorf("%w: contract %s -> %s", ErrInvalidTransition, s, next)
+	}
+	return nil
+}
+
+// SignStatus 签署者签署状态
+type SignStatus string
+
+// 签署者签署状态
+const (
+	SignPending  SignStatus = "0" // 待签署
+	SignSigned   SignStatus = "1" // 已签署
+	SignRejected SignStatus = "2" // 已拒签
+)
+
+var signStatuses = enumSpec{
+	{string(SignPending), "待签署", "Pending"},
+	{string(SignSigned), "已签署", "Signed"},
+	{string(SignRejected), "已拒签", "Rejected"},
+}
+
+// CanTransitionTo reports whether a signer in s may become next, only a
+// pending signer may sign or reject.
+func (s SignStatus) CanTransitionTo(next SignStatus) bool {
+	return s == SignPending && (next == SignSigned || next == SignRejected)
+}
+
+// Transition returns an error wrapping ErrInvalidTransition if s can not become next.
+func (s SignStatus) Transition(next SignStatus) error {
+	if !s.CanTransitionTo(next) {
+		return fmt.Errorf("%w: signer %s -> %s", ErrInvalidTransition, s, next)
+	}
+	return nil
+}
+
+// 等待合同状态时的默认轮询间隔
+const (
+	DefaultPollInterval    = time.Second
+	DefaultMaxPollInterval = 30 * time.Second
+)
+
+// waitOptions WaitForStatus的配置
+type waitOptions struct {
+	policy  RetryPolicy
+	handler *NotificationHandler
+}
+
+// WaitOption configures WaitForStatus.
+type WaitOption func(*waitOptions)
+
+// WithPollInterval polls first after base and doubles the interval up to max,
+// intervals shorter than MinRetryDelay are raised to it.
+func WithPollInterval(base, max time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.policy.BaseDelay, o.policy.MaxDelay = base, max
+	}
+}
+
+// WithNotifications polls again as soon as h receives a notification about the contract.
+func WithNotifications(h *NotificationHandler) WaitOption {
+	return func(o *waitOptions) {
+		o.handler = h
+	}
+}
+
+// WaitForStatus 轮询合同详情直到合同变为target状态。合同进入无法到达target的状态时返回
+// 包含ErrInvalidTransition的错误，ctx结束时返回ctx.Err()
+func (c *Client) WaitForStatus(ctx context.Context, contractID string, target ContractStatus, opts ...WaitOption) (*YhtContractDetail, error) {
+	o := waitOptions{policy: RetryPolicy{BaseDelay: DefaultPollInterval, MaxDelay: DefaultMaxPollInterval}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	wake := make(chan struct{}, 1)
+	if o.handler != nil {
+		cancel := o.handler.watch(func(ev Event) {
+			if ce, ok := contractEventOf(ev); ok && ce.ContractID == contractID {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+		})
+		defer cancel()
+	}
+
+	req := &YhtContractDetailReq{IDType: YHTIDTypeSystem, IDContent: contractID}
+	for attempt := 1; ; attempt++ {
+		rsp, err := c.LookupContractDetailV4Ctx(ctx, req)
+		if err != nil && !IsRetryable(err) {
+			return nil, err
+		}
+		if err == nil {
+			status := rsp.Data.Status
+			if status == target {
+				return &rsp.Data, nil
+			}
+			if !status.Reachable(target) {
+				return &rsp.Data, fmt.Errorf("%w: contract %s is %s, waiting for %s", ErrInvalidTransition, contractID, status, target)
+			}
+		}
+
+		timer := time.NewTimer(o.policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// contractEventOf 返回合同相关事件的公共字段
+func contractEventOf(ev Event) (*ContractEvent, bool) {
+	switch e := ev.(type) {
+	case *ContractSignedEvent:
+		return &e.ContractEvent, true
+	case *ContractCompletedEvent:
+		return &e.ContractEvent, true
+	case *ContractInvalidatedEvent:
+		return &e.ContractEvent, true
+	case *SignerRejectedEvent:
+		return &e.ContractEvent, true
+	}
+	return nil, false
+}
+
+// watchers 收到通知时调用的观察者
+type watchers struct {
+	mu   sync.Mutex
+	next int
+	fns  map[int]func(Event)
+}
+
+// watch 注册fn，每个通知分发前调用fn，返回取消注册的函数
+func (h *NotificationHandler) watch(fn func(Event)) func() {
+	h.watchers.mu.Lock()
+	defer h.watchers.mu.Unlock()
+	if h.watchers.fns == nil {
+		h.watchers.fns = map[int]func(Event){}
+	}
+	id := h.watchers.next
+	h.watchers.next++
+	h.watchers.fns[id] = fn
+	return func() {
+		h.watchers.mu.Lock()
+		defer h.watchers.mu.Unlock()
+		delete(h.watchers.fns, id)
+	}
+}
+
+func (h *NotificationHandler) notifyWatchers(ev Event) {
+	h.watchers.mu.Lock()
+	defer h.watchers.mu.Unlock()
+	for _, fn := range h.watchers.fns {
+		fn(ev)
+	}
+}
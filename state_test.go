@@ -0,0 +1,30 @@
+package goyht
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestContractStatusTransition(t *testing.T) {
+	if !ContractDraft.CanTransitionTo(ContractSigning) || ContractCompleted.CanTransitionTo(ContractSigning) {
+		t.Fatal("unexpected contract transitions")
+	}
+	if !ContractDraft.Reachable(ContractCompleted) || ContractInvalid.Reachable(ContractCompleted) {
+		t.Fatal("unexpected reachable status")
+	}
+	if err := ContractInvalid.Transition(ContractDraft); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expect invalid transition, got %v", err)
+	}
+	if err := SignSigned.Transition(SignRejected); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expect invalid transition, got %v", err)
+	}
+	if ContractCompleted.String() != "2" || ContractCompleted.Label() != "已完成" || ContractStatus("9").Label() != "未知(9)" || !ContractSigning.Valid() || SignStatus("9").Valid() {
+		t.Fatalf("unexpected label %s", ContractCompleted)
+	}
+	var detail YhtContractDetail
+	err := json.Unmarshal([]byte(`{"status":2,"signers":[{"signStatus":"Rejected"}]}`), &detail)
+	if err != nil || detail.Status != ContractCompleted || detail.Signers[0].SignStatus != SignRejected {
+		t.Fatalf("unexpected detail %+v %v", detail, err)
+	}
+}
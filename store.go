@@ -0,0 +1,508 @@
+package goyht
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DeadLetter 处理失败的异步通知
+type DeadLetter struct {
+	ID          string          `json:"id"`
+	Notice      json.RawMessage `json:"notice"` // 通知的原始JSON
+	Err         string          `json:"err"`    // 最近一次处理失败的原因
+	Attempts    int             `json:"attempts"`
+	FirstFailed time.Time       `json:"firstFailed"`
+	LastFailed  time.Time       `json:"lastFailed"`
+}
+
+// NotificationStore 记录已处理的通知ID，并保存处理失败的通知
+type NotificationStore interface {
+	DedupeStore
+	// Fail records a failed attempt to process notice.
+	Fail(ctx context.Context, id string, notice []byte, cause error) error
+	// DeadLetters returns the failed notices ordered by first failure.
+	DeadLetters(ctx context.Context) ([]DeadLetter, error)
+	// Remove deletes the dead letter with id.
+	Remove(ctx context.Context, id string) error
+}
+
+// WithNotificationStore records processed and failed notifications in store,
+// which also replaces the DedupeStore.
+func WithNotificationStore(store NotificationStore) NotifyOption {
+	return func(h *NotificationHandler) {
+		h.store = store
+		h.dedupe = store
+	}
+}
+
+// MemoryNotificationStore 内存中的NotificationStore，已处理的通知ID在ttl后过期
+type MemoryNotificationStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	processed map[string]time.Time
+	sweepAt   int                 // processed达到此数量时清理过期的ID
+	pending   map[string]struct{} // 正在处理的通知ID，不保存
+	dead      map[string]*DeadLetter
+	now       func() time.Time
+	record    func(rec storeRecord) error // 修改后调用，调用时持有锁
+}
+
+// NewMemoryNotificationStore returns a MemoryNotificationStore keeping
+// processed ids for ttl, DefaultDedupeTTL if ttl is not positive.
+func NewMemoryNotificationStore(ttl time.Duration) *MemoryNotificationStore {
+	if ttl <= 0 {
+		ttl = DefaultDedupeTTL
+	}
+	return &MemoryNotificationStore{
+		ttl:       ttl,
+		processed: map[string]time.Time{},
+		pending:   map[string]struct{}{},
+		dead:      map[string]*DeadLetter{},
+		now:       time.Now,
+	}
+}
+
+// Reserve implements DedupeStore. Reservations are kept in memory only, so a
+// notice being processed when the process exits is delivered again.
+func (s *MemoryNotificationStore) Reserve(ctx context.Context, id string) (NoticeState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if expiry, ok := s.processed[id]; ok && s.now().Before(expiry) {
+		return NoticeProcessed, nil
+	}
+	if _, ok := s.pending[id]; ok {
+		return NoticePending, nil
+	}
+	s.pending[id] = struct{}{}
+	return NoticeReserved, nil
+}
+
+// Release implements DedupeStore.
+func (s *MemoryNotificationStore) Release(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, id)
+	return nil
+}
+
+// Add implements DedupeStore, it also removes the dead letter with id.
+func (s *MemoryNotificationStore) Add(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	sweepExpired(s.processed, now, &s.sweepAt)
+	expiry := now.Add(s.ttl)
+	s.processed[id] = expiry
+	delete(s.pending, id)
+	delete(s.dead, id)
+	return s.persist(storeRecord{Op: opProcessed, ID: id, Expiry: expiry.Unix()})
+}
+
+// Fail implements NotificationStore.
+func (s *MemoryNotificationStore) Fail(ctx context.Context, id string, notice []byte, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	d, ok := s.dead[id]
+	if !ok {
+		d = &DeadLetter{ID: id, Notice: append(json.RawMessage(nil), notice...), FirstFailed: now}
+		s.dead[id] = d
+	}
+	d.Attempts++
+	d.LastFailed = now
+	if cause != nil {
+		d.Err = cause.Error()
+	}
+	letter := *d
+	return s.persist(storeRecord{Op: opFailed, ID: id, Letter: &letter})
+}
+
+// DeadLetters implements NotificationStore.
+func (s *MemoryNotificationStore) DeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deadLetters(), nil
+}
+
+func (s *MemoryNotificationStore) deadLetters() []DeadLetter {
+	letters := make([]DeadLetter, 0, len(s.dead))
+	for _, d := range s.dead {
+		letters = append(letters, *d)
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		if letters[i].FirstFailed.Equal(letters[j].FirstFailed) {
+			return letters[i].ID < letters[j].ID
+		}
+		return letters[i].FirstFailed.Before(letters[j].FirstFailed)
+	})
+	return letters
+}
+
+// Remove implements NotificationStore.
+func (s *MemoryNotificationStore) Remove(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.dead[id]; !ok {
+		return nil
+	}
+	delete(s.dead, id)
+	return s.persist(storeRecord{Op: opRemoved, ID: id})
+}
+
+func (s *MemoryNotificationStore) persist(rec storeRecord) error {
+	if s.record == nil {
+		return nil
+	}
+	return s.record(rec)
+}
+
+// apply 将日志中的记录应用到内存状态
+func (s *MemoryNotificationStore) apply(rec storeRecord) error {
+	switch rec.Op {
+	case opProcessed:
+		s.processed[rec.ID] = time.Unix(rec.Expiry, 0)
+		delete(s.dead, rec.ID)
+	case opFailed:
+		if rec.Letter == nil {
+			return fmt.Errorf("failed record %s without dead letter", rec.ID)
+		}
+		s.dead[rec.ID] = rec.Letter
+	case opRemoved:
+		delete(s.dead, rec.ID)
+	default:
+		return fmt.Errorf("unknown record op %q", rec.Op)
+	}
+	return nil
+}
+
+// 日志记录的操作
+const (
+	opProcessed = "processed"
+	opFailed    = "failed"
+	opRemoved   = "removed"
+)
+
+// storeRecord FileNotificationStore日志中的一行
+type storeRecord struct {
+	Op     string      `json:"op"`
+	ID     string      `json:"id"`
+	Expiry int64       `json:"expiry,omitempty"` // processed的过期时间，Unix秒
+	Letter *DeadLetter `json:"letter,omitempty"` // failed后的死信
+}
+
+// defaultCompactRecords 日志记录数少于此值时不压缩
+const defaultCompactRecords = 1024
+
+// ErrStoreLocked 通知存储文件已被其他进程打开
+var ErrStoreLocked = errors.New("goyht: notification store is locked")
+
+// FileNotificationStore 保存在本地文件中的NotificationStore。文件是只追加的日志，
+// 每次修改追加一行JSON记录并同步到磁盘，写入的开销与已有的记录数无关。
+// 记录数超过存活记录（未过期的已处理ID和死信）的两倍时，以写临时文件再重命名的方式
+// 重写文件，去掉过期和被覆盖的记录。崩溃时写了一半的最后一行在打开时丢弃。
+// 打开期间独占锁定同目录下的path.lock文件，同一文件只能由一个FileNotificationStore使用
+type FileNotificationStore struct {
+	*MemoryNotificationStore
+	path       string
+	lock       *os.File
+	file       *os.File
+	size       int64 // 已完整写入的字节数
+	records    int   // 文件中的记录数
+	minCompact int
+}
+
+// OpenFileNotificationStore loads the store saved at path, or creates an
+// empty one if path does not exist. The store is locked until Close, opening
+// a locked store fails with ErrStoreLocked.
+func OpenFileNotificationStore(path string, ttl time.Duration) (*FileNotificationStore, error) {
+	lock, err := lockFile(path + ".lock")
+	if err == ErrStoreLocked {
+		return nil, fmt.Errorf("%w: %s", ErrStoreLocked, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s, err := openFileNotificationStore(path, ttl)
+	if err != nil {
+		unlockFile(lock)
+		return nil, err
+	}
+	s.lock = lock
+	return s, nil
+}
+
+func openFileNotificationStore(path string, ttl time.Duration) (*FileNotificationStore, error) {
+	s := &FileNotificationStore{
+		MemoryNotificationStore: NewMemoryNotificationStore(ttl),
+		path:                    path,
+		minCompact:              defaultCompactRecords,
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	truncated, err := s.load(data)
+	if err != nil {
+		return nil, err
+	}
+	if truncated || s.needCompact() {
+		err = s.compact()
+	} else {
+		err = s.openLog()
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.record = s.write
+	return s, nil
+}
+
+// ReadDeadLetters returns the dead letters of the store saved at path. The
+// file is neither locked nor changed, so it can be read while a service has
+// the store open.
+func ReadDeadLetters(path string) ([]DeadLetter, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileNotificationStore{MemoryNotificationStore: NewMemoryNotificationStore(0), path: path}
+	if _, err = s.load(data); err != nil {
+		return nil, err
+	}
+	return s.deadLetters(), nil
+}
+
+// load 应用日志中的记录，返回最后一行是否未写完
+func (s *FileNotificationStore) load(data []byte) (bool, error) {
+	for line := 1; len(data) > 0; line++ {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			// 崩溃时或正在写入的最后一行
+			return true, nil
+		}
+		var rec storeRecord
+		err := json.Unmarshal(data[:i], &rec)
+		if err == nil {
+			err = s.apply(rec)
+		}
+		if err != nil {
+			return false, fmt.Errorf("goyht: corrupted notification store %s line %d: %v", s.path, line, err)
+		}
+		s.records++
+		s.size += int64(i + 1)
+		data = data[i+1:]
+	}
+	return false, nil
+}
+
+// Close closes the file of the store and releases the lock.
+func (s *FileNotificationStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	if lerr := unlockFile(s.lock); err == nil {
+		err = lerr
+	}
+	return err
+}
+
+func (s *FileNotificationStore) openLog() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.file = f
+	return nil
+}
+
+// write 追加一行记录，调用时须持有锁
+func (s *FileNotificationStore) write(rec storeRecord) error {
+	if s.file == nil {
+		return fmt.Errorf("goyht: notification store %s is closed", s.path)
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err = s.file.Write(data); err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		// 去掉写了一半的记录，避免之后的记录接在它后面
+		s.file.Truncate(s.size)
+		return err
+	}
+	s.size += int64(len(data))
+	s.records++
+	if s.needCompact() {
+		return s.compact()
+	}
+	return nil
+}
+
+func (s *FileNotificationStore) needCompact() bool {
+	return s.records > s.minCompact && s.records > 2*(len(s.processed)+len(s.dead))
+}
+
+// compact 重写文件，只保留未过期的已处理ID和死信，调用时须持有锁
+func (s *FileNotificationStore) compact() error {
+	now := s.now()
+	var buf bytes.Buffer
+	records := 0
+	add := func(rec storeRecord) error {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+		records++
+		return nil
+	}
+	for id, expiry := range s.processed {
+		if !now.Before(expiry) {
+			delete(s.processed, id)
+			continue
+		}
+		if err := add(storeRecord{Op: opProcessed, ID: id, Expiry: expiry.Unix()}); err != nil {
+			return err
+		}
+	}
+	for _, d := range s.deadLetters() {
+		d := d
+		if err := add(storeRecord{Op: opFailed, ID: d.ID, Letter: &d}); err != nil {
+			return err
+		}
+	}
+	if err := writeFileAtomic(s.path, buf.Bytes()); err != nil {
+		return err
+	}
+	s.size, s.records = int64(buf.Len()), records
+	return s.openLog()
+}
+
+// writeFileAtomic 先写入同目录下的临时文件再重命名，避免崩溃时留下不完整的文件
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// DeadLetters returns the dead letters in the NotificationStore of h.
+func (h *NotificationHandler) DeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	if h.store == nil {
+		return nil, nil
+	}
+	return h.store.DeadLetters(ctx)
+}
+
+// Replay dispatches the dead letter with id again and removes it on success.
+func (h *NotificationHandler) Replay(ctx context.Context, id string) error {
+	letters, err := h.DeadLetters(ctx)
+	if err != nil {
+		return err
+	}
+	for _, d := range letters {
+		if d.ID == id {
+			return h.replay(ctx, d)
+		}
+	}
+	return fmt.Errorf("%w: dead letter %s not found", ErrInvalidParam, id)
+}
+
+// ReplayAll replays every dead letter in order and returns the number of
+// notices processed successfully, the error is the last failure.
+func (h *NotificationHandler) ReplayAll(ctx context.Context) (int, error) {
+	letters, err := h.DeadLetters(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, d := range letters {
+		if ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+		if e := h.replay(ctx, d); e != nil {
+			err = e
+			continue
+		}
+		n++
+	}
+	return n, err
+}
+
+func (h *NotificationHandler) replay(ctx context.Context, d DeadLetter) error {
+	ev, err := ParseNotice(d.Notice)
+	if err != nil {
+		return err
+	}
+	if h.dedupe != nil {
+		state, err := h.dedupe.Reserve(ctx, d.ID)
+		if err != nil {
+			return err
+		}
+		switch state {
+		case NoticeProcessed:
+			// 云合同重发的通知已处理成功
+			return h.store.Remove(ctx, d.ID)
+		case NoticePending:
+			return fmt.Errorf("goyht: notice %s is being processed", d.ID)
+		}
+	}
+	return h.process(ctx, d.ID, ev)
+}
+
+// process 分发已占用ID的通知，成功时记录通知已处理，失败时保存到死信队列后释放占用。
+// 释放前保存死信，避免重发的通知处理成功并删除死信后又被写入。
+// 死信或处理记录写入失败时返回error，应答云合同处理失败，由云合同重发通知
+func (h *NotificationHandler) process(ctx context.Context, id string, ev Event) error {
+	if err := h.Dispatch(ctx, ev); err != nil {
+		if h.store != nil {
+			if ferr := h.store.Fail(ctx, id, ev.Base().Raw, err); ferr != nil {
+				err = fmt.Errorf("%w; dead letter %s not saved: %v", err, id, ferr)
+			}
+		}
+		if h.dedupe != nil {
+			if rerr := h.dedupe.Release(ctx, id); rerr != nil {
+				err = fmt.Errorf("%w; release notice %s: %v", err, id, rerr)
+			}
+		}
+		return err
+	}
+	// 记录失败时云合同会重发通知，回调须能处理重复的通知
+	if h.dedupe != nil {
+		if err := h.dedupe.Add(ctx, id); err != nil {
+			return fmt.Errorf("goyht: record processed notice %s: %w", id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package goyht
+
+import "os"
+
+// lockFile 以独占创建的方式锁定path，文件已存在时返回ErrStoreLocked。
+// 进程崩溃后锁文件不会自动删除，确认没有进程使用后须手动删除
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return nil, ErrStoreLocked
+	}
+	return f, err
+}
+
+// unlockFile 释放lockFile取得的锁
+func unlockFile(f *os.File) error {
+	err := f.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
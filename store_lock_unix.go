@@ -0,0 +1,31 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package goyht
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile 以flock独占锁定path，锁已被其他进程或其他打开的文件持有时返回ErrStoreLocked。
+// 进程退出时锁自动释放
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrStoreLocked
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// unlockFile 释放lockFile取得的锁
+func unlockFile(f *os.File) error {
+	return f.Close()
+}
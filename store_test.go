@@ -0,0 +1,188 @@
+package goyht
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNotificationStoreReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "notices.json")
+	store, err := OpenFileNotificationStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fail := true
+	calls := 0
+	h := NewNotificationHandler(WithNotificationStore(store))
+	h.OnContractCompleted(func(ctx context.Context, ev *ContractCompletedEvent) error {
+		calls++
+		if fail {
+			return errors.New("db down")
+		}
+		return nil
+	})
+
+	notice := `{"noticeType":2,"map":{"noticeId":"n1","contractId":"1001"}}`
+	if w := postNotice(h, notice); w.Code != http.StatusOK || w.Body.String() != `{"msg":"db down","response":false}` {
+		t.Fatalf("unexpected answer %d %q", w.Code, w.Body.String())
+	}
+	postNotice(h, notice)
+	store.Close()
+
+	// 重新打开文件后死信仍然存在
+	store, err = OpenFileNotificationStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	h.store, h.dedupe = store, store
+	letters, err := h.DeadLetters(ctx)
+	if err != nil || len(letters) != 1 || letters[0].ID != "n1" || letters[0].Attempts != 2 || letters[0].Err != "db down" {
+		t.Fatalf("unexpected dead letters %+v %v", letters, err)
+	}
+
+	fail = false
+	if n, err := h.ReplayAll(ctx); n != 1 || err != nil {
+		t.Fatalf("unexpected replay %d %v", n, err)
+	}
+	if letters, _ = h.DeadLetters(ctx); len(letters) != 0 {
+		t.Fatalf("expect empty dead letters, got %+v", letters)
+	}
+	postNotice(h, notice)
+	if calls != 3 {
+		t.Fatalf("expect processed notice skipped, got %d calls", calls)
+	}
+	if err = h.Replay(ctx, "n1"); !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("expect not found, got %v", err)
+	}
+}
+
+func TestFileNotificationStoreCompact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "notices.log")
+	store, err := OpenFileNotificationStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.minCompact = 8
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("n%d", i%3)
+		if err = store.Fail(ctx, id, []byte("{}"), errors.New("db down")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Add(ctx, "n0")
+	if store.records > 2*store.minCompact {
+		t.Errorf("log not compacted, %d records", store.records)
+	}
+	store.Close()
+
+	// 崩溃时写了一半的最后一行被丢弃
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"removed","id":`)
+	f.Close()
+
+	store, err = OpenFileNotificationStore(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 其他进程不能同时打开同一文件
+	if _, err = OpenFileNotificationStore(path, 0); !errors.Is(err, ErrStoreLocked) {
+		t.Fatalf("expect locked store rejected, got %v", err)
+	}
+	if state, _ := store.Reserve(ctx, "n0"); state != NoticeProcessed {
+		t.Error("processed n0 reserved after reopen")
+	}
+	letters, _ := store.DeadLetters(ctx)
+	if len(letters) != 2 || letters[0].Attempts != 7 || letters[1].Attempts != 6 {
+		t.Fatalf("unexpected dead letters %+v", letters)
+	}
+	if err = store.Remove(ctx, "n1"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(path)
+	if !strings.HasSuffix(string(data), "{\"op\":\"removed\",\"id\":\"n1\"}\n") {
+		t.Errorf("unexpected log tail %q", data)
+	}
+	store.Close()
+
+	ioutil.WriteFile(path, []byte("not json\n"), 0600)
+	if _, err = OpenFileNotificationStore(path, 0); err == nil {
+		t.Error("expect corrupted store rejected")
+	}
+}
+
+// failingStore 写入失败的NotificationStore
+type failingStore struct {
+	*MemoryNotificationStore
+}
+
+func (s failingStore) Fail(ctx context.Context, id string, notice []byte, cause error) error {
+	return errors.New("disk full")
+}
+
+func (s failingStore) Add(ctx context.Context, id string) error {
+	s.MemoryNotificationStore.Add(ctx, id)
+	return errors.New("disk full")
+}
+
+func TestNotificationStoreErrors(t *testing.T) {
+	h := NewNotificationHandler(WithNotificationStore(failingStore{NewMemoryNotificationStore(0)}))
+	fail := true
+	h.HandleDefault(func(ctx context.Context, ev Event) error {
+		if fail {
+			return errors.New("db down")
+		}
+		return nil
+	})
+
+	notice := `{"noticeType":2,"map":{"noticeId":"n1","contractId":"1001"}}`
+	w := postNotice(h, notice)
+	if w.Body.String() != `{"msg":"db down; dead letter n1 not saved: disk full","response":false}` {
+		t.Fatalf("unexpected answer %q", w.Body.String())
+	}
+	fail = false
+	w = postNotice(h, notice)
+	if !strings.Contains(w.Body.String(), `"response":false`) || !strings.Contains(w.Body.String(), "disk full") {
+		t.Fatalf("expect failure answered when the record is not saved, got %q", w.Body.String())
+	}
+}
+
+// pendingCheckStore 记录保存死信时通知是否仍被占用
+type pendingCheckStore struct {
+	*MemoryNotificationStore
+	state NoticeState
+}
+
+func (s *pendingCheckStore) Fail(ctx context.Context, id string, notice []byte, cause error) error {
+	s.state, _ = s.Reserve(ctx, id)
+	return s.MemoryNotificationStore.Fail(ctx, id, notice, cause)
+}
+
+func TestNotificationFailBeforeRelease(t *testing.T) {
+	store := &pendingCheckStore{MemoryNotificationStore: NewMemoryNotificationStore(0)}
+	h := NewNotificationHandler(WithNotificationStore(store))
+	h.HandleDefault(func(ctx context.Context, ev Event) error {
+		return errors.New("db down")
+	})
+	postNotice(h, `{"noticeType":2,"map":{"noticeId":"n1","contractId":"1001"}}`)
+	// 保存死信时重发的通知不能占用ID，否则处理成功删除的死信会被重新写入
+	if store.state != NoticePending {
+		t.Fatalf("expect notice reserved while the dead letter is saved, got %v", store.state)
+	}
+	if state, _ := store.Reserve(context.Background(), "n1"); state != NoticeReserved {
+		t.Fatalf("expect notice released after failure, got %v", state)
+	}
+}
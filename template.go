@@ -0,0 +1,371 @@
+package goyht
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/iotdog/goyht/internal/conf"
+)
+
+// PlaceholderType 模板占位符的取值类型
+type PlaceholderType string
+
+// 模板占位符的取值类型
+const (
+	PlaceholderString   PlaceholderType = "string"   // 任意文本(默认)
+	PlaceholderInteger  PlaceholderType = "integer"  // 整数
+	PlaceholderNumber   PlaceholderType = "number"   // 十进制数
+	PlaceholderMoney    PlaceholderType = "money"    // 金额，最多两位小数
+	PlaceholderDate     PlaceholderType = "date"     // 日期，默认格式为TemplateDateLayout
+	PlaceholderDateTime PlaceholderType = "datetime" // 日期时间，默认格式为TemplateDateTimeLayout
+)
+
+var moneyPattern = regexp.MustCompile(`^-?\d+(\.\d{1,2})?$`)
+
+// PlaceholderSchema 模板占位符的定义
+type PlaceholderSchema struct {
+	Name      string          `json:"name"`      // 占位符名称，可省略${}
+	Type      PlaceholderType `json:"type"`      // 取值类型，默认为PlaceholderString
+	Required  bool            `json:"required"`  // 是否必填
+	MaxLength int             `json:"maxLength"` // 最大字符数，0表示不限制
+	Enum      []string        `json:"enum"`      // 可选的取值，为空表示不限制
+	Layout    string          `json:"layout"`    // 日期类型的格式，可选
+}
+
+// TemplateSchema 合同模板的定义
+type TemplateSchema struct {
+	TemplateID   string              `json:"templateId"`   // 模板ID
+	Name         string              `json:"name"`         // 模板名称，可选
+	Placeholders []PlaceholderSchema `json:"placeholders"` // 合同参数占位符
+	Locations    []string            `json:"locations"`    // 签名占位符名称，为空表示不校验
+}
+
+// TemplateDataError 合同参数与模板定义不符
+type TemplateDataError struct {
+	TemplateID string
+	Missing    []string           // 缺失的必填占位符
+	Unknown    []string           // 模板中未定义的占位符
+	Invalid    []*ValidationError // 取值不符合定义的占位符
+}
+
+// Error implements the error interface.
+func (e *TemplateDataError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unknown) > 0 {
+		parts = append(parts, "unknown "+strings.Join(e.Unknown, ", "))
+	}
+	for _, v := range e.Invalid {
+		parts = append(parts, fmt.Sprintf("invalid %s: %v", v.Field, v.Err))
+	}
+	return fmt.Sprintf("goyht: template %s: %s", e.TemplateID, strings.Join(parts, "; "))
+}
+
+// Is reports whether target is ErrInvalidParam.
+func (e *TemplateDataError) Is(target error) bool {
+	return target == ErrInvalidParam
+}
+
+// check 校验定义本身
+func (s *TemplateSchema) check() error {
+	if s.TemplateID == "" {
+		return fmt.Errorf("%w: template schema without templateId", ErrInvalidParam)
+	}
+	seen := map[string]bool{}
+	for i := range s.Placeholders {
+		p := &s.Placeholders[i]
+		if p.Name == "" {
+			return fmt.Errorf("%w: template %s: placeholder %d without name", ErrInvalidParam, s.TemplateID, i)
+		}
+		if seen[placeholder(p.Name)] {
+			return fmt.Errorf("%w: template %s: duplicate placeholder %s", ErrInvalidParam, s.TemplateID, p.Name)
+		}
+		seen[placeholder(p.Name)] = true
+		switch p.Type {
+		case "":
+			p.Type = PlaceholderString
+		case PlaceholderString, PlaceholderInteger, PlaceholderNumber, PlaceholderMoney,
+			PlaceholderDate, PlaceholderDateTime:
+		default:
+			return fmt.Errorf("%w: template %s: placeholder %s has unknown type %q",
+				ErrInvalidParam, s.TemplateID, p.Name, p.Type)
+		}
+	}
+	return nil
+}
+
+// Validate 校验合同参数：必填的占位符不能缺失或为空，不能包含未定义的占位符，
+// 取值须符合类型、长度和可选值的限制。data可以是占位符map或带yht标签的结构体，
+// 不符合时返回*TemplateDataError
+func (s *TemplateSchema) Validate(data interface{}) error {
+	values, err := placeholderValues(data)
+	if err != nil {
+		return err
+	}
+	e := &TemplateDataError{TemplateID: s.TemplateID}
+	defined := map[string]bool{}
+	for i := range s.Placeholders {
+		p := &s.Placeholders[i]
+		key := placeholder(p.Name)
+		defined[key] = true
+		value, ok := values[key]
+		if !ok || value == "" {
+			if p.Required {
+				e.Missing = append(e.Missing, key)
+			}
+			continue
+		}
+		if err := p.validate(value); err != nil {
+			e.Invalid = append(e.Invalid, &ValidationError{Field: key, Err: err})
+		}
+	}
+	for key := range values {
+		if !defined[key] {
+			e.Unknown = append(e.Unknown, key)
+		}
+	}
+	sort.Strings(e.Unknown)
+	if len(e.Missing) > 0 || len(e.Unknown) > 0 || len(e.Invalid) > 0 {
+		return e
+	}
+	return nil
+}
+
+// validate 校验单个占位符的取值
+func (p *PlaceholderSchema) validate(value string) error {
+	if p.MaxLength > 0 && utf8.RuneCountInString(value) > p.MaxLength {
+		return fmt.Errorf("longer than %d characters", p.MaxLength)
+	}
+	if len(p.Enum) > 0 {
+		found := false
+		for _, v := range p.Enum {
+			if v == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%q is not one of %v", value, p.Enum)
+		}
+	}
+	switch p.Type {
+	case PlaceholderInteger:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+	case PlaceholderNumber:
+		if _, ok := new(big.Rat).SetString(value); !ok || strings.Contains(value, "/") {
+			return fmt.Errorf("%q is not a number", value)
+		}
+	case PlaceholderMoney:
+		if !moneyPattern.MatchString(value) {
+			return fmt.Errorf("%q is not an amount of money", value)
+		}
+	case PlaceholderDate, PlaceholderDateTime:
+		layout := p.Layout
+		if layout == "" {
+			layout = TemplateDateLayout
+			if p.Type == PlaceholderDateTime {
+				layout = TemplateDateTimeLayout
+			}
+		}
+		if _, err := time.Parse(layout, value); err != nil {
+			return fmt.Errorf("%q does not match layout %s", value, layout)
+		}
+	}
+	return nil
+}
+
+// HasLocation 判断签名占位符是否在模板中定义，未定义任何签名占位符时总是返回true
+func (s *TemplateSchema) HasLocation(name string) bool {
+	if len(s.Locations) == 0 {
+		return true
+	}
+	for _, l := range s.Locations {
+		if l == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSigners 校验以签名占位符定位的签署者，占位符须在模板中定义
+func (s *TemplateSchema) ValidateSigners(signers ...YhtSigner) error {
+	for _, signer := range signers {
+		if signer.SignPositionType != YHTSignPositionTypePlaceHolder {
+			continue
+		}
+		if !s.HasLocation(signer.PositionContent) {
+			return &ValidationError{
+				Field: "positionContent",
+				Err:   fmt.Errorf("location %q is not defined in template %s", signer.PositionContent, s.TemplateID),
+			}
+		}
+	}
+	return nil
+}
+
+// placeholderValues 将合同参数转换为占位符到文本的映射，键统一为${name}
+func placeholderValues(data interface{}) (map[string]string, error) {
+	data, err := templateData(data)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err = dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("%w: contract data must be an object: %v", ErrInvalidParam, err)
+	}
+	values := make(map[string]string, len(m))
+	for k, v := range m {
+		var s string
+		switch x := v.(type) {
+		case nil:
+		case string:
+			s = x
+		case json.Number:
+			s = x.String()
+		case bool:
+			s = strconv.FormatBool(x)
+		default:
+			return nil, &ValidationError{Field: placeholder(k), Err: fmt.Errorf("value must be text or a number, got %T", v)}
+		}
+		values[placeholder(k)] = s
+	}
+	return values, nil
+}
+
+// TemplateRegistry 合同模板的登记表，按模板ID查找定义。
+// 可以并发使用
+type TemplateRegistry struct {
+	mu      sync.RWMutex
+	schemas map[string]*TemplateSchema
+}
+
+// NewTemplateRegistry returns an empty registry.
+func NewTemplateRegistry() *TemplateRegistry {
+	return &TemplateRegistry{schemas: map[string]*TemplateSchema{}}
+}
+
+// Register 登记模板定义，相同模板ID的定义会被替换
+func (r *TemplateRegistry) Register(schemas ...TemplateSchema) error {
+	checked := make([]*TemplateSchema, 0, len(schemas))
+	for i := range schemas {
+		s := schemas[i]
+		s.Placeholders = append([]PlaceholderSchema(nil), s.Placeholders...)
+		if err := s.check(); err != nil {
+			return err
+		}
+		checked = append(checked, &s)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range checked {
+		r.schemas[s.TemplateID] = s
+	}
+	return nil
+}
+
+// Lookup 查找模板定义
+func (r *TemplateRegistry) Lookup(templateID string) (*TemplateSchema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.schemas[templateID]
+	return s, ok
+}
+
+// TemplateIDs 返回已登记的模板ID
+func (r *TemplateRegistry) TemplateIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.schemas))
+	for id := range r.schemas {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// LoadFile 从YAML(.yaml/.yml)、TOML(.toml)或JSON(.json)文件登记模板定义，文件格式为：
+//
+//	templates:
+//	  - templateId: "92130"
+//	    name: 房屋租赁合同
+//	    locations: [甲方签章, 乙方签章]
+//	    placeholders:
+//	      - {name: lessee, required: true, maxLength: 20}
+//	      - {name: rent, type: money, required: true}
+//	      - {name: payment, enum: [月付, 季付]}
+//
+// 也可以省略templates，直接列出模板定义。
+//
+// YAML和TOML由内置的解析器解析，只支持配置文件常用的子集：YAML支持以空格缩进的映射和列表、
+// 单行的[...]和{...}、引号字符串和注释，不支持|和>多行文本、锚点、别名和标签；
+// TOML不支持多行字符串。使用不支持的语法时返回错误，需要完整的语法时请使用JSON
+func (r *TemplateRegistry) LoadFile(path string) error {
+	tree, err := readConfigTree(path)
+	if err != nil {
+		return fmt.Errorf("goyht: load %s: %w", path, err)
+	}
+	if list, ok := tree.([]interface{}); ok {
+		tree = map[string]interface{}{"templates": list}
+	}
+	var file struct {
+		Templates []TemplateSchema `json:"templates"`
+	}
+	if err = conf.Decode(tree, &file); err != nil {
+		return fmt.Errorf("goyht: load %s: %w", path, err)
+	}
+	return r.Register(file.Templates...)
+}
+
+// LoadTemplateRegistry returns a registry loaded from the YAML, TOML or JSON files.
+func LoadTemplateRegistry(paths ...string) (*TemplateRegistry, error) {
+	r := NewTemplateRegistry()
+	for _, path := range paths {
+		if err := r.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// checkTemplate 按登记的模板定义校验合同参数，未登记的模板不校验
+func (c *Client) checkTemplate(templateID string, data interface{}) error {
+	if !c.validation || c.templates == nil {
+		return nil
+	}
+	s, ok := c.templates.Lookup(templateID)
+	if !ok {
+		return nil
+	}
+	return s.Validate(data)
+}
+
+// checkTemplateSigners 按登记的模板定义校验签名占位符，未登记的模板不校验
+func (c *Client) checkTemplateSigners(templateID string, signers ...YhtSigner) error {
+	if !c.validation || c.templates == nil {
+		return nil
+	}
+	s, ok := c.templates.Lookup(templateID)
+	if !ok {
+		return nil
+	}
+	return s.ValidateSigners(signers...)
+}
@@ -0,0 +1,91 @@
+package goyht
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const leaseTemplates = `
+templates:
+  - templateId: "92130"
+    name: 房屋租赁合同
+    locations: [甲方签章, 乙方签章]
+    placeholders:
+      - {name: lessee, required: true, maxLength: 4}
+      - {name: monthlyRent, type: money, required: true}
+      - {name: leasemonths, type: integer}
+      - {name: date, type: date}
+      - name: paycycle
+        enum: [月付, 季付]
+`
+
+func TestTemplateRegistry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "templates.yaml")
+	if err := ioutil.WriteFile(path, []byte(leaseTemplates), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := LoadTemplateRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := reg.Lookup("92130")
+	if !ok || len(s.Placeholders) != 5 || s.Placeholders[0].Type != PlaceholderString {
+		t.Fatalf("unexpected schema %+v", s)
+	}
+
+	good := M{"${lessee}": "Mike", "monthlyRent": "3000.50", "${leasemonths}": 12, "${date}": "2026-01-02"}
+	if err = s.Validate(good); err != nil {
+		t.Fatal(err)
+	}
+	err = s.Validate(M{"${lessee}": "Michael", "${monthlyRent}": "3000.505", "${paycycle}": "年付", "${room}": "1"})
+	var te *TemplateDataError
+	if !errors.As(err, &te) || !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("expect *TemplateDataError, got %v", err)
+	}
+	if len(te.Missing) != 0 || !reflect.DeepEqual(te.Unknown, []string{"${room}"}) || len(te.Invalid) != 3 {
+		t.Fatalf("unexpected error %v", err)
+	}
+	err = s.Validate(M{"${monthlyRent}": "1"})
+	if !errors.As(err, &te) || !reflect.DeepEqual(te.Missing, []string{"${lessee}"}) {
+		t.Fatalf("expect missing lessee, got %v", err)
+	}
+	if err = s.ValidateSigners(YhtSigner{SignPositionType: YHTSignPositionTypePlaceHolder, PositionContent: "丙方签章"}); err == nil {
+		t.Fatal("expect undefined location error")
+	}
+
+	jsonPath := filepath.Join(dir, "templates.json")
+	ioutil.WriteFile(jsonPath, []byte(`[{"templateId": 56006, "placeholders": [{"name": "x", "type": "bool"}]}]`), 0644)
+	if err = reg.LoadFile(jsonPath); !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("expect unknown type error, got %v", err)
+	}
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"code":200,"msg":"ok","data":{"contractId":1}}`)
+	}))
+	defer srv.Close()
+	cli := NewClient(Config{APIGateway: srv.URL}, WithTokenRefresher(staticToken("tok")), WithTemplateRegistry(reg))
+	defer cli.Close()
+	req := &YhtCreateTemplateContractReq{Title: "lease", TemplateID: "92130", ContractData: M{"${lessee}": "Mike"}}
+	if _, err = cli.CreateContractFromTemplateV4(req); !errors.As(err, &te) {
+		t.Fatalf("expect *TemplateDataError, got %v", err)
+	}
+	if calls != 0 {
+		t.Fatal("invalid contract data should not be sent")
+	}
+	req.ContractData = good
+	if _, err = cli.CreateContractFromTemplateV4(req); err != nil || calls != 1 {
+		t.Fatalf("calls = %d, err = %v", calls, err)
+	}
+	if _, err = cli.CreateTemplateContract("lease", "1", "92130", "tok", false, M{"${x}": "1"}); !errors.As(err, &te) {
+		t.Fatalf("expect *TemplateDataError, got %v", err)
+	}
+}
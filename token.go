@@ -0,0 +1,160 @@
+package goyht
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// 平台长效令牌刷新参数
+const (
+	defaultTokenRefreshInterval = 14 * time.Minute // 令牌有效期为15分钟，提前刷新
+	defaultTokenRetryInterval   = 30 * time.Second // 刷新失败后的重试间隔
+	tokenRefreshJitter          = 0.1              // 刷新间隔的随机抖动比例
+)
+
+// errNoTokenRefresher 没有配置TokenRefresher，无法获取平台长效令牌
+var errNoTokenRefresher = errors.New("no token refresher configured")
+
+// tokenCall 正在进行中的平台登录，并发的调用方共享同一次登录
+type tokenCall struct {
+	done  chan struct{} // 登录结束时关闭
+	token string
+	err   error
+}
+
+// tokenManager 维护平台长效令牌，可被多个goroutine并发使用
+type tokenManager struct {
+	refresher TokenRefresher
+	logger    Logger
+	interval  time.Duration
+
+	mu    sync.RWMutex
+	token string
+	call  *tokenCall // 保证同一时刻只有一个登录请求
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running bool
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newTokenManager(refresher TokenRefresher, logger Logger, interval time.Duration) *tokenManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &tokenManager{
+		refresher: refresher,
+		logger:    logger,
+		interval:  interval,
+		ctx:       ctx,
+		cancel:    cancel,
+		stopped:   make(chan struct{}),
+	}
+}
+
+// current 返回当前令牌，不触发登录
+func (m *tokenManager) current() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.token
+}
+
+// Token 返回平台长效令牌，尚未获取时阻塞登录
+func (m *tokenManager) Token(ctx context.Context) (string, error) {
+	if token := m.current(); token != "" {
+		return token, nil
+	}
+	if m.refresher == nil {
+		return "", errNoTokenRefresher
+	}
+	return m.renew(ctx, "")
+}
+
+// renew 在令牌仍为stale时重新登录，若其它goroutine已刷新则直接返回新令牌。
+// 等待其他调用方登录时ctx取消则立即返回，其他调用方因自身ctx取消而登录失败时重新登录
+func (m *tokenManager) renew(ctx context.Context, stale string) (string, error) {
+	for {
+		m.mu.Lock()
+		if token := m.token; token != stale && token != "" {
+			m.mu.Unlock()
+			return token, nil
+		}
+		call := m.call
+		if call == nil {
+			break
+		}
+		m.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if call.err != nil && isContextError(call.err) && ctx.Err() == nil {
+			continue
+		}
+		return call.token, call.err
+	}
+	call := &tokenCall{done: make(chan struct{})}
+	m.call = call
+	m.mu.Unlock()
+
+	call.token, call.err = m.refresher.RefreshToken(ctx)
+
+	m.mu.Lock()
+	m.call = nil
+	if call.err == nil {
+		m.token = call.token
+	}
+	m.mu.Unlock()
+	close(call.done)
+
+	return call.token, call.err
+}
+
+// start 开启后台goroutine定期刷新令牌
+func (m *tokenManager) start() {
+	m.running = true
+	go m.run()
+}
+
+func (m *tokenManager) run() {
+	defer close(m.stopped)
+	var delay time.Duration
+	for {
+		timer := time.NewTimer(delay)
+		select {
+		case <-m.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if _, err := m.renew(m.ctx, m.current()); err != nil {
+			m.logger.Debugln(err)
+			delay = defaultTokenRetryInterval
+			continue
+		}
+		delay = jitter(m.interval)
+	}
+}
+
+// Close 停止后台刷新
+func (m *tokenManager) Close() {
+	m.once.Do(func() {
+		m.cancel()
+		if m.running {
+			<-m.stopped
+		}
+	})
+}
+
+// jitter 将d随机缩短至多tokenRefreshJitter比例，避免多个实例同时刷新
+func jitter(d time.Duration) time.Duration {
+	return d - time.Duration(rand.Float64()*tokenRefreshJitter*float64(d))
+}
+
+// respBase 可获取基础应答的V4应答模型
+type respBase interface {
+	baseResp() *YhtBaseResp
+}
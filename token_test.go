@@ -0,0 +1,138 @@
+package goyht
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingRefresher struct {
+	n int32
+}
+
+func (r *countingRefresher) RefreshToken(ctx context.Context) (string, error) {
+	n := atomic.AddInt32(&r.n, 1)
+	return fmt.Sprintf("token-%d", n), nil
+}
+
+func TestTokenManagerConcurrent(t *testing.T) {
+	r := &countingRefresher{}
+	m := newTokenManager(r, holmesLogger{}, defaultTokenRefreshInterval)
+	defer m.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if tok, err := m.Token(context.Background()); err != nil || tok != "token-1" {
+				t.Errorf("unexpected token %q %v", tok, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 多个调用方同时发现令牌失效时只登录一次
+	for i := 0; i < 5; i++ {
+		if tok, _ := m.renew(context.Background(), "token-1"); tok != "token-2" {
+			t.Fatalf("unexpected renewed token %q", tok)
+		}
+	}
+	if atomic.LoadInt32(&r.n) != 2 {
+		t.Fatalf("expect 2 logins, got %d", r.n)
+	}
+}
+
+func TestCallV4ReplayOnTokenExpired(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("token") != "token-2" {
+			json.NewEncoder(w).Encode(M{"code": 401, "msg": "token expired"})
+			return
+		}
+		json.NewEncoder(w).Encode(M{"code": 200, "msg": "ok", "data": []M{{"1": 100}}})
+	}))
+	defer srv.Close()
+
+	r := &countingRefresher{}
+	cli := NewClient(Config{}, WithAPIGateway(srv.URL), WithTokenRefresher(r))
+	defer cli.Close()
+	if err := cli.Authenticate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	rsp, err := cli.QuerySignerID(&YhtQuerySignerIDReq{CertifyNumList: []string{"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rsp.Success() {
+		t.Fatalf("request not replayed: %s", rsp.Message())
+	}
+}
+
+// blockingRefresher 第一次登录阻塞到release关闭或ctx取消
+type blockingRefresher struct {
+	n       int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRefresher) RefreshToken(ctx context.Context) (string, error) {
+	if atomic.AddInt32(&r.n, 1) == 1 {
+		close(r.started)
+		select {
+		case <-r.release:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	return "token", nil
+}
+
+func TestTokenManagerWaiterContext(t *testing.T) {
+	if _, err := newTokenManager(nil, holmesLogger{}, defaultTokenRefreshInterval).Token(context.Background()); err != errNoTokenRefresher {
+		t.Fatalf("expect no refresher error, got %v", err)
+	}
+
+	r := &blockingRefresher{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(r.release)
+	m := newTokenManager(r, holmesLogger{}, defaultTokenRefreshInterval)
+	defer m.Close()
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := m.Token(leaderCtx)
+		leader <- err
+	}()
+	<-r.started
+
+	// 登录阻塞时等待者的ctx到期立即返回
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := m.Token(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expect waiter deadline, got %v", err)
+	}
+
+	// 发起登录的调用方取消后，等待者重新登录
+	waiter := make(chan string, 1)
+	go func() {
+		tok, err := m.Token(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		waiter <- tok
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancelLeader()
+	if err := <-leader; err != context.Canceled {
+		t.Fatalf("expect leader canceled, got %v", err)
+	}
+	if tok := <-waiter; tok != "token" || atomic.LoadInt32(&r.n) != 2 {
+		t.Fatalf("unexpected token %q after %d logins", tok, r.n)
+	}
+}
@@ -0,0 +1,153 @@
+package goyht
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 支持上传的合同文件类型
+const (
+	MIMETypePDF  = "application/pdf"
+	MIMETypeDOC  = "application/msword"
+	MIMETypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+)
+
+// mimeExtensions 合同文件类型对应的扩展名
+var mimeExtensions = map[string]string{
+	MIMETypePDF:  ".pdf",
+	MIMETypeDOC:  ".doc",
+	MIMETypeDOCX: ".docx",
+}
+
+// 合同文件的魔数
+var (
+	magicPDF = []byte("%PDF-")
+	magicOLE = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1} // DOC
+	magicZip = []byte("PK\x03\x04")                                   // DOCX
+)
+
+// ContractFile 上传的合同文件
+type ContractFile struct {
+	Name        string    // 文件名，为空时根据文件类型生成
+	ContentType string    // MIME类型，为空时根据文件内容和扩展名检测
+	Reader      io.Reader // 文件内容
+}
+
+// DetectContentType 根据文件内容和文件名检测合同文件的MIME类型，仅支持PDF、DOC和DOCX
+func DetectContentType(name string, head []byte) (string, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	switch {
+	case bytes.HasPrefix(head, magicPDF):
+		return MIMETypePDF, nil
+	case bytes.HasPrefix(head, magicOLE):
+		return MIMETypeDOC, nil
+	case bytes.HasPrefix(head, magicZip) && (ext == ".docx" || ext == ""):
+		return MIMETypeDOCX, nil
+	}
+	return "", fmt.Errorf("%w: unsupported contract file %q", ErrInvalidParam, name)
+}
+
+// prepare 检测文件类型并补全文件名，返回可读取完整内容的Reader
+func (f ContractFile) prepare() (ContractFile, error) {
+	if f.Reader == nil {
+		return f, fmt.Errorf("%w: empty contract file", ErrInvalidParam)
+	}
+	br := bufio.NewReader(f.Reader)
+	head, _ := br.Peek(len(magicOLE))
+	if len(head) == 0 {
+		return f, fmt.Errorf("%w: empty contract file", ErrInvalidParam)
+	}
+	detected, err := DetectContentType(f.Name, head)
+	if err != nil {
+		return f, err
+	}
+	if f.ContentType == "" {
+		f.ContentType = detected
+	} else if f.ContentType != detected {
+		return f, fmt.Errorf("%w: content type %s does not match file content %s", ErrInvalidParam, f.ContentType, detected)
+	}
+	if f.Name == "" {
+		f.Name = "contract" + mimeExtensions[f.ContentType]
+	}
+	f.Reader = br
+	return f, nil
+}
+
+// multipartBody 以流的方式构造multipart/form-data请求体，文件内容不会缓存在内存中
+func multipartBody(fields map[string]string, file ContractFile) (io.ReadCloser, string, error) {
+	file, err := file.prepare()
+	if err != nil {
+		return nil, "", err
+	}
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeMultipart(writer, fields, file))
+	}()
+	return pr, writer.FormDataContentType(), nil
+}
+
+func writeMultipart(writer *multipart.Writer, fields map[string]string, file ContractFile) error {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := writer.WriteField(k, fields[k]); err != nil {
+			return err
+		}
+	}
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, escapeQuotes(file.Name)))
+	h.Set("Content-Type", file.ContentType)
+	fw, err := writer.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(fw, file.Reader); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// rewinder 返回将r恢复到当前位置的函数，首次调用不做任何操作，
+// r不支持Seek时再次调用返回错误
+func rewinder(r io.Reader) func() error {
+	seeker, ok := r.(io.Seeker)
+	var start int64
+	if ok {
+		var err error
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			ok = false
+		}
+	}
+	first := true
+	return func() error {
+		if first {
+			first = false
+			return nil
+		}
+		if !ok {
+			return errors.New("goyht: contract file can not be read again")
+		}
+		_, err := seeker.Seek(start, io.SeekStart)
+		return err
+	}
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
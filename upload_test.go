@@ -0,0 +1,73 @@
+package goyht
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectContentType(t *testing.T) {
+	cases := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"a.pdf", []byte("%PDF-1.7"), MIMETypePDF},
+		{"a.doc", magicOLE, MIMETypeDOC},
+		{"a.docx", []byte("PK\x03\x04\x14\x00"), MIMETypeDOCX},
+		{"a.zip", []byte("PK\x03\x04\x14\x00"), ""},
+		{"a.txt", []byte("hello"), ""},
+	}
+	for _, c := range cases {
+		got, err := DetectContentType(c.name, c.head)
+		if got != c.want || (c.want == "") != errors.Is(err, ErrInvalidParam) {
+			t.Errorf("%s: got %q %v, want %q", c.name, got, err, c.want)
+		}
+	}
+}
+
+func TestCreateFileContractV4Stream(t *testing.T) {
+	pdf := append([]byte("%PDF-1.4\n"), bytes.Repeat([]byte("scanned page "), 4096)...)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, header, err := r.FormFile("file")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		data, _ := ioutil.ReadAll(f)
+		if header.Filename != "scan.pdf" || header.Header.Get("Content-Type") != MIMETypePDF || !bytes.Equal(data, pdf) {
+			t.Errorf("unexpected file %q %v", header.Filename, header.Header)
+		}
+		if r.FormValue("contractTitle") != "Scan" || r.ContentLength != -1 {
+			t.Errorf("unexpected request %q %d", r.FormValue("contractTitle"), r.ContentLength)
+		}
+		json.NewEncoder(w).Encode(M{"code": 200, "msg": "ok", "data": M{"contractId": 1}})
+	}))
+	defer srv.Close()
+
+	cli := NewClient(Config{}, WithAPIGateway(srv.URL), WithTokenRefresher(staticToken("tok")))
+	defer cli.Close()
+	// 不支持Seek的Reader，请求体必须以流的方式发送
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write(pdf)
+		pw.Close()
+	}()
+	rsp, err := cli.CreateFileContractV4(&YhtCreateFileContractReq{Title: "Scan", FileName: "scan.pdf", Reader: pr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Data.ContractID != 1 {
+		t.Fatalf("unexpected response %+v", rsp)
+	}
+
+	_, err = cli.CreateFileContractV4(&YhtCreateFileContractReq{Title: "Text", FileName: "a.txt", File: []byte("hello")})
+	if !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("expect invalid param, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package goyht
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 用户长效令牌缓存默认参数
+const (
+	defaultUserTokenCacheSize = 1024
+	defaultUserTokenTTL       = 14 * time.Minute // 用户令牌有效期为15分钟，提前过期
+)
+
+// UserTokenCacheStats 用户令牌缓存统计
+type UserTokenCacheStats struct {
+	Hits      uint64 // 命中缓存次数
+	Misses    uint64 // 缓存中不存在而登录的次数
+	Refreshes uint64 // 缓存过期而重新登录的次数
+	Size      int    // 当前缓存的令牌数
+}
+
+type userTokenEntry struct {
+	signerID string
+	resp     *YhtBaseResp
+	token    string
+	expires  time.Time
+}
+
+// userTokenCall 正在进行中的用户登录，相同signerID的并发请求共享同一次登录
+type userTokenCall struct {
+	done  chan struct{} // 登录结束时关闭
+	resp  *YhtBaseResp
+	token string
+	err   error
+}
+
+// userTokenCache 按signerID缓存用户长效令牌，容量有限，按最近最少使用淘汰
+type userTokenCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu       sync.Mutex
+	ll       *list.List
+	items    map[string]*list.Element
+	inflight map[string]*userTokenCall
+
+	hits, misses, refreshes uint64
+}
+
+func newUserTokenCache(size int, ttl time.Duration) *userTokenCache {
+	return &userTokenCache{
+		size:     size,
+		ttl:      ttl,
+		now:      time.Now,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+		inflight: map[string]*userTokenCall{},
+	}
+}
+
+// get 返回signerID的令牌，不存在或已过期时调用login获取。
+// 等待其他请求登录时ctx取消则立即返回，其他请求因自身ctx取消而登录失败时重新登录
+func (uc *userTokenCache) get(ctx context.Context, signerID string, login func(context.Context, string) (*YhtBaseResp, string, error)) (*YhtBaseResp, string, error) {
+	counted := false
+	for {
+		uc.mu.Lock()
+		if ele, ok := uc.items[signerID]; ok {
+			entry := ele.Value.(*userTokenEntry)
+			if uc.now().Before(entry.expires) {
+				uc.ll.MoveToFront(ele)
+				uc.mu.Unlock()
+				if !counted {
+					atomic.AddUint64(&uc.hits, 1)
+				}
+				return entry.resp, entry.token, nil
+			}
+			uc.removeElement(ele)
+			if !counted {
+				atomic.AddUint64(&uc.refreshes, 1)
+			}
+		} else if !counted {
+			atomic.AddUint64(&uc.misses, 1)
+		}
+		counted = true
+		call, ok := uc.inflight[signerID]
+		if !ok {
+			break
+		}
+		uc.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		}
+		if call.err != nil && isContextError(call.err) && ctx.Err() == nil {
+			continue
+		}
+		return call.resp, call.token, call.err
+	}
+	call := &userTokenCall{done: make(chan struct{})}
+	uc.inflight[signerID] = call
+	uc.mu.Unlock()
+
+	call.resp, call.token, call.err = login(ctx, signerID)
+
+	uc.mu.Lock()
+	delete(uc.inflight, signerID)
+	if call.err == nil && call.resp.Success() && call.token != "" {
+		uc.add(&userTokenEntry{
+			signerID: signerID,
+			resp:     call.resp,
+			token:    call.token,
+			expires:  uc.now().Add(uc.ttl),
+		})
+	}
+	uc.mu.Unlock()
+	close(call.done)
+
+	return call.resp, call.token, call.err
+}
+
+func (uc *userTokenCache) add(entry *userTokenEntry) {
+	if ele, ok := uc.items[entry.signerID]; ok {
+		uc.removeElement(ele)
+	}
+	uc.items[entry.signerID] = uc.ll.PushFront(entry)
+	for uc.ll.Len() > uc.size {
+		uc.removeElement(uc.ll.Back())
+	}
+}
+
+func (uc *userTokenCache) removeElement(ele *list.Element) {
+	uc.ll.Remove(ele)
+	delete(uc.items, ele.Value.(*userTokenEntry).signerID)
+}
+
+// remove 删除signerID的令牌
+func (uc *userTokenCache) remove(signerID string) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if ele, ok := uc.items[signerID]; ok {
+		uc.removeElement(ele)
+	}
+}
+
+func (uc *userTokenCache) stats() UserTokenCacheStats {
+	uc.mu.Lock()
+	size := uc.ll.Len()
+	uc.mu.Unlock()
+	return UserTokenCacheStats{
+		Hits:      atomic.LoadUint64(&uc.hits),
+		Misses:    atomic.LoadUint64(&uc.misses),
+		Refreshes: atomic.LoadUint64(&uc.refreshes),
+		Size:      size,
+	}
+}
@@ -0,0 +1,104 @@
+package goyht
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUserTokenCache(t *testing.T) {
+	var logins int32
+	login := func(ctx context.Context, signerID string) (*YhtBaseResp, string, error) {
+		n := atomic.AddInt32(&logins, 1)
+		time.Sleep(10 * time.Millisecond)
+		return &YhtBaseResp{Code: 200}, fmt.Sprintf("%s-%d", signerID, n), nil
+	}
+	now := time.Now()
+	uc := newUserTokenCache(2, time.Minute)
+	uc.now = func() time.Time { return now }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, tok, err := uc.get(context.Background(), "a", login); err != nil || tok != "a-1" {
+				t.Errorf("unexpected token %q %v", tok, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if logins != 1 {
+		t.Fatalf("expect concurrent logins collapsed, got %d", logins)
+	}
+
+	uc.get(context.Background(), "b", login)
+	uc.get(context.Background(), "c", login) // 淘汰a
+	if _, tok, _ := uc.get(context.Background(), "a", login); tok != "a-4" {
+		t.Fatalf("expect a evicted, got %q", tok)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, tok, _ := uc.get(context.Background(), "a", login); tok != "a-5" {
+		t.Fatalf("expect a expired, got %q", tok)
+	}
+
+	st := uc.stats()
+	if st.Refreshes != 1 || st.Size != 2 || st.Hits+st.Misses+st.Refreshes != 14 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestUserTokenCacheWaiterContext(t *testing.T) {
+	uc := newUserTokenCache(2, time.Minute)
+	started, release := make(chan struct{}), make(chan struct{})
+	var logins int32
+	login := func(ctx context.Context, signerID string) (*YhtBaseResp, string, error) {
+		if atomic.AddInt32(&logins, 1) == 1 {
+			close(started)
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return nil, "", ctx.Err()
+			}
+		}
+		return &YhtBaseResp{Code: 200}, "token", nil
+	}
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, _, err := uc.get(leaderCtx, "a", login)
+		leader <- err
+	}()
+	<-started
+
+	// 等待者的ctx取消时立即返回
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := uc.get(ctx, "a", login); err != context.DeadlineExceeded {
+		t.Fatalf("expect waiter deadline, got %v", err)
+	}
+
+	// 发起登录的请求取消后，等待者重新登录
+	waiter := make(chan string, 1)
+	go func() {
+		_, tok, err := uc.get(context.Background(), "a", login)
+		if err != nil {
+			t.Error(err)
+		}
+		waiter <- tok
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancelLeader()
+	if err := <-leader; err != context.Canceled {
+		t.Fatalf("expect leader canceled, got %v", err)
+	}
+	if tok := <-waiter; tok != "token" || atomic.LoadInt32(&logins) != 2 {
+		t.Fatalf("unexpected token %q after %d logins", tok, logins)
+	}
+	close(release)
+}
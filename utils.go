@@ -31,17 +31,24 @@ func toMap(st interface{}, extras map[string]string) (map[string]string, error)
 	return result, nil
 }
 
-func checkErr(code, subcode int, message string) error {
+// checkErr 检查V3接口应答，失败时返回*APIError
+func checkErr(info callInfo, code, subcode int, message string) error {
 	const success = 200
 	if code != success || subcode != success {
-		return fmt.Errorf("code %d subcode %d msg %s", code, subcode, message)
+		return info.apiError(code, subcode, message, nil)
 	}
 	return nil
 }
 
-func checkAuthErr(code int, message string, success bool) error {
+// checkAuthErr 检查实名认证接口应答，失败时返回*APIError
+func checkAuthErr(info callInfo, code int, message string, success bool) error {
 	if !success || code != 200 {
-		return fmt.Errorf("code %d message %s success %t", code, message, success)
+		return info.apiError(code, 0, message, nil)
 	}
 	return nil
 }
+
+// validIDType 检查合同ID类型
+func validIDType(idType string) bool {
+	return idType == YHTIDTypeSystem || idType == YHTIDTypeCustom
+}
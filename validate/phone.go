@@ -0,0 +1,119 @@
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 手机号格式
+var (
+	mainlandMobilePattern = regexp.MustCompile(`^1(3[0-9]|4[5-9]|5[0-35-9]|6[2567]|7[0-8]|8[0-9]|9[0-35-9])[0-9]{8}$`)
+	hkMacaoMobilePattern  = regexp.MustCompile(`^[4-9][0-9]{7}$`)
+	taiwanMobilePattern   = regexp.MustCompile(`^0?9[0-9]{8}$`)
+)
+
+// stripSeparators 去掉空格和连字符
+func stripSeparators(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '-', '　':
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// NormalizeMobile strips spaces, dashes and a +86 or 0086 prefix.
+func NormalizeMobile(num string) string {
+	num = stripSeparators(num)
+	for _, prefix := range []string{"+86", "0086"} {
+		if strings.HasPrefix(num, prefix) {
+			return num[len(prefix):]
+		}
+	}
+	return num
+}
+
+// MainlandMobile checks a mainland mobile number against the carrier prefixes.
+func MainlandMobile(num string) error {
+	if !mainlandMobilePattern.MatchString(NormalizeMobile(num)) {
+		return fmt.Errorf("%w: mainland mobile number", ErrFormat)
+	}
+	return nil
+}
+
+// HKMacaoMobile checks an 8-digit Hong Kong or Macao mobile number without
+// the area code.
+func HKMacaoMobile(num string) error {
+	num = stripSeparators(num)
+	for _, prefix := range []string{"+852", "+853"} {
+		num = strings.TrimPrefix(num, prefix)
+	}
+	if !hkMacaoMobilePattern.MatchString(num) {
+		return fmt.Errorf("%w: hong kong or macao mobile number", ErrFormat)
+	}
+	return nil
+}
+
+// TaiwanMobile checks a Taiwan mobile number, 09 followed by 8 digits, the
+// leading 0 is optional.
+func TaiwanMobile(num string) error {
+	if !taiwanMobilePattern.MatchString(strings.TrimPrefix(stripSeparators(num), "+886")) {
+		return fmt.Errorf("%w: taiwan mobile number", ErrFormat)
+	}
+	return nil
+}
+
+// cardLengths 发卡行识别码（BIN）前缀对应的卡号长度
+var cardLengths = []struct {
+	prefix   string
+	min, max int
+}{
+	{"34", 15, 15}, // American Express
+	{"37", 15, 15},
+	{"35", 16, 19}, // JCB
+	{"4", 13, 19},  // Visa
+	{"5", 16, 16},  // Mastercard
+	{"62", 16, 19}, // 银联
+}
+
+// NormalizeBankCard strips spaces and dashes.
+func NormalizeBankCard(num string) string {
+	return stripSeparators(num)
+}
+
+// BankCard checks a bank card number with the Luhn algorithm and the length
+// allowed for its BIN, 12 to 19 digits for unknown BINs.
+func BankCard(num string) error {
+	num = NormalizeBankCard(num)
+	min, max := 12, 19
+	for _, l := range cardLengths {
+		if strings.HasPrefix(num, l.prefix) {
+			min, max = l.min, l.max
+			break
+		}
+	}
+	if len(num) < min || len(num) > max {
+		return fmt.Errorf("%w: bank card number must have %d to %d digits", ErrFormat, min, max)
+	}
+	sum := 0
+	for i := 0; i < len(num); i++ {
+		d := num[len(num)-1-i]
+		if d < '0' || d > '9' {
+			return fmt.Errorf("%w: bank card number must be digits", ErrFormat)
+		}
+		v := int(d - '0')
+		if i%2 == 1 {
+			v *= 2
+			if v > 9 {
+				v -= 9
+			}
+		}
+		sum += v
+	}
+	if sum%10 != 0 {
+		return fmt.Errorf("%w: bank card number", ErrChecksum)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// usccCharset 统一社会信用代码使用的字符，下标即字符的值
+const usccCharset = "0123456789ABCDEFGHJKLMNPQRTUWXY"
+
+// usccWeights 统一社会信用代码前17位的加权因子
+var usccWeights = []int{1, 3, 9, 27, 19, 26, 16, 17, 20, 29, 25, 13, 8, 24, 10, 30, 28}
+
+// usccCategories 登记管理部门代码及其允许的机构类别代码
+var usccCategories = map[byte]string{
+	'1': "1239",   // 机构编制
+	'2': "19",     // 外交
+	'3': "123459", // 司法行政
+	'4': "19",     // 文化
+	'5': "1239",   // 民政
+	'6': "129",    // 旅游
+	'7': "129",    // 宗教
+	'8': "19",     // 工会
+	'9': "123",    // 工商
+	'A': "19",     // 中央军委改革和编制办公室
+	'N': "1239",   // 农业
+	'Y': "1",      // 其他
+}
+
+// orgCodeWeights 组织机构代码本体代码的加权因子
+var orgCodeWeights = []int{3, 7, 9, 10, 5, 8, 4, 2}
+
+// USCC checks an 18-character Unified Social Credit Code against GB 32100,
+// including the registration authority and category prefix, the
+// administrative division code and the check character.
+func USCC(code string) error {
+	code = normalize(code)
+	if len(code) != 18 {
+		return fmt.Errorf("%w: unified social credit code must have 18 characters", ErrFormat)
+	}
+	categories, ok := usccCategories[code[0]]
+	if !ok || strings.IndexByte(categories, code[1]) < 0 {
+		return fmt.Errorf("%w: unknown registration authority or category %s", ErrFormat, code[:2])
+	}
+	for i := 2; i < 8; i++ {
+		if code[i] < '0' || code[i] > '9' {
+			return fmt.Errorf("%w: administrative division code must be digits", ErrFormat)
+		}
+	}
+	sum := 0
+	for i := 0; i < 17; i++ {
+		v := strings.IndexByte(usccCharset, code[i])
+		if v < 0 {
+			return fmt.Errorf("%w: invalid character %c", ErrFormat, code[i])
+		}
+		sum += v * usccWeights[i]
+	}
+	if usccCharset[(31-sum%31)%31] != code[17] {
+		return fmt.Errorf("%w: unified social credit code", ErrChecksum)
+	}
+	return nil
+}
+
+// OrganizationCode checks a 9-character organization code against GB 11714,
+// the check character may be separated by a dash.
+func OrganizationCode(code string) error {
+	code = strings.Replace(normalize(code), "-", "", 1)
+	if len(code) != 9 {
+		return fmt.Errorf("%w: organization code must have 9 characters", ErrFormat)
+	}
+	sum := 0
+	for i := 0; i < 8; i++ {
+		c := code[i]
+		var v int
+		switch {
+		case c >= '0' && c <= '9':
+			v = int(c - '0')
+		case c >= 'A' && c <= 'Z':
+			v = int(c-'A') + 10
+		default:
+			return fmt.Errorf("%w: invalid character %c", ErrFormat, c)
+		}
+		sum += v * orgCodeWeights[i]
+	}
+	check := byte('0' + (11-sum%11)%11)
+	if check == '0'+10 {
+		check = 'X'
+	}
+	if check != code[8] {
+		return fmt.Errorf("%w: organization code", ErrChecksum)
+	}
+	return nil
+}
+
+// BusinessLicence checks the number on a business licence, either a
+// Unified Social Credit Code or a legacy 15-digit registration number with
+// the ISO 7064 MOD 11,10 check digit.
+func BusinessLicence(num string) error {
+	num = normalize(num)
+	if len(num) == 18 {
+		return USCC(num)
+	}
+	if len(num) != 15 {
+		return fmt.Errorf("%w: business licence number must have 15 or 18 characters", ErrFormat)
+	}
+	p := 10
+	for i := 0; i < 14; i++ {
+		if num[i] < '0' || num[i] > '9' {
+			return fmt.Errorf("%w: registration number must be digits", ErrFormat)
+		}
+		s := (p + int(num[i]-'0')) % 10
+		if s == 0 {
+			s = 10
+		}
+		p = s * 2 % 11
+	}
+	if byte('0'+(11-p)%10) != num[14] {
+		return fmt.Errorf("%w: registration number", ErrChecksum)
+	}
+	return nil
+}
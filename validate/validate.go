@@ -0,0 +1,142 @@
+// Package validate checks identity numbers and other parameters of the
+// YunHeTong APIs locally, so malformed input is rejected before a billed
+// request is sent.
+//
+// The functions return nil for valid input, or an error wrapping one of the
+// sentinel errors of this package.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 校验失败的类别
+var (
+	ErrFormat   = errors.New("validate: malformed")
+	ErrChecksum = errors.New("validate: checksum mismatch")
+	ErrDate     = errors.New("validate: invalid birth date")
+	ErrRegion   = errors.New("validate: region mismatch")
+)
+
+// 证件号格式
+var (
+	passportPattern      = regexp.MustCompile(`^[A-Z0-9]{5,17}$`)
+	eepPattern           = regexp.MustCompile(`^[CW][A-Z0-9][0-9]{7}$`)
+	taiwanPermitPattern  = regexp.MustCompile(`^([0-9]{8}|[0-9]{10}[A-Z]?)$`)
+	hkMacaoPermitPattern = regexp.MustCompile(`^[HM][0-9]{8}([0-9]{2})?$`)
+)
+
+// residentIDWeights 居民身份证号前17位的加权因子
+var residentIDWeights = []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+
+// residentIDProvinces 居民身份证号前两位的省级行政区划代码，81、82、83为港澳台居民居住证
+var residentIDProvinces = map[string]bool{
+	"11": true, "12": true, "13": true, "14": true, "15": true,
+	"21": true, "22": true, "23": true,
+	"31": true, "32": true, "33": true, "34": true, "35": true, "36": true, "37": true,
+	"41": true, "42": true, "43": true, "44": true, "45": true, "46": true,
+	"50": true, "51": true, "52": true, "53": true, "54": true,
+	"61": true, "62": true, "63": true, "64": true, "65": true,
+	"71": true, "81": true, "82": true, "83": true,
+}
+
+// now 当前时间，测试时替换
+var now = time.Now
+
+// normalize 去掉空格并转换为大写
+func normalize(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), ""))
+}
+
+// NormalizeCertNum strips spaces and converts letters to upper case, the
+// form checked by ResidentID, Passport and the permit checks.
+func NormalizeCertNum(num string) string {
+	return normalize(num)
+}
+
+// ResidentID checks an 18-digit resident identity card number, including
+// the region prefix, the birth date and the ISO 7064 check character.
+func ResidentID(id string) error {
+	id = normalize(id)
+	if len(id) != 18 {
+		return fmt.Errorf("%w: resident id must have 18 characters", ErrFormat)
+	}
+	sum := 0
+	for i := 0; i < 17; i++ {
+		if id[i] < '0' || id[i] > '9' {
+			return fmt.Errorf("%w: resident id must be digits", ErrFormat)
+		}
+		sum += int(id[i]-'0') * residentIDWeights[i]
+	}
+	if !residentIDProvinces[id[:2]] {
+		return fmt.Errorf("%w: unknown region code %s", ErrFormat, id[:2])
+	}
+	if _, err := ResidentIDBirthDate(id); err != nil {
+		return err
+	}
+	if "10X98765432"[sum%11] != id[17] {
+		return fmt.Errorf("%w: resident id", ErrChecksum)
+	}
+	return nil
+}
+
+// ResidentIDBirthDate returns the birth date encoded in a resident id.
+func ResidentIDBirthDate(id string) (time.Time, error) {
+	id = normalize(id)
+	if len(id) != 18 {
+		return time.Time{}, fmt.Errorf("%w: resident id must have 18 characters", ErrFormat)
+	}
+	birth, err := time.Parse("20060102", id[6:14])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrDate, id[6:14])
+	}
+	if birth.Year() < 1900 || birth.After(now()) {
+		return time.Time{}, fmt.Errorf("%w: %s out of range", ErrDate, id[6:14])
+	}
+	return birth, nil
+}
+
+// IsHKMacaoTaiwanResidentID reports whether id is a residence permit for
+// Hong Kong, Macao or Taiwan residents, which shares the resident id format.
+func IsHKMacaoTaiwanResidentID(id string) bool {
+	id = normalize(id)
+	return strings.HasPrefix(id, "81") || strings.HasPrefix(id, "82") || strings.HasPrefix(id, "83")
+}
+
+// Passport checks the format of a passport number, 5 to 17 letters or digits.
+func Passport(num string) error {
+	if !passportPattern.MatchString(normalize(num)) {
+		return fmt.Errorf("%w: passport", ErrFormat)
+	}
+	return nil
+}
+
+// ExitEntryPermit checks the format of an Exit-Entry Permit for Travelling to
+// and from Hong Kong and Macao held by mainland residents.
+func ExitEntryPermit(num string) error {
+	if !eepPattern.MatchString(normalize(num)) {
+		return fmt.Errorf("%w: exit-entry permit", ErrFormat)
+	}
+	return nil
+}
+
+// TaiwanPermit checks the format of a Mainland Travel Permit for Taiwan Residents.
+func TaiwanPermit(num string) error {
+	if !taiwanPermitPattern.MatchString(normalize(num)) {
+		return fmt.Errorf("%w: mainland travel permit for taiwan residents", ErrFormat)
+	}
+	return nil
+}
+
+// HKMacaoPermit checks the format of a Mainland Travel Permit for Hong Kong
+// and Macao Residents.
+func HKMacaoPermit(num string) error {
+	if !hkMacaoPermitPattern.MatchString(normalize(num)) {
+		return fmt.Errorf("%w: mainland travel permit for hong kong and macao residents", ErrFormat)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package validate
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResidentID(t *testing.T) {
+	cases := []struct {
+		id  string
+		err error
+	}{
+		{"520103198801011432", nil},
+		{"11010519491231002x", nil},
+		{"520103198801011430", ErrChecksum},
+		{"520103198802301432", ErrDate},
+		{"520103290001011432", ErrDate},
+		{"990103198801011432", ErrFormat},
+		{"52010319880101143", ErrFormat},
+		{"52010319880101A432", ErrFormat},
+	}
+	for _, c := range cases {
+		if err := ResidentID(c.id); !errors.Is(err, c.err) && err != c.err {
+			t.Errorf("ResidentID(%s) = %v, expect %v", c.id, err, c.err)
+		}
+	}
+}
+
+func TestPermits(t *testing.T) {
+	if Passport("E12345678") != nil || Passport("E1") == nil {
+		t.Error("unexpected passport result")
+	}
+	if ExitEntryPermit("C12345678") != nil || ExitEntryPermit("H12345678") == nil {
+		t.Error("unexpected exit-entry permit result")
+	}
+	if TaiwanPermit("12345678") != nil || TaiwanPermit("1234") == nil {
+		t.Error("unexpected taiwan permit result")
+	}
+	if HKMacaoPermit("H1234567800") != nil || HKMacaoPermit("C12345678") == nil {
+		t.Error("unexpected hk macao permit result")
+	}
+}
+
+func TestCompanyCodes(t *testing.T) {
+	cases := []struct {
+		check func(string) error
+		code  string
+		err   error
+	}{
+		{USCC, "915201903470159140", nil},
+		{USCC, "91350100M000100Y43", nil},
+		{USCC, "72520103MA6DN5K7X2", nil},
+		{USCC, "915201903470159141", ErrChecksum},
+		{USCC, "945201903470159140", ErrFormat},
+		{USCC, "9152019034701591I0", ErrFormat},
+		{OrganizationCode, "D2143569-X", nil},
+		{OrganizationCode, "d2143569x", nil},
+		{OrganizationCode, "D21435691", ErrChecksum},
+		{BusinessLicence, "110108000000016", nil},
+		{BusinessLicence, "110108000000017", ErrChecksum},
+		{BusinessLicence, "915201903470159140", nil},
+		{BusinessLicence, "1101080000", ErrFormat},
+	}
+	for _, c := range cases {
+		if err := c.check(c.code); !errors.Is(err, c.err) && err != c.err {
+			t.Errorf("check(%s) = %v, expect %v", c.code, err, c.err)
+		}
+	}
+}
+
+func TestMobileAndBankCard(t *testing.T) {
+	if NormalizeMobile("+86 159-2800-9058") != "15928009058" {
+		t.Error("unexpected normalized mobile")
+	}
+	if MainlandMobile("+86 159-2800-9058") != nil || MainlandMobile("12928009058") == nil {
+		t.Error("unexpected mainland mobile result")
+	}
+	if HKMacaoMobile("+852 9123 4567") != nil || HKMacaoMobile("1234567") == nil {
+		t.Error("unexpected hk macao mobile result")
+	}
+	if TaiwanMobile("0912-345-678") != nil || TaiwanMobile("0212345678") == nil {
+		t.Error("unexpected taiwan mobile result")
+	}
+
+	cases := []struct {
+		num string
+		err error
+	}{
+		{"6222 0202 0000 0000 006", nil},
+		{"4111-1111-1111-1111", nil},
+		{"378282246310005", nil},
+		{"4111111111111112", ErrChecksum},
+		{"5555555555554444000", ErrFormat},
+		{"62220202000000X", ErrFormat},
+	}
+	for _, c := range cases {
+		if err := BankCard(c.num); !errors.Is(err, c.err) && err != c.err {
+			t.Errorf("BankCard(%s) = %v, expect %v", c.num, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package goyht
+
+import (
+	"fmt"
+
+	"github.com/iotdog/goyht/validate"
+)
+
+// ValidationError 请求参数未通过本地校验，请求没有发送。errors.Is(err, ErrInvalidParam)为true
+type ValidationError struct {
+	Field string // 未通过校验的参数
+	Err   error  // 校验失败的原因，包含validate包中的错误类别
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("goyht: invalid %s: %v", e.Field, e.Err)
+}
+
+// Is reports whether target is ErrInvalidParam.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidParam
+}
+
+// Unwrap returns the underlying error.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// personCertValidators 个人证件类型对应的证件号校验
+var personCertValidators = map[PersonCertType]func(string) error{
+	YHTPersonCertTypeIDCard:   validate.ResidentID,
+	YHTPersonCertTypePassport: validate.Passport,
+	YHTPersonCertTypeEEP:      validate.ExitEntryPermit,
+	YHTPersonCertTypeMTPForTW: validate.TaiwanPermit,
+	YHTPersonCertTypeMTPForHM: validate.HKMacaoPermit,
+}
+
+// regionCertTypes 身份地区允许使用的个人证件类型
+var regionCertTypes = map[IdentityRegion][]PersonCertType{
+	YHTIdentityRegionMainland: {YHTPersonCertTypeIDCard, YHTPersonCertTypePassport, YHTPersonCertTypeEEP, YHTPersonCertTypeOther},
+	YHTIdentityRegionHK:       {YHTPersonCertTypeIDCard, YHTPersonCertTypePassport, YHTPersonCertTypeMTPForHM, YHTPersonCertTypeOther},
+	YHTIdentityRegionMacao:    {YHTPersonCertTypeIDCard, YHTPersonCertTypePassport, YHTPersonCertTypeMTPForHM, YHTPersonCertTypeOther},
+	YHTIdentityRegionTaiwan:   {YHTPersonCertTypeIDCard, YHTPersonCertTypePassport, YHTPersonCertTypeMTPForTW, YHTPersonCertTypeOther},
+	YHTIdentityRegionForeign:  {YHTPersonCertTypePassport, YHTPersonCertTypeOther},
+}
+
+// residentIDRegionPrefixes 港澳台居民居住证号码的前缀
+var residentIDRegionPrefixes = map[IdentityRegion]string{
+	YHTIdentityRegionHK:     "81",
+	YHTIdentityRegionMacao:  "82",
+	YHTIdentityRegionTaiwan: "83",
+}
+
+// phoneRegionValidators 手机号地区对应的手机号校验
+var phoneRegionValidators = map[PhoneRegion]func(string) error{
+	YHTPhoneRegionMainland: validate.MainlandMobile,
+	YHTPhoneRegionHKMacao:  validate.HKMacaoMobile,
+	YHTPhoneRegionTaiwan:   validate.TaiwanMobile,
+}
+
+// normalizePersonCertNum 返回发送给平台的个人证件号，已知证件类型的证件号去掉空格并转换为大写
+func normalizePersonCertNum(t PersonCertType, num string) string {
+	if _, ok := personCertValidators[t]; ok {
+		return validate.NormalizeCertNum(num)
+	}
+	return num
+}
+
+// ValidatePerson 校验个人用户的证件号格式及其与身份地区是否一致，以及手机号与手机号地区是否一致。
+// 已知证件类型的证件号去掉空格并转换为大写后校验，未知的证件类型只校验证件号不为空
+func ValidatePerson(req *YhtCreatePersonReq) error {
+	certNum := normalizePersonCertNum(req.CertType, req.CertNum)
+	if certNum == "" {
+		return &ValidationError{Field: "certifyNum", Err: fmt.Errorf("%w: empty", validate.ErrFormat)}
+	}
+	region := req.IdentityRegion
+	if region == "" {
+		region = YHTIdentityRegionMainland
+	}
+	if types, ok := regionCertTypes[region]; ok && req.CertType != "" {
+		allowed := false
+		for _, t := range types {
+			allowed = allowed || t == req.CertType
+		}
+		if !allowed {
+			return &ValidationError{Field: "certifyType", Err: fmt.Errorf("%w: cert type %s in identity region %s", validate.ErrRegion, req.CertType, region)}
+		}
+	}
+	if check, ok := personCertValidators[req.CertType]; ok {
+		if err := check(certNum); err != nil {
+			return &ValidationError{Field: "certifyNum", Err: err}
+		}
+	}
+	if req.CertType == YHTPersonCertTypeIDCard {
+		prefix, hmt := residentIDRegionPrefixes[region]
+		if validate.IsHKMacaoTaiwanResidentID(certNum) != hmt || (hmt && certNum[:2] != prefix) {
+			return &ValidationError{Field: "certifyNum", Err: fmt.Errorf("%w: resident id in identity region %s", validate.ErrRegion, region)}
+		}
+	}
+	if req.Phone != "" {
+		phoneRegion := req.PhoneRegion
+		if phoneRegion == "" {
+			phoneRegion = YHTPhoneRegionMainland
+		}
+		if check, ok := phoneRegionValidators[phoneRegion]; ok {
+			if err := check(req.Phone); err != nil {
+				return &ValidationError{Field: "phoneNo", Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+// validateResidentID 校验实名认证使用的身份证号，idNo须已经过validate.NormalizeCertNum
+func validateResidentID(idNo string) error {
+	if err := validate.ResidentID(idNo); err != nil {
+		return &ValidationError{Field: "idNo", Err: err}
+	}
+	return nil
+}
+
+// validateMobile 校验实名认证使用的大陆手机号
+func validateMobile(phone string) error {
+	if err := validate.MainlandMobile(phone); err != nil {
+		return &ValidationError{Field: "mobile", Err: err}
+	}
+	return nil
+}
+
+// validateBankCard 校验实名认证使用的银行卡号
+func validateBankCard(cardNo string) error {
+	if err := validate.BankCard(cardNo); err != nil {
+		return &ValidationError{Field: "bankCardNo", Err: err}
+	}
+	return nil
+}
+
+// ValidateCompany 校验企业用户的统一社会信用代码
+func ValidateCompany(req *YhtCreateCompanyReq) error {
+	if req.CertType != "" && req.CertType != YHTCompanyCertTypeUniformSocailCreditCode {
+		return &ValidationError{Field: "certifyType", Err: fmt.Errorf("%w: unsupported cert type %q", validate.ErrFormat, string(req.CertType))}
+	}
+	if err := validate.USCC(req.CertNum); err != nil {
+		return &ValidationError{Field: "certifyNum", Err: err}
+	}
+	return nil
+}
+
+// userCertValidators V3接口证件类型对应的证件号校验
+var userCertValidators = map[string]func(string) error{
+	CertTypeIDCard:   validate.ResidentID,
+	CertTypePassport: validate.Passport,
+	CertTypeLicence:  validate.BusinessLicence,
+	CertTypeOrgan:    validate.OrganizationCode,
+	CertTypeSocial:   validate.USCC,
+}
+
+// ValidateUserCert 校验V3接口添加用户时的证件号，未知的证件类型只校验证件号不为空
+func ValidateUserCert(certType, certNum string) error {
+	if certNum == "" {
+		return &ValidationError{Field: "certifyNumber", Err: fmt.Errorf("%w: empty", validate.ErrFormat)}
+	}
+	if check, ok := userCertValidators[certType]; ok {
+		if err := check(certNum); err != nil {
+			return &ValidationError{Field: "certifyNumber", Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package goyht
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iotdog/goyht/validate"
+)
+
+func TestValidatePerson(t *testing.T) {
+	cases := []struct {
+		req YhtCreatePersonReq
+		err error
+	}{
+		{YhtCreatePersonReq{CertType: YHTPersonCertTypeIDCard, CertNum: "520103198801011432"}, nil},
+		{YhtCreatePersonReq{CertType: YHTPersonCertTypeIDCard, CertNum: "520103198801011430"}, validate.ErrChecksum},
+		{YhtCreatePersonReq{IdentityRegion: YHTIdentityRegionHK, CertType: YHTPersonCertTypeMTPForHM, CertNum: "H12345678"}, nil},
+		{YhtCreatePersonReq{IdentityRegion: YHTIdentityRegionHK, CertType: YHTPersonCertTypeIDCard, CertNum: "520103198801011432"}, validate.ErrRegion},
+		{YhtCreatePersonReq{IdentityRegion: YHTIdentityRegionForeign, CertType: YHTPersonCertTypeMTPForTW, CertNum: "12345678"}, validate.ErrRegion},
+		{YhtCreatePersonReq{CertType: YHTPersonCertTypePassport, CertNum: "E1"}, validate.ErrFormat},
+	}
+	for _, c := range cases {
+		err := ValidatePerson(&c.req)
+		if !errors.Is(err, c.err) && err != c.err {
+			t.Errorf("ValidatePerson(%+v) = %v, expect %v", c.req, err, c.err)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("expect ErrInvalidParam, got %v", err)
+		}
+	}
+
+	cli := NewClient(Config{APIGateway: "http://127.0.0.1:1"}, WithTokenRefresher(nil))
+	defer cli.Close()
+	_, err := cli.CreatePersonV4(&YhtCreatePersonReq{CertType: YHTPersonCertTypeIDCard, CertNum: "1"})
+	var vErr *ValidationError
+	if !errors.As(err, &vErr) || vErr.Field != "certifyNum" {
+		t.Fatalf("expect validation error, got %v", err)
+	}
+	if err = cli.AuthRealNameMobileV4("1", "Mike", "15928009058"); !errors.As(err, &vErr) {
+		t.Fatalf("expect validation error, got %v", err)
+	}
+}
+
+func TestValidateCompany(t *testing.T) {
+	if err := ValidateCompany(&YhtCreateCompanyReq{CertNum: "915201903470159140"}); err != nil {
+		t.Fatal(err)
+	}
+	err := ValidateCompany(&YhtCreateCompanyReq{CertNum: "915201903470159141"})
+	if !errors.Is(err, validate.ErrChecksum) || !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("expect checksum error, got %v", err)
+	}
+	if err = ValidateUserCert(CertTypeOrgan, "D2143569-X"); err != nil {
+		t.Fatal(err)
+	}
+	if err = ValidateUserCert(CertTypeLicence, "110108000000017"); !errors.Is(err, validate.ErrChecksum) {
+		t.Fatalf("expect checksum error, got %v", err)
+	}
+}
+
+func TestValidateAuthParams(t *testing.T) {
+	var idNo, bankCard string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idNo, bankCard = r.FormValue("idNo"), r.FormValue("bankCardNo")
+		fmt.Fprint(w, `{"code":200,"msg":"认证成功"}`)
+	}))
+	defer srv.Close()
+	cli := NewClient(Config{AuthGateway: srv.URL}, WithTokenRefresher(nil))
+	defer cli.Close()
+
+	if err := cli.AuthRealNameBankV4("520103 19880101 1432", "Mike", "+86 159 2800 9058", "6222-0202-0000-0000-006"); err != nil {
+		t.Fatal(err)
+	}
+	if idNo != "520103198801011432" || bankCard != "6222020200000000006" {
+		t.Fatalf("expect normalized id and bank card, got %q %q", idNo, bankCard)
+	}
+	err := cli.AuthRealNameBankV4("520103198801011432", "Mike", "15928009058", "6222020200000000007")
+	if !errors.Is(err, validate.ErrChecksum) {
+		t.Fatalf("expect checksum error, got %v", err)
+	}
+	err = ValidatePerson(&YhtCreatePersonReq{CertNum: "E12345678", PhoneRegion: YHTPhoneRegionTaiwan, Phone: "15928009058"})
+	if !errors.Is(err, validate.ErrFormat) {
+		t.Fatalf("expect phone format error, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package goyht
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidNotice 异步通知未通过校验，可能是伪造或重放的通知
+var ErrInvalidNotice = errors.New("goyht: invalid notification")
+
+// 异步通知的签名和时间戳字段，可以在表单或请求头中传递
+const (
+	NoticeSignField      = "sign"
+	NoticeTimestampField = "timestamp"
+)
+
+// DefaultDedupeTTL 默认的通知去重时长
+const DefaultDedupeTTL = 24 * time.Hour
+
+// minSweepSize 已处理的通知ID少于此数时不清理过期的ID
+const minSweepSize = 1024
+
+// noticeLocation 云合同通知时间使用的时区
+var noticeLocation = time.FixedZone("CST", 8*3600)
+
+// NoticeState Reserve返回的通知状态
+type NoticeState int
+
+// 通知状态
+const (
+	NoticeReserved  NoticeState = iota // 占用成功，须处理通知
+	NoticeProcessed                    // 已处理成功
+	NoticePending                      // 正在处理，结果未知
+)
+
+// DedupeStore 记录已处理的通知ID，用于拒绝重放的通知。通知分发前以Reserve占用ID，
+// 同一ID并发送达时只有一个请求得到占用，处理成功后Add，失败后Release
+type DedupeStore interface {
+	// Reserve atomically marks id as in progress if it is neither processed
+	// nor in progress. It returns NoticeReserved when the id is reserved,
+	// otherwise the state of the id.
+	Reserve(ctx context.Context, id string) (NoticeState, error)
+	// Add records the reserved id as processed.
+	Add(ctx context.Context, id string) error
+	// Release drops the reservation of id so the notice can be processed again.
+	Release(ctx context.Context, id string) error
+}
+
+// NotifyOption configures a NotificationHandler.
+type NotifyOption func(*NotificationHandler)
+
+// WithNoticeKey verifies the sign of notifications with appKey, see
+// SignNotice. Notifications without a valid sign are rejected.
+func WithNoticeKey(appKey string) NotifyOption {
+	return func(h *NotificationHandler) {
+		h.appKey = appKey
+	}
+}
+
+// SignNotice 返回异步通知的签名：以appKey为密钥，对timestamp字段与通知内容
+// 拼接后的数据计算HMAC-SHA256，以小写十六进制表示。没有timestamp字段时timestamp为空
+func SignNotice(appKey, timestamp string, notice []byte) string {
+	mac := hmac.New(sha256.New, []byte(appKey))
+	mac.Write([]byte(timestamp))
+	mac.Write(notice)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WithAllowedIPs only accepts notifications from the given IPs or CIDRs.
+// Entries that are neither are ignored.
+func WithAllowedIPs(addrs ...string) NotifyOption {
+	return func(h *NotificationHandler) {
+		for _, addr := range addrs {
+			if !strings.Contains(addr, "/") {
+				if ip := net.ParseIP(addr); ip != nil {
+					bits := 8 * len(ip.To16())
+					if ip.To4() != nil {
+						ip, bits = ip.To4(), 32
+					}
+					h.allowed = append(h.allowed, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				}
+				continue
+			}
+			if _, n, err := net.ParseCIDR(addr); err == nil {
+				h.allowed = append(h.allowed, n)
+			}
+		}
+	}
+}
+
+// WithProxyHeader takes the source IP from the last entry of header, such as
+// X-Forwarded-For, which is set by a trusted reverse proxy.
+func WithProxyHeader(header string) NotifyOption {
+	return func(h *NotificationHandler) {
+		h.proxyHeader = header
+	}
+}
+
+// WithFreshness rejects notifications older or newer than window. With
+// WithNoticeKey the time comes from the signed timestamp field, or from
+// noticeTime in the signed notice when there is none. Without a key nothing
+// is signed and noticeTime is used as received, so only stale redeliveries
+// are rejected, not forged ones.
+func WithFreshness(window time.Duration) NotifyOption {
+	return func(h *NotificationHandler) {
+		h.window = window
+	}
+}
+
+// WithDedupeStore sets the store used to reject replayed notifications,
+// nil disables replay protection.
+func WithDedupeStore(store DedupeStore) NotifyOption {
+	return func(h *NotificationHandler) {
+		h.dedupe = store
+	}
+}
+
+// ID 返回通知ID，通知中没有noticeId时使用通知内容的摘要
+func (n *Notice) ID() string {
+	if id := n.info("noticeId"); id != "" {
+		return id
+	}
+	sum := sha256.Sum256(n.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Time 返回通知时间，通知中没有noticeTime时返回零值
+func (n *Notice) Time() time.Time {
+	t, _ := time.ParseInLocation("2006-01-02 15:04:05", n.info("noticeTime"), noticeLocation)
+	return t
+}
+
+// noticeField 从表单或请求头中读取字段
+func noticeField(r *http.Request, key string) string {
+	if v := r.PostForm.Get(key); v != "" {
+		return v
+	}
+	return r.Header.Get(key)
+}
+
+// verifyRequest 校验通知的来源IP和签名
+func (h *NotificationHandler) verifyRequest(r *http.Request, data []byte) error {
+	if len(h.allowed) > 0 {
+		ip := sourceIP(r, h.proxyHeader)
+		ok := false
+		for _, n := range h.allowed {
+			if ip != nil && n.Contains(ip) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("%w: source %s not allowed", ErrInvalidNotice, ip)
+		}
+	}
+
+	if h.appKey == "" {
+		return nil
+	}
+	sign := noticeField(r, NoticeSignField)
+	if sign == "" {
+		return fmt.Errorf("%w: missing sign", ErrInvalidNotice)
+	}
+	want := SignNotice(h.appKey, noticeField(r, NoticeTimestampField), data)
+	if !hmac.Equal([]byte(strings.ToLower(sign)), []byte(want)) {
+		return fmt.Errorf("%w: sign mismatch", ErrInvalidNotice)
+	}
+	return nil
+}
+
+// verifyTime 校验通知时间是否在有效期内
+func (h *NotificationHandler) verifyTime(r *http.Request, n *Notice) error {
+	if h.window <= 0 {
+		return nil
+	}
+	t := n.Time()
+	// 时间戳只有签名覆盖时才可信，否则重放者可以随意更换
+	if ts := noticeField(r, NoticeTimestampField); ts != "" && h.appKey != "" {
+		v, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: malformed timestamp", ErrInvalidNotice)
+		}
+		// 13位为毫秒时间戳
+		if v > 1e12 {
+			t = time.Unix(0, v*int64(time.Millisecond))
+		} else {
+			t = time.Unix(v, 0)
+		}
+	}
+	if t.IsZero() {
+		return fmt.Errorf("%w: missing timestamp", ErrInvalidNotice)
+	}
+	if d := h.now().Sub(t); d > h.window || d < -h.window {
+		return fmt.Errorf("%w: stale notice at %s", ErrInvalidNotice, t.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// sourceIP 返回请求的来源IP
+func sourceIP(r *http.Request, proxyHeader string) net.IP {
+	if proxyHeader != "" {
+		if v := r.Header.Get(proxyHeader); v != "" {
+			parts := strings.Split(v, ",")
+			return net.ParseIP(strings.TrimSpace(parts[len(parts)-1]))
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// MemoryDedupeStore 内存中的DedupeStore，记录在ttl后过期
+type MemoryDedupeStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	ids     map[string]time.Time
+	sweepAt int                 // ids达到此数量时清理过期的ID
+	pending map[string]struct{} // 正在处理的通知ID
+	now     func() time.Time
+}
+
+// NewMemoryDedupeStore returns a MemoryDedupeStore keeping ids for ttl,
+// DefaultDedupeTTL if ttl is not positive.
+func NewMemoryDedupeStore(ttl time.Duration) *MemoryDedupeStore {
+	if ttl <= 0 {
+		ttl = DefaultDedupeTTL
+	}
+	return &MemoryDedupeStore{
+		ttl:     ttl,
+		ids:     map[string]time.Time{},
+		pending: map[string]struct{}{},
+		now:     time.Now,
+	}
+}
+
+// Reserve implements DedupeStore.
+func (s *MemoryDedupeStore) Reserve(ctx context.Context, id string) (NoticeState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if expiry, ok := s.ids[id]; ok && s.now().Before(expiry) {
+		return NoticeProcessed, nil
+	}
+	if _, ok := s.pending[id]; ok {
+		return NoticePending, nil
+	}
+	s.pending[id] = struct{}{}
+	return NoticeReserved, nil
+}
+
+// Release implements DedupeStore.
+func (s *MemoryDedupeStore) Release(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, id)
+	return nil
+}
+
+// Add implements DedupeStore.
+func (s *MemoryDedupeStore) Add(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	sweepExpired(s.ids, now, &s.sweepAt)
+	s.ids[id] = now.Add(s.ttl)
+	delete(s.pending, id)
+	return nil
+}
+
+// sweepExpired 在ids的数量达到*sweepAt时删除now之前过期的ID，并将*sweepAt设为剩余数量的两倍，
+// 每次添加ID的均摊开销为O(1)
+func sweepExpired(ids map[string]time.Time, now time.Time, sweepAt *int) {
+	if len(ids) < *sweepAt {
+		return
+	}
+	for k, expiry := range ids {
+		if !now.Before(expiry) {
+			delete(ids, k)
+		}
+	}
+	*sweepAt = 2 * len(ids)
+	if *sweepAt < minSweepSize {
+		*sweepAt = minSweepSize
+	}
+}
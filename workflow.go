@@ -0,0 +1,552 @@
+package goyht
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// 工作流步骤
+const (
+	StepCreateUser     = "createUser"
+	StepCreateSeal     = "createSeal"
+	StepCreateContract = "createContract"
+	StepAddSigners     = "addSigners"
+	StepSign           = "sign"
+)
+
+// Party 合同签署方，Person和Company二选一
+type Party struct {
+	Key         string                      // 签署方在工作流中的唯一标识
+	Person      *YhtCreatePersonReq         // 个人用户
+	Company     *YhtCreateCompanyReq        // 企业用户
+	PersonSeal  *YhtCreatePersonMoulageReq  // 个人印章，可选，SignerID由工作流填写
+	CompanySeal *YhtCreateCompanyMoulageReq // 企业印章，可选，SignerID由工作流填写
+	Signer      YhtSigner                   // 签署位置等，SignerID由工作流填写
+	AutoSign    bool                        // 为true时由工作流代为签署，否则由签署方在页面签署
+	SealClass   SealClass                   // 代为签署时的签章样式
+}
+
+// certNum 返回签署方的证件号
+func (p *Party) certNum() string {
+	if p.Person != nil {
+		return normalizePersonCertNum(p.Person.CertType, p.Person.CertNum)
+	}
+	if p.Company != nil {
+		return p.Company.CertNum
+	}
+	return ""
+}
+
+// WorkflowSpec 签署流程描述，Parties的顺序即签署顺序
+type WorkflowSpec struct {
+	ID       string                       // 工作流ID，用于保存进度
+	Contract YhtCreateTemplateContractReq // 模板合同，ContractNo为空时使用ID
+	Parties  []Party
+}
+
+// validate 校验流程描述
+func (spec *WorkflowSpec) validate() error {
+	if spec.ID == "" {
+		return fmt.Errorf("%w: workflow id is required", ErrInvalidParam)
+	}
+	if len(spec.Parties) == 0 {
+		return fmt.Errorf("%w: workflow %s has no party", ErrInvalidParam, spec.ID)
+	}
+	keys := map[string]bool{}
+	for i := range spec.Parties {
+		p := &spec.Parties[i]
+		if p.Key == "" || keys[p.Key] {
+			return fmt.Errorf("%w: party %d has empty or duplicate key %q", ErrInvalidParam, i, p.Key)
+		}
+		keys[p.Key] = true
+		if (p.Person == nil) == (p.Company == nil) {
+			return fmt.Errorf("%w: party %s must be either a person or a company", ErrInvalidParam, p.Key)
+		}
+		if p.PersonSeal != nil && p.CompanySeal != nil {
+			return fmt.Errorf("%w: party %s has two seals", ErrInvalidParam, p.Key)
+		}
+	}
+	return nil
+}
+
+// WorkflowState 工作流进度，每完成一步保存一次
+type WorkflowState struct {
+	ID           string              `json:"id"`
+	SignerIDs    map[string]string   `json:"signerIds"`   // 签署方 -> 用户ID
+	MoulageIDs   map[string]string   `json:"moulageIds"`  // 签署方 -> 印章ID
+	SealPending  map[string]bool     `json:"sealPending"` // 已请求创建印章但未保存印章ID的签署方
+	SealsBefore  map[string][]string `json:"sealsBefore"` // 签署方 -> 请求创建印章前已有的印章ID
+	ContractID   string              `json:"contractId"`
+	ContractNo   string              `json:"contractNo"`
+	SignersAdded bool                `json:"signersAdded"`
+	Signed       map[string]bool     `json:"signed"` // 已代为签署的签署方
+	Done         bool                `json:"done"`
+}
+
+func newWorkflowState(id string) *WorkflowState {
+	return &WorkflowState{
+		ID:          id,
+		SignerIDs:   map[string]string{},
+		MoulageIDs:  map[string]string{},
+		SealPending: map[string]bool{},
+		SealsBefore: map[string][]string{},
+		Signed:      map[string]bool{},
+	}
+}
+
+// WorkflowStore 保存工作流进度
+type WorkflowStore interface {
+	// Load returns the state saved for id, or nil if there is none.
+	Load(ctx context.Context, id string) (*WorkflowState, error)
+	// Save stores state.
+	Save(ctx context.Context, state *WorkflowState) error
+}
+
+// WorkflowError 工作流某一步失败
+type WorkflowError struct {
+	Workflow string
+	Step     string // StepCreateUser等之一
+	Party    string // 失败的签署方，与签署方无关时为空
+	Err      error
+}
+
+// Error implements the error interface.
+func (e *WorkflowError) Error() string {
+	if e.Party != "" {
+		return fmt.Sprintf("goyht: workflow %s step %s party %s: %v", e.Workflow, e.Step, e.Party, e.Err)
+	}
+	return fmt.Sprintf("goyht: workflow %s step %s: %v", e.Workflow, e.Step, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *WorkflowError) Unwrap() error {
+	return e.Err
+}
+
+// Workflow 按顺序执行创建用户、创建印章、生成合同、添加签署者和签署合同，
+// 每完成一步都保存进度。再次运行同一ID的工作流时从中断处继续，已完成的步骤不会重复执行。
+//
+// 同一Workflow中同一ID的工作流依次运行。多个Workflow或多个进程共用WorkflowStore时，
+// 由调用者保证同一ID的工作流不会同时运行
+type Workflow struct {
+	c     *Client
+	store WorkflowStore
+
+	mu      sync.Mutex
+	running map[string]chan struct{} // 正在运行的工作流ID，结束时关闭
+}
+
+// NewWorkflow returns a Workflow saving progress in store,
+// a MemoryWorkflowStore if store is nil.
+func NewWorkflow(c *Client, store WorkflowStore) *Workflow {
+	if store == nil {
+		store = NewMemoryWorkflowStore()
+	}
+	return &Workflow{c: c, store: store, running: map[string]chan struct{}{}}
+}
+
+// lock 等待同一ID的工作流结束后占用该ID，返回释放函数
+func (w *Workflow) lock(ctx context.Context, id string) (func(), error) {
+	for {
+		w.mu.Lock()
+		done, busy := w.running[id]
+		if !busy {
+			done = make(chan struct{})
+			w.running[id] = done
+			w.mu.Unlock()
+			return func() {
+				w.mu.Lock()
+				delete(w.running, id)
+				w.mu.Unlock()
+				close(done)
+			}, nil
+		}
+		w.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Run runs spec from the saved progress and returns the final state.
+// A concurrent Run of the same spec.ID waits for the running one to finish.
+func (w *Workflow) Run(ctx context.Context, spec *WorkflowSpec) (*WorkflowState, error) {
+	if spec == nil {
+		return nil, ErrInvalidParam
+	}
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	// 在创建用户之前按模板定义校验合同参数和签名位置
+	if err := w.c.checkTemplate(spec.Contract.TemplateID, spec.Contract.ContractData); err != nil {
+		return nil, err
+	}
+	signers := make([]YhtSigner, len(spec.Parties))
+	for i, p := range spec.Parties {
+		signers[i] = p.Signer
+	}
+	if err := w.c.checkTemplateSigners(spec.Contract.TemplateID, signers...); err != nil {
+		return nil, err
+	}
+	unlock, err := w.lock(ctx, spec.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	state, err := w.store.Load(ctx, spec.ID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		state = newWorkflowState(spec.ID)
+	}
+	if state.Done {
+		return state, nil
+	}
+
+	steps := []func(context.Context, *WorkflowSpec, *WorkflowState) error{
+		w.createUsers, w.createSeals, w.createContract, w.addSigners, w.sign,
+	}
+	for _, step := range steps {
+		if err = step(ctx, spec, state); err != nil {
+			return state, err
+		}
+	}
+	state.Done = true
+	return state, w.store.Save(ctx, state)
+}
+
+func (w *Workflow) createUsers(ctx context.Context, spec *WorkflowSpec, state *WorkflowState) error {
+	for i := range spec.Parties {
+		p := &spec.Parties[i]
+		if state.SignerIDs[p.Key] != "" {
+			continue
+		}
+		var rsp *YhtCreateUserResp
+		var err error
+		if p.Person != nil {
+			rsp, err = w.c.CreatePersonV4Ctx(ctx, p.Person)
+		} else {
+			rsp, err = w.c.CreateCompanyV4Ctx(ctx, p.Company)
+		}
+		signerID := ""
+		switch {
+		case err == nil:
+			signerID = strconv.Itoa(rsp.Data.SignerID)
+		case IsDuplicateUser(err):
+			signerID, err = w.querySignerID(ctx, p.certNum())
+		}
+		if err != nil {
+			return &WorkflowError{Workflow: spec.ID, Step: StepCreateUser, Party: p.Key, Err: err}
+		}
+		state.SignerIDs[p.Key] = signerID
+		if err = w.store.Save(ctx, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// querySignerID 按证件号查询已存在的用户ID
+func (w *Workflow) querySignerID(ctx context.Context, certNum string) (string, error) {
+	rsp, err := w.c.QuerySignerIDCtx(ctx, &YhtQuerySignerIDReq{CertifyNumList: []string{certNum}})
+	if err != nil {
+		return "", err
+	}
+	for _, m := range rsp.Data {
+		if id, ok := m[certNum]; ok {
+			return strconv.Itoa(id), nil
+		}
+	}
+	return "", fmt.Errorf("%w: no signer id for existing user", ErrRejected)
+}
+
+func (w *Workflow) createSeals(ctx context.Context, spec *WorkflowSpec, state *WorkflowState) error {
+	for i := range spec.Parties {
+		p := &spec.Parties[i]
+		if state.MoulageIDs[p.Key] != "" || (p.PersonSeal == nil && p.CompanySeal == nil) {
+			continue
+		}
+		fail := func(err error) error {
+			return &WorkflowError{Workflow: spec.ID, Step: StepCreateSeal, Party: p.Key, Err: err}
+		}
+		ids, err := w.moulageIDs(ctx, state.SignerIDs[p.Key])
+		if err != nil {
+			return fail(err)
+		}
+		if state.SealPending[p.Key] {
+			// 上次请求创建印章后未保存印章ID，印章可能已经创建，沿用请求前没有的印章
+			if id := newMoulageID(ids, state.SealsBefore[p.Key]); id != "" {
+				if err = w.saveMoulage(ctx, state, p.Key, id); err != nil {
+					return err
+				}
+				continue
+			}
+		} else {
+			// 已存在的用户可能已有印章，记录下来以区分本次创建的印章
+			state.SealPending[p.Key] = true
+			state.SealsBefore[p.Key] = ids
+			if err = w.store.Save(ctx, state); err != nil {
+				return err
+			}
+		}
+		var rsp *YhtCreateMoulageResp
+		if p.PersonSeal != nil {
+			req := *p.PersonSeal
+			req.SignerID = state.SignerIDs[p.Key]
+			rsp, err = w.c.CreatePersonMoulageV4Ctx(ctx, &req)
+		} else {
+			req := *p.CompanySeal
+			req.SignerID = state.SignerIDs[p.Key]
+			rsp, err = w.c.CreateCompanyMoulageV4Ctx(ctx, &req)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if err = w.saveMoulage(ctx, state, p.Key, strconv.Itoa(rsp.Data.MoulageID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moulageIDs 查询用户的印章ID，按创建顺序排列
+func (w *Workflow) moulageIDs(ctx context.Context, signerID string) ([]string, error) {
+	rsp, err := w.c.QueryMoulagesV4Ctx(ctx, &YhtQueryMoulagesReq{SignerID: signerID})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(rsp.Data))
+	for _, m := range rsp.Data {
+		ids = append(ids, strconv.Itoa(m.MoulageID))
+	}
+	return ids, nil
+}
+
+// newMoulageID 返回ids中最后一个不在before中的印章ID，没有时返回空字符串
+func newMoulageID(ids, before []string) string {
+	for i := len(ids) - 1; i >= 0; i-- {
+		found := false
+		for _, id := range before {
+			if id == ids[i] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ids[i]
+		}
+	}
+	return ""
+}
+
+func (w *Workflow) saveMoulage(ctx context.Context, state *WorkflowState, key, moulageID string) error {
+	state.MoulageIDs[key] = moulageID
+	delete(state.SealPending, key)
+	delete(state.SealsBefore, key)
+	return w.store.Save(ctx, state)
+}
+
+// createContract 生成合同，合同编号已存在时沿用已生成的合同
+func (w *Workflow) createContract(ctx context.Context, spec *WorkflowSpec, state *WorkflowState) error {
+	if state.ContractID != "" {
+		return nil
+	}
+	req := spec.Contract
+	if req.ContractNo == "" {
+		req.ContractNo = spec.ID
+	}
+	fail := func(err error) error {
+		return &WorkflowError{Workflow: spec.ID, Step: StepCreateContract, Err: err}
+	}
+
+	id := 0
+	detail, err := w.c.LookupContractDetailV4Ctx(ctx, &YhtContractDetailReq{IDType: YHTIDTypeCustom, IDContent: req.ContractNo})
+	switch {
+	case err == nil && detail.Data.ContractID != 0:
+		id = detail.Data.ContractID
+	case err == nil || isNotFound(err):
+		// 合同不存在时平台返回404应答，或返回成功应答和空的合同详情
+		rsp, err := w.c.CreateContractFromTemplateV4Ctx(ctx, &req)
+		if err != nil {
+			return fail(err)
+		}
+		id = rsp.Data.ContractID
+	default:
+		return fail(err)
+	}
+	state.ContractID = strconv.Itoa(id)
+	state.ContractNo = req.ContractNo
+	return w.store.Save(ctx, state)
+}
+
+// addSigners 添加尚未添加到合同的签署者
+func (w *Workflow) addSigners(ctx context.Context, spec *WorkflowSpec, state *WorkflowState) error {
+	if state.SignersAdded {
+		return nil
+	}
+	fail := func(err error) error {
+		return &WorkflowError{Workflow: spec.ID, Step: StepAddSigners, Err: err}
+	}
+	detail, err := w.detail(ctx, state)
+	if err != nil {
+		return fail(err)
+	}
+	exist := map[string]bool{}
+	for _, s := range detail.Signers {
+		exist[strconv.Itoa(s.SignerID)] = true
+	}
+	req := &YhtAddSignerReq{IDType: YHTIDTypeSystem, IDContent: state.ContractID}
+	for _, p := range spec.Parties {
+		signer := p.Signer
+		signer.SignerID = state.SignerIDs[p.Key]
+		if !exist[signer.SignerID] {
+			req.Signers = append(req.Signers, signer)
+		}
+	}
+	if len(req.Signers) > 0 {
+		if _, err = w.c.AddSignerV4Ctx(ctx, req); err != nil {
+			return fail(err)
+		}
+	}
+	state.SignersAdded = true
+	return w.store.Save(ctx, state)
+}
+
+// sign 按顺序代为签署，已在平台签署的签署方直接跳过
+func (w *Workflow) sign(ctx context.Context, spec *WorkflowSpec, state *WorkflowState) error {
+	var signed map[string]bool
+	for _, p := range spec.Parties {
+		if !p.AutoSign || state.Signed[p.Key] {
+			continue
+		}
+		signerID := state.SignerIDs[p.Key]
+		if signed == nil {
+			detail, err := w.detail(ctx, state)
+			if err != nil {
+				return &WorkflowError{Workflow: spec.ID, Step: StepSign, Party: p.Key, Err: err}
+			}
+			signed = map[string]bool{}
+			for _, s := range detail.Signers {
+				signed[strconv.Itoa(s.SignerID)] = s.SignStatus == SignSigned
+			}
+		}
+		if !signed[signerID] {
+			_, err := w.c.SignContractV4Ctx(ctx, &YhtSignContractReq{
+				IDType:    YHTIDTypeSystem,
+				IDContent: state.ContractID,
+				SignerID:  signerID,
+				MoulageID: state.MoulageIDs[p.Key],
+				SealClass: p.SealClass,
+			})
+			if err != nil {
+				return &WorkflowError{Workflow: spec.ID, Step: StepSign, Party: p.Key, Err: err}
+			}
+		}
+		state.Signed[p.Key] = true
+		if err := w.store.Save(ctx, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Workflow) detail(ctx context.Context, state *WorkflowState) (*YhtContractDetail, error) {
+	rsp, err := w.c.LookupContractDetailV4Ctx(ctx, &YhtContractDetailReq{IDType: YHTIDTypeSystem, IDContent: state.ContractID})
+	if err != nil {
+		return nil, err
+	}
+	return &rsp.Data, nil
+}
+
+// isNotFound 判断err是否为资源不存在的应答
+func isNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == YHTCodeNotFound
+}
+
+// MemoryWorkflowStore 内存中的WorkflowStore
+type MemoryWorkflowStore struct {
+	mu     sync.Mutex
+	states map[string][]byte
+}
+
+// NewMemoryWorkflowStore returns an empty MemoryWorkflowStore.
+func NewMemoryWorkflowStore() *MemoryWorkflowStore {
+	return &MemoryWorkflowStore{states: map[string][]byte{}}
+}
+
+// Load implements WorkflowStore.
+func (s *MemoryWorkflowStore) Load(ctx context.Context, id string) (*WorkflowState, error) {
+	s.mu.Lock()
+	data, ok := s.states[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	state := newWorkflowState(id)
+	return state, json.Unmarshal(data, state)
+}
+
+// Save implements WorkflowStore.
+func (s *MemoryWorkflowStore) Save(ctx context.Context, state *WorkflowState) error {
+	// 保存副本，避免调用者修改已保存的进度
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[state.ID] = data
+	return nil
+}
+
+// FileWorkflowStore 将每个工作流的进度保存为目录下的一个JSON文件
+type FileWorkflowStore struct {
+	dir string
+}
+
+// NewFileWorkflowStore returns a FileWorkflowStore saving into dir,
+// which is created if missing.
+func NewFileWorkflowStore(dir string) (*FileWorkflowStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileWorkflowStore{dir: dir}, nil
+}
+
+func (s *FileWorkflowStore) path(id string) string {
+	return filepath.Join(s.dir, url.PathEscape(id)+".json")
+}
+
+// Load implements WorkflowStore.
+func (s *FileWorkflowStore) Load(ctx context.Context, id string) (*WorkflowState, error) {
+	data, err := ioutil.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := newWorkflowState(id)
+	return state, json.Unmarshal(data, state)
+}
+
+// Save implements WorkflowStore.
+func (s *FileWorkflowStore) Save(ctx context.Context, state *WorkflowState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(state.ID), data)
+}
@@ -1,7 +1,11 @@
 package goyht
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -51,7 +55,7 @@ func TestCreateContractFromTemplate(t *testing.T) {
 		AuthPWD:     "ZZZ",
 		AuthGateway: YHTAuthGateway,
 	})
-	cli.AddUser("testUserID1", "15928009057", "company", "915201903470159141", UserTypeEnterprise, CertTypeLicence, true)
+	cli.AddUser("testUserID1", "15928009057", "company", "915201903470159140", UserTypeEnterprise, CertTypeLicence, true)
 
 	_, err := cli.AddUser("testUserID2", "15928009058", "Mike", "520103198801011430", UserTypePersonal, CertTypeIDCard, false)
 	if err != nil {
@@ -69,7 +73,7 @@ func TestCreateContractFromTemplate(t *testing.T) {
 	endY, endM, endD := after.Date()
 	holder := M{
 		"${lessor}":           "company",
-		"${lessorID}":         "915201903470159141",
+		"${lessorID}":         "915201903470159140",
 		"${lessorName}":       "tree",
 		"${lessorAddress}":    "tree address",
 		"${lessorPhone}":      "15928009057",
@@ -107,3 +111,35 @@ func TestCreateContractFromTemplate(t *testing.T) {
 		}
 	}
 }
+
+func TestNewClientOptions(t *testing.T) {
+	hc := &http.Client{}
+	a := NewClient(Config{AppID: "A"}, WithHTTPClient(hc), WithAPIGateway("http://a.example"))
+	b := NewClient(Config{AppID: "B"})
+	if a.tlsClient != hc || a.config.APIGateway != "http://a.example" {
+		t.Fatalf("options not applied: %+v", a.config)
+	}
+	if b.config.APIGateway != YHTAPIGatewayV4 || b.config.AuthGateway != YHTAuthGateway {
+		t.Fatalf("default gateways not set: %+v", b.config)
+	}
+	if a.refresher != nil || b.refresher != nil {
+		t.Fatal("refresher should not start without AppKey")
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	cli := NewClient(Config{}, WithAPIGateway(srv.URL), WithTokenRefresher(staticToken("tok")))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := cli.QuerySignerIDCtx(ctx, &YhtQuerySignerIDReq{CertifyNumList: []string{"1"}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+}